@@ -1,9 +1,11 @@
 package main
 
 import (
-    "flag"
-    "fmt"
+    "bufio"
+    "io"
     "os"
+    "strconv"
+    "strings"
 
     "github.com/voodooEntity/go-tachicrypt/src/core"
     "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
@@ -16,90 +18,104 @@ var version = "Alpha 0.2.0"
 var exitErrorFn = utils.ExitError
 
 // test hooks to allow stubbing core operations in unit tests
-var hideFunc = func(dataPath string, partCount int, outputDir string, prefilledPassword string) error {
-    return core.New().Hide(dataPath, partCount, outputDir, prefilledPassword)
-}
-var unhideFunc = func(dataPath string, outputDir string, prefilledPassword string) error {
-    return core.New().Unhide(dataPath, outputDir, prefilledPassword)
-}
+var (
+    hideFunc   = core.Hide
+    unhideFunc = core.Unhide
+    dryRunFunc = core.DryRun
+    verifyFunc = core.Verify
+    listFunc   = core.List
+    rekeyFunc  = core.Rekey
+)
 
-func main() {
-	// Define flags
-	hide := flag.Bool("hide", false, "Hide (encrypt) data")
-	unhide := flag.Bool("unhide", false, "Unhide (decrypt) data")
-	dataPath := flag.String("data", "", "Path to the data file or directory")
-	partCount := flag.Int("parts", -1, "Amount of parts that should be created")
-	outputDir := flag.String("output", "", "Output directory for encrypted data or decrypted data")
-	help := flag.Bool("help", false, "Show help message")
+// test hooks for the streams used by --data - / --data-from - and --output -, and by the
+// JSON events and results of --output-format json
+var (
+    stdin  io.Reader = os.Stdin
+    stdout io.Writer = os.Stdout
+    stderr io.Writer = os.Stderr
+)
 
-	// Parse flags
-	flag.Parse()
+// stringList is a flag.Value collecting every occurrence of a repeatable flag
+type stringList []string
 
- // Show help if --help is specified
- if *help {
-     printUsage()
-     return
- }
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
 
- // Validate flags; on failure exitErrorFn will be invoked and we return
- if !validateFlags(*hide, *unhide, *partCount, *dataPath, *outputDir) {
-     return
- }
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
 
-	utils.PrintApplicationHeader(version)
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage()
+		return
+	}
+	if cmd := findCommand(args[0]); cmd != nil {
+		cmd.run(args[1:])
+		return
+	}
+	if args[0] == "help" {
+		runHelp(args[1:])
+		return
+	}
+	runLegacy(args)
+}
 
- if *hide {
-        prefilledPwd := os.Getenv("TACHICRYPT_PASSWORD")
-        err := hideFunc(*dataPath, *partCount, *outputDir, prefilledPwd)
-        if err != nil {
-            exitErrorFn(fmt.Sprintf("Error hiding data: %v \n", err))
-        }
-        return
-    }
+// contains reports whether value is one of the given values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
 
- if *unhide {
-        prefilledPwd := os.Getenv("TACHICRYPT_PASSWORD")
-        err := unhideFunc(*dataPath, *outputDir, prefilledPwd)
-        if err != nil {
-            exitErrorFn(fmt.Sprintf("Error unhiding data: %v \n", err))
-        }
-        return
-    }
-	printUsage()
+// readPathList reads the paths listed in the given file, or stdin for "-". Every non-blank
+// line is one path, taken as is so names with leading or trailing spaces keep working.
+func readPathList(source string) ([]string, error) {
+	r := stdin
+	if source != "-" {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
 }
 
-// printUsage prints the usage information for the command-line tool.
-func printUsage() {
-	prettywriter.WriteInBox(40, "Usage: tachicrypt [options]", prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
-	fmt.Println("")
-	prettywriter.Writeln("Options:", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --hide             Hide (encrypt) data", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --unhide           Unhide (decrypt) data", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --data     [arg]   Path to the data file or directory", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --parts    [arg]   Amount of parts to be created when hiding", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --output   [arg]   Output directory for encrypted data or decrypted data", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  --help             Show this help message", prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println("")
-	prettywriter.Writeln("Examples:", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  Encrypt data: tachicrypt --hide --parts 10 --data /path/to/data --output /path/to/output", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("  Decrypt data: tachicrypt --data /path/to/encrypted/data --unhide --output /path/to/output ", prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println("")
+// newHideOptions returns hide options configured by the flags deciding what and how to pack
+func newHideOptions(stripMetadata, followSymlinks bool, format, compression string, level int, excludes []string) core.HideOptions {
+	return core.HideOptions{
+		StripMetadata:    stripMetadata,
+		FollowSymlinks:   followSymlinks,
+		Format:           format,
+		Compression:      compression,
+		CompressionLevel: level,
+		Excludes:         excludes,
+	}
 }
 
-// validateFlags performs CLI flags validation and invokes exitErrorFn on failure.
-// Returns true if validation succeeded and execution can continue.
-func validateFlags(hide, unhide bool, parts int, dataPath, outputDir string) bool {
-    if hide && -1 == parts {
-        exitErrorFn("Missing mandatory --parts parameter \n")
-        return false
-    }
-    if hide && unhide {
-        exitErrorFn("Cannot use both --hide and --unhide options at the same time. \n")
-        return false
-    }
-    if (hide || unhide) && (dataPath == "" || outputDir == "") {
-        exitErrorFn("Both --data and --output must be specified. \n")
-        return false
-    }
-    return true
+// printDryRun lists the entries a dry run found
+func printDryRun(names []string) {
+	prettywriter.WriteInBox(prettywriter.Width(), "Dry run", prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
+	for _, name := range names {
+		prettywriter.Writeln("[==] "+name, prettywriter.Green, prettywriter.BlackBG)
+	}
+	prettywriter.Println("")
+	prettywriter.Writeln("[**] "+strconv.Itoa(len(names))+" entries would be hidden.", prettywriter.BlackBG, prettywriter.Green)
 }
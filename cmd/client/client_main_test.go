@@ -8,7 +8,7 @@ import (
     "testing"
 )
 
-// runClient runs main() in-process with a fresh FlagSet and a stubbed exitErrorFn.
+// runClient runs main() in-process with a fresh FlagSet and stubbed exitErrorFn and exitErrorCodeFn.
 // It returns stdout/stderr output (captured by caller if desired), whether exitError was invoked, and the exit message.
 func runClient(t *testing.T, args ...string) (called bool, msg string) {
     t.Helper()
@@ -23,11 +23,14 @@ func runClient(t *testing.T, args ...string) (called bool, msg string) {
         gotMsg = m
         panic("test-exit")
     }
-    t.Cleanup(func() { exitErrorFn = oldExit })
+    oldExitCode := exitErrorCodeFn
+    exitErrorCodeFn = func(m string, code int) { exitErrorFn(m) }
+    t.Cleanup(func() { exitErrorFn = oldExit; exitErrorCodeFn = oldExitCode })
 
     os.Args = append([]string{"tachicrypt"}, args...)
     defer func() {
         _ = recover() // swallow our test-exit panic
+        called, msg = calledFlag, gotMsg
     }()
     main()
     return calledFlag, gotMsg
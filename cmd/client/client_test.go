@@ -7,6 +7,8 @@ import (
     "strings"
     "testing"
     "flag"
+
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
 )
 
 // captureStdout captures stdout output while fn runs and returns the printed string.
@@ -52,16 +54,22 @@ func TestPrintUsage_ContainsCoreSections(t *testing.T) {
 
     mustContain := []string{
         "Usage: tachicrypt",
-        "Options:",
+        "Commands:",
+        "hide",
+        "unhide",
+        "verify",
+        "list",
+        "rekey",
+        "keygen",
+        "genpass",
+        "help",
+        "Examples:",
+        "Encrypt data: tachicrypt hide",
+        "Decrypt data: tachicrypt unhide",
+        "Deprecated:",
         "--hide",
         "--unhide",
-        "--data",
-        "--parts",
-        "--output",
-        "--help",
-        "Examples:",
-        "Encrypt data: tachicrypt --hide",
-        "Decrypt data: tachicrypt --data",
+        "Exit codes:",
     }
     for _, s := range mustContain {
         if !strings.Contains(cleaned, s) {
@@ -70,6 +78,22 @@ func TestPrintUsage_ContainsCoreSections(t *testing.T) {
     }
 }
 
+func TestPrintUsage_FollowsPrettywriterOutput(t *testing.T) {
+    var buf bytes.Buffer
+    prettywriter.SetOutput(&buf)
+    t.Cleanup(func() { prettywriter.SetOutput(nil) })
+    out := captureStdout(t, func() {
+        printUsage()
+        printCommandHelp(hideCommand)
+    })
+    if out != "" {
+        t.Fatalf("expected the help to go to the prettywriter output only, got %q on stdout", out)
+    }
+    if !strings.Contains(buf.String(), "Exit codes:") || !strings.Contains(buf.String(), "Options:") {
+        t.Fatalf("expected the help in the prettywriter output, got %q", buf.String())
+    }
+}
+
 func TestMain_HelpFlagPath(t *testing.T) {
     out := captureStdout(t, func() {
         flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
@@ -80,3 +104,26 @@ func TestMain_HelpFlagPath(t *testing.T) {
         t.Fatalf("expected usage output when --help is provided; got: %q", out)
     }
 }
+
+func TestHelp_Command(t *testing.T) {
+    out := captureStdout(t, func() {
+        os.Args = []string{"tachicrypt", "help", "hide"}
+        main()
+    })
+    for _, s := range []string{"Usage: tachicrypt hide", "--parts", "--data", "--keyfile", "--timestamps [string]", "(default random)", "--help", "Examples:"} {
+        if !strings.Contains(out, s) {
+            t.Fatalf("hide help missing %q. Output: %q", s, out)
+        }
+    }
+    if strings.Contains(out, "--on-conflict") {
+        t.Fatalf("expected the flags of unhide to be left out of the hide help. Output: %q", out)
+    }
+
+    // -h inside a command shows the same help
+    if got := captureStdout(t, func() {
+        os.Args = []string{"tachicrypt", "rekey", "-h"}
+        main()
+    }); !strings.Contains(got, "--new-keyfile") {
+        t.Fatalf("expected the rekey help, got %q", got)
+    }
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+// command is a subcommand of the cli, e.g. `tachicrypt hide`
+type command struct {
+	name string
+	// usage is the synopsis shown in its help, summary the line it's listed with
+	usage   string
+	summary string
+	// maxArgs is the number of positional arguments it takes after its flags
+	maxArgs  int
+	examples []string
+	// setup defines the flags of the command and returns what runs it once they're parsed
+	setup func(fs *flag.FlagSet) func(args []string)
+}
+
+// commands are the subcommands in the order they're listed in the usage
+var commands = []*command{hideCommand, unhideCommand, verifyCommand, listCommand, rekeyCommand, keygenCommand, genpassCommand}
+
+// findCommand returns the command called name, nil if there is none
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// flagSet returns the flags of cmd, parse errors are reported by the caller. --quiet is
+// shared by all commands.
+func (cmd *command) flagSet() (*flag.FlagSet, func(args []string)) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Bool("quiet", false, "Only print errors, prompts and a generated password")
+	return fs, cmd.setup(fs)
+}
+
+// run parses args and runs the command, or shows its help for -h and --help
+func (cmd *command) run(args []string) {
+	cmd.runAlias(args, "")
+}
+
+// runAlias is run for an alias of the command, telling notice, e.g. that the alias is
+// deprecated, before the command runs. Scripts get it as warning event.
+func (cmd *command) runAlias(args []string, notice string) {
+	fs, run := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandHelp(cmd)
+			return
+		}
+		exitErrorCodeFn(fmt.Sprintf("%v, see tachicrypt help %s. \n", err, cmd.name), exitUsage)
+		return
+	}
+	// scripts read the result from stdout, what's left for people goes to stderr
+	json := false
+	if f := fs.Lookup("output-format"); f != nil && f.Value.String() == string(formatJSON) {
+		prettywriter.SetOutput(os.Stderr)
+		json = true
+	}
+	if fs.Lookup("quiet").Value.String() == "true" {
+		prettywriter.SetQuiet(true)
+	}
+	if notice != "" && json {
+		newJSONEvents(stderr).write(jsonEvent{Event: core.EventWarning, Message: notice})
+	} else if notice != "" {
+		fmt.Fprintln(stderr, "[!!] "+notice)
+	}
+	if fs.NArg() > cmd.maxArgs {
+		message := fmt.Sprintf("Unexpected argument %q, see tachicrypt help %s.", fs.Arg(cmd.maxArgs), cmd.name)
+		if f := strayValue(fs, args); f != nil {
+			message = fmt.Sprintf("--%s only takes a value as --%s=%s, see tachicrypt help %s.", f.Name, f.Name, fs.Arg(0), cmd.name)
+		}
+		if json {
+			newReporter(cmd.name, formatJSON).exit(message, exitUsage)
+			return
+		}
+		exitErrorCodeFn(message+" \n", exitUsage)
+		return
+	}
+	run(fs.Args())
+}
+
+// strayValue returns the flag the first positional argument probably was meant as the value
+// of, like 8 in --generate-password 8. Flags with an optional value only take it as --flag=VALUE.
+func strayValue(fs *flag.FlagSet, args []string) *flag.Flag {
+	i := len(args) - fs.NArg()
+	if i < 1 || fs.NArg() == 0 {
+		return nil
+	}
+	f := fs.Lookup(strings.TrimLeft(args[i-1], "-"))
+	if f == nil {
+		return nil
+	}
+	if _, optional := f.Value.(*wordCount); !optional {
+		return nil
+	}
+	return f
+}
+
+// runHelp implements `tachicrypt help [command]`
+func runHelp(args []string) {
+	if len(args) == 0 {
+		printUsage()
+		return
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		exitErrorCodeFn(fmt.Sprintf("Unknown command %q, see tachicrypt help. \n", args[0]), exitUsage)
+		return
+	}
+	printCommandHelp(cmd)
+}
+
+// runLegacy runs the flags-only invocations of earlier versions, `tachicrypt --hide ...` and
+// `tachicrypt --unhide ...`, as the commands they're now deprecated aliases of
+func runLegacy(args []string) {
+	var hide, unhide bool
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--hide", "-hide":
+			hide = true
+		case "--unhide", "-unhide":
+			unhide = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	switch {
+	case hide && unhide:
+		exitErrorFn("Cannot use both --hide and --unhide options at the same time. \n")
+	case hide:
+		hideCommand.runAlias(rest, "--hide is deprecated, use `tachicrypt hide` instead")
+	case unhide:
+		unhideCommand.runAlias(rest, "--unhide is deprecated, use `tachicrypt unhide` instead")
+	case len(args) > 0 && !strings.HasPrefix(args[0], "-"):
+		exitErrorCodeFn(fmt.Sprintf("Unknown command %q, see tachicrypt help. \n", args[0]), exitUsage)
+	default:
+		printUsage()
+	}
+}
+
+// printUsage prints the usage information for the command-line tool.
+func printUsage() {
+	prettywriter.WriteInBox(prettywriter.Width(), "Usage: tachicrypt <command>", prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
+	prettywriter.Println("")
+	prettywriter.Writeln("Commands:", prettywriter.Green, prettywriter.BlackBG)
+	for _, cmd := range commands {
+		prettywriter.Writeln(fmt.Sprintf("  %-9s %s", cmd.name, cmd.summary), prettywriter.Green, prettywriter.BlackBG)
+	}
+	prettywriter.Writeln(fmt.Sprintf("  %-9s %s", "help", "Show the options of a command: tachicrypt help <command>"), prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+	prettywriter.Writeln("Examples:", prettywriter.Green, prettywriter.BlackBG)
+	for _, cmd := range commands {
+		prettywriter.Writeln("  "+cmd.examples[0], prettywriter.Green, prettywriter.BlackBG)
+	}
+	prettywriter.Println("")
+	prettywriter.Writeln("Deprecated:", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln("  --hide     Alias of tachicrypt hide, e.g. tachicrypt --hide --parts 10 --data /path/to/data --output /path/to/output", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln("  --unhide   Alias of tachicrypt unhide, e.g. tachicrypt --unhide --data /path/to/encrypted/data --output /path/to/output", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+	prettywriter.Writeln("Exit codes:", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln("  1 any other error, 2 invalid flags, 3 wrong password or keyfile, 4 part missing,", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln("  5 part corrupt, 6 unsupported by this version, 7 unsafe path in the hidden data,", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln("  8 fewer or more keyfiles than the masterlock requires, 130 interrupted", prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+}
+
+// printCommandHelp prints the usage, options and examples of cmd
+func printCommandHelp(cmd *command) {
+	fs, _ := cmd.flagSet()
+	prettywriter.WriteInBox(prettywriter.Width(), "tachicrypt "+cmd.name, prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
+	prettywriter.Println("")
+	prettywriter.Writeln("Usage: "+cmd.usage, prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Writeln(cmd.summary, prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+	prettywriter.Writeln("Options:", prettywriter.Green, prettywriter.BlackBG)
+	fs.VisitAll(func(f *flag.Flag) {
+		prettywriter.Writeln(flagLine(f), prettywriter.Green, prettywriter.BlackBG)
+	})
+	prettywriter.Writeln(fmt.Sprintf("  %-24s %s", "--help", "Show this help message"), prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+	prettywriter.Writeln("Examples:", prettywriter.Green, prettywriter.BlackBG)
+	for _, example := range cmd.examples {
+		prettywriter.Writeln("  "+example, prettywriter.Green, prettywriter.BlackBG)
+	}
+	prettywriter.Println("")
+}
+
+// flagLine describes f in the help of a command, with its default unless that's empty
+func flagLine(f *flag.Flag) string {
+	arg, usage := flag.UnquoteUsage(f)
+	name := "--" + f.Name
+	if arg != "" {
+		name += " [" + arg + "]"
+	}
+	switch f.DefValue {
+	case "", "0", "-1", "false":
+	default:
+		usage += " (default " + f.DefValue + ")"
+	}
+	return fmt.Sprintf("  %-24s %s", name, usage)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+func TestCommands_KeygenVerifyListRekey(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "notes.txt")
+    newSecret := filepath.Join(tmp, "new.pass")
+    for path, content := range map[string]string{src: "remember", newSecret: "a new password"} {
+        if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+            t.Fatalf("write %s: %v", path, err)
+        }
+    }
+    keyfile := filepath.Join(tmp, "usb.key")
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+    os.Setenv("TACHICRYPT_PASSWORD", "old password")
+    t.Cleanup(func() { os.Unsetenv("TACHICRYPT_PASSWORD") })
+    oldPrompt := promptPasswordFn
+    promptPasswordFn = func(prompt string) (string, error) {
+        t.Fatalf("unexpected prompt %q", prompt)
+        return "", nil
+    }
+    var listed bytes.Buffer
+    oldStdout := stdout
+    stdout = &listed
+    t.Cleanup(func() { promptPasswordFn = oldPrompt; stdout = oldStdout; prettywriter.SetOutput(nil) })
+
+    if called, msg := runClient(t, "keygen", "--size", "32", keyfile); called {
+        t.Fatalf("keygen failed: %s", msg)
+    }
+    if info, err := os.Stat(keyfile); err != nil || info.Size() != 32 {
+        t.Fatalf("expected a keyfile of 32 bytes, got %v (%v)", info, err)
+    }
+    if called, _ := runClient(t, "keygen", keyfile); !called {
+        t.Fatalf("expected an existing keyfile not to be overwritten")
+    }
+    if called, msg := runClient(t, "hide", "--parts", "2", "--data", src, "--output", enc, "--keyfile", keyfile); called {
+        t.Fatalf("hide failed: %s", msg)
+    }
+    if called, msg := runClient(t, "verify", "--data", enc, "--keyfile", keyfile); called {
+        t.Fatalf("verify failed: %s", msg)
+    }
+    if called, msg := runClient(t, "list", "--data", enc, "--keyfile", keyfile); called {
+        t.Fatalf("list failed: %s", msg)
+    }
+    if lines := strings.Split(strings.TrimSpace(listed.String()), "\n"); len(lines) != 1 || !strings.HasSuffix(lines[0], " notes.txt") || !strings.Contains(lines[0], " 8 ") {
+        t.Fatalf("expected notes.txt of 8 bytes to be listed, got %q", listed.String())
+    }
+    if _, err := os.Stat(filepath.Join(enc, "notes.txt")); !os.IsNotExist(err) {
+        t.Fatalf("expected verify and list not to extract anything, got %v", err)
+    }
+
+    // the keyfile is dropped and the password changed
+    if called, msg := runClient(t, "rekey", "--data", enc, "--keyfile", keyfile, "--new-password-file", newSecret); called {
+        t.Fatalf("rekey failed: %s", msg)
+    }
+    if called, _ := runClient(t, "verify", "--data", enc, "--keyfile", keyfile); !called {
+        t.Fatalf("expected the old credentials to be refused after rekey")
+    }
+    if called, msg := runClient(t, "unhide", "--data", enc, "--output", out, "--password-file", newSecret); called {
+        t.Fatalf("unhide failed: %s", msg)
+    }
+    if b, err := os.ReadFile(filepath.Join(out, "notes.txt")); err != nil || string(b) != "remember" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+}
+
+func TestCommands_Quiet(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "notes.txt")
+    if err := os.WriteFile(src, []byte("remember"), 0o600); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    os.Setenv("TACHICRYPT_PASSWORD", "a password")
+    t.Cleanup(func() { os.Unsetenv("TACHICRYPT_PASSWORD"); prettywriter.SetQuiet(false) })
+
+    out := captureStdout(t, func() {
+        if called, msg := runClient(t, "hide", "--quiet", "--parts", "2", "--data", src, "--output", filepath.Join(tmp, "enc")); called {
+            t.Fatalf("hide failed: %s", msg)
+        }
+    })
+    if out != "" {
+        t.Fatalf("expected nothing printed with --quiet, got %q", out)
+    }
+    if _, err := os.Stat(filepath.Join(tmp, "enc")); err != nil {
+        t.Fatalf("expected the data to be hidden: %v", err)
+    }
+
+    // prompts get through
+    prettywriter.SetQuiet(true)
+    oldPrompt := promptPasswordFn
+    promptPasswordFn = func(prompt string) (string, error) {
+        if prettywriter.Quiet() {
+            t.Fatalf("expected the prompt %q to be shown", prompt)
+        }
+        return "secret", nil
+    }
+    t.Cleanup(func() { promptPasswordFn = oldPrompt })
+    if _, err := (promptPassword{}).Password("unhide"); err != nil {
+        t.Fatalf("prompt: %v", err)
+    }
+    if !prettywriter.Quiet() {
+        t.Fatalf("expected quiet to be restored after the prompt")
+    }
+}
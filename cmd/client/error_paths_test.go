@@ -1,11 +1,19 @@
 package main
 
 import (
+    "bytes"
+    "context"
     "flag"
     "fmt"
+    "io"
     "os"
     "path/filepath"
+    "strings"
     "testing"
+    "time"
+
+    "github.com/voodooEntity/go-tachicrypt/src/core"
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
 )
 
 // Test the error branch in main() when hideFunc returns an error
@@ -25,16 +33,16 @@ func TestMain_Hide_ErrorPath_Stubbed(t *testing.T) {
 
     // Stub hooks
     oldHide := hideFunc
-    oldExit := exitErrorFn
+    oldExit := exitErrorCodeFn
     called := false
-    hideFunc = func(dataPath string, partCount int, outputDir string, prefilledPassword string) error {
-        return fmt.Errorf("boom-hide")
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        return nil, fmt.Errorf("boom-hide")
     }
-    exitErrorFn = func(message string) {
-        called = true
+    exitErrorCodeFn = func(message string, code int) {
+        called = code == exitFailure
         panic("test-exit")
     }
-    t.Cleanup(func() { hideFunc = oldHide; exitErrorFn = oldExit })
+    t.Cleanup(func() { hideFunc = oldHide; exitErrorCodeFn = oldExit })
 
     // Run main() with valid hide flags
     defer func() { _ = recover() }()
@@ -43,7 +51,7 @@ func TestMain_Hide_ErrorPath_Stubbed(t *testing.T) {
     main()
 
     if !called {
-        t.Fatalf("expected exitErrorFn to be called on hide error")
+        t.Fatalf("expected exitErrorCodeFn to be called with the generic code on hide error")
     }
 }
 
@@ -58,16 +66,16 @@ func TestMain_Unhide_ErrorPath_Stubbed(t *testing.T) {
     t.Cleanup(func() { os.Unsetenv("TACHICRYPT_PASSWORD") })
 
     oldUnhide := unhideFunc
-    oldExit := exitErrorFn
+    oldExit := exitErrorCodeFn
     called := false
-    unhideFunc = func(dataPath string, outputDir string, prefilledPassword string) error {
-        return fmt.Errorf("boom-unhide")
+    unhideFunc = func(ctx context.Context, opts core.UnhideOptions) (*core.UnhideResult, error) {
+        return nil, fmt.Errorf("boom-unhide")
     }
-    exitErrorFn = func(message string) {
-        called = true
+    exitErrorCodeFn = func(message string, code int) {
+        called = code == exitFailure
         panic("test-exit")
     }
-    t.Cleanup(func() { unhideFunc = oldUnhide; exitErrorFn = oldExit })
+    t.Cleanup(func() { unhideFunc = oldUnhide; exitErrorCodeFn = oldExit })
 
     defer func() { _ = recover() }()
     flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
@@ -75,7 +83,7 @@ func TestMain_Unhide_ErrorPath_Stubbed(t *testing.T) {
     main()
 
     if !called {
-        t.Fatalf("expected exitErrorFn to be called on unhide error")
+        t.Fatalf("expected exitErrorCodeFn to be called with the generic code on unhide error")
     }
 }
 
@@ -109,3 +117,403 @@ func TestMain_ValidateFails_BothFlags_NoPanic(t *testing.T) {
         t.Fatalf("expected exitErrorFn to be called when both --hide and --unhide are provided")
     }
 }
+
+// Cover the dry-run branch in main(), which neither needs --parts/--output nor a password
+func TestMain_DryRun_PassesExcludes(t *testing.T) {
+    oldDryRun := dryRunFunc
+    oldExit := exitErrorFn
+    var gotExcludes []string
+    var gotPath string
+    dryRunFunc = func(opts core.HideOptions) ([]string, error) {
+        gotExcludes, gotPath = opts.Excludes, opts.Paths[0]
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    t.Cleanup(func() { dryRunFunc = oldDryRun; exitErrorFn = oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--dry-run", "--data", "/tmp/x", "--exclude", "*.log", "--exclude", ".git/"}
+    main()
+
+    if gotPath != "/tmp/x" || len(gotExcludes) != 2 || gotExcludes[0] != "*.log" || gotExcludes[1] != ".git/" {
+        t.Fatalf("unexpected dry-run call: %q %v", gotPath, gotExcludes)
+    }
+}
+
+// Cover repeated --data flags combined with a --data-from list read from stdin
+func TestMain_Hide_MultipleDataPaths(t *testing.T) {
+    oldHide := hideFunc
+    oldExit := exitErrorFn
+    oldStdin := stdin
+    var got []string
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        got = opts.Paths
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    stdin = strings.NewReader("/tmp/listed one\n\n/tmp/listed-two\r\n")
+    t.Cleanup(func() { hideFunc = oldHide; exitErrorFn = oldExit; stdin = oldStdin })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--data", "/tmp/b", "--data-from", "-", "--output", "/tmp/out"}
+    main()
+
+    want := []string{"/tmp/a", "/tmp/b", "/tmp/listed one", "/tmp/listed-two"}
+    if strings.Join(got, "|") != strings.Join(want, "|") {
+        t.Fatalf("unexpected data paths: %q", got)
+    }
+}
+
+// Cover the rejection of several --data paths when unhiding
+func TestMain_Unhide_MultipleDataPathsRejected(t *testing.T) {
+    oldExit := exitErrorFn
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() { exitErrorFn = oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/a", "--data", "/tmp/b", "--output", "/tmp/out"}
+    main()
+    if !strings.Contains(msg, "single --data") {
+        t.Fatalf("expected rejection, got %q", msg)
+    }
+}
+
+// Cover a --data-from file that can't be read
+func TestMain_DataFrom_MissingFile(t *testing.T) {
+    oldExit := exitErrorFn
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() { exitErrorFn = oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data-from", filepath.Join(t.TempDir(), "missing"), "--output", "/tmp/out"}
+    main()
+    if !strings.Contains(msg, "--data-from") {
+        t.Fatalf("expected list read error, got %q", msg)
+    }
+}
+
+// Cover hiding from stdin and unhiding to stdout, with all output moved to stderr
+func TestMain_StdinAndStdoutStreams(t *testing.T) {
+    oldHide, oldUnhide, oldExit := hideFunc, unhideFunc, exitErrorFn
+    oldStdin, oldStdout := stdin, stdout
+    var gotName, gotData string
+    var gotWriter io.Writer
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        b, _ := io.ReadAll(opts.Reader)
+        gotName, gotData = opts.Name, string(b)
+        return nil, nil
+    }
+    unhideFunc = func(ctx context.Context, opts core.UnhideOptions) (*core.UnhideResult, error) {
+        gotWriter = opts.Writer
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    var sink bytes.Buffer
+    stdin, stdout = strings.NewReader("piped data"), &sink
+    t.Cleanup(func() {
+        hideFunc, unhideFunc, exitErrorFn = oldHide, oldUnhide, oldExit
+        stdin, stdout = oldStdin, oldStdout
+        prettywriter.SetOutput(nil)
+    })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "-", "--name", "db.sql", "--output", "/tmp/out"}
+    main()
+    if gotName != "db.sql" || gotData != "piped data" {
+        t.Fatalf("unexpected stdin hide: %q %q", gotName, gotData)
+    }
+    if prettywriter.Output() != os.Stderr {
+        t.Fatalf("expected output to be moved to stderr")
+    }
+
+    prettywriter.SetOutput(nil)
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/enc", "--output", "-"}
+    main()
+    if gotWriter != &sink || prettywriter.Output() != os.Stderr {
+        t.Fatalf("expected unhide to write to stdout with output on stderr")
+    }
+}
+
+// Cover the rejection of stdin combined with other data paths
+func TestMain_StdinWithOtherPathsRejected(t *testing.T) {
+    oldExit := exitErrorFn
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() { exitErrorFn = oldExit; prettywriter.SetOutput(nil) })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "-", "--data", "/tmp/a", "--output", "/tmp/out"}
+    main()
+    if !strings.Contains(msg, "--data -") {
+        t.Fatalf("expected rejection, got %q", msg)
+    }
+}
+
+// Cover passing --on-conflict on to the core used for unhiding
+func TestMain_Unhide_OnConflict(t *testing.T) {
+    oldUnhide, oldExit := unhideFunc, exitErrorFn
+    var got string
+    unhideFunc = func(ctx context.Context, opts core.UnhideOptions) (*core.UnhideResult, error) {
+        got = opts.OnConflict
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    t.Cleanup(func() { unhideFunc, exitErrorFn = oldUnhide, oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/enc", "--output", "/tmp/out", "--on-conflict", "rename"}
+    main()
+    if got != "rename" {
+        t.Fatalf("expected rename policy, got %q", got)
+    }
+}
+
+// Cover passing --force on to the core used for hiding
+func TestMain_Hide_Force(t *testing.T) {
+    oldHide, oldExit := hideFunc, exitErrorFn
+    var got bool
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        got = opts.Force
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    t.Cleanup(func() { hideFunc, exitErrorFn = oldHide, oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--force"}
+    main()
+    if !got {
+        t.Fatalf("expected force to be set")
+    }
+}
+
+// Cover parsing --timestamps into the core used for hiding
+func TestMain_Hide_Timestamps(t *testing.T) {
+    oldHide, oldExit := hideFunc, exitErrorFn
+    var got string
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        got = opts.Timestamps.Strategy + "|" + opts.Timestamps.From.Format("2006-01-02")
+        return nil, nil
+    }
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() { hideFunc, exitErrorFn = oldHide, oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--timestamps", "range=2019-01-01,2020-01-01"}
+    main()
+    if got != "range|2019-01-01" || msg != "" {
+        t.Fatalf("unexpected timestamps %q (%q)", got, msg)
+    }
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--timestamps", "sometimes"}
+    main()
+    if !strings.Contains(msg, "--timestamps") {
+        t.Fatalf("expected invalid strategy to be rejected, got %q", msg)
+    }
+}
+
+// Cover passing --shred-source and --shred-passes on to the core used for hiding
+func TestMain_Hide_ShredSource(t *testing.T) {
+    oldHide, oldExit := hideFunc, exitErrorFn
+    var shred bool
+    var passes int
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        shred, passes = opts.ShredSource, opts.ShredPasses
+        return nil, nil
+    }
+    exitErrorFn = func(message string) { t.Fatalf("unexpected exit: %s", message) }
+    t.Cleanup(func() { hideFunc, exitErrorFn = oldHide, oldExit })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--shred-source", "--shred-passes", "7"}
+    main()
+    if !shred || passes != 7 {
+        t.Fatalf("unexpected shred settings: %v %d", shred, passes)
+    }
+}
+
+// Cover passing --resume on to hide and unhide, and its rejection when unhiding to stdout
+func TestMain_Resume(t *testing.T) {
+    oldHide, oldUnhide, oldExit := hideFunc, unhideFunc, exitErrorFn
+    var hideResume, unhideResume bool
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        hideResume = opts.Resume
+        return nil, nil
+    }
+    unhideFunc = func(ctx context.Context, opts core.UnhideOptions) (*core.UnhideResult, error) {
+        unhideResume = opts.Resume
+        return nil, nil
+    }
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() {
+        hideFunc, unhideFunc, exitErrorFn = oldHide, oldUnhide, oldExit
+        prettywriter.SetOutput(nil)
+    })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--resume"}
+    main()
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/enc", "--output", "/tmp/out", "--resume"}
+    main()
+    if !hideResume || !unhideResume || msg != "" {
+        t.Fatalf("expected resume to be passed on: %v %v %q", hideResume, unhideResume, msg)
+    }
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/enc", "--output", "-", "--resume"}
+    main()
+    if !strings.Contains(msg, "--resume") {
+        t.Fatalf("expected resume to stdout to be rejected, got %q", msg)
+    }
+}
+
+// Cover passing --jobs on to hide and unhide, and the rejection of negative values
+func TestMain_Jobs(t *testing.T) {
+    oldHide, oldUnhide, oldExit := hideFunc, unhideFunc, exitErrorFn
+    hideJobs, unhideJobs := -1, -1
+    hideFunc = func(ctx context.Context, opts core.HideOptions) (*core.HideResult, error) {
+        hideJobs = opts.Jobs
+        return nil, nil
+    }
+    unhideFunc = func(ctx context.Context, opts core.UnhideOptions) (*core.UnhideResult, error) {
+        unhideJobs = opts.Jobs
+        return nil, nil
+    }
+    var msg string
+    exitErrorFn = func(message string) { msg = message }
+    t.Cleanup(func() {
+        hideFunc, unhideFunc, exitErrorFn = oldHide, oldUnhide, oldExit
+        prettywriter.SetOutput(nil)
+    })
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--jobs", "4"}
+    main()
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--unhide", "--data", "/tmp/enc", "--output", "/tmp/out"}
+    main()
+    if hideJobs != 4 || unhideJobs != 0 || msg != "" {
+        t.Fatalf("expected jobs to be passed on: %d %d %q", hideJobs, unhideJobs, msg)
+    }
+
+    flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+    os.Args = []string{"tachicrypt", "--hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--jobs", "-1"}
+    main()
+    if !strings.Contains(msg, "--jobs") {
+        t.Fatalf("expected negative jobs to be rejected, got %q", msg)
+    }
+}
+
+func TestExitCode(t *testing.T) {
+    tests := []struct {
+        err  error
+        code int
+    }{
+        {err: fmt.Errorf("boom"), code: exitFailure},
+        {err: fmt.Errorf("error decrypting master lock file: %w", core.ErrWrongPassword), code: exitWrongPassword},
+        {err: fmt.Errorf("the masterlock requires 2 keyfiles, 0 given: %w", core.ErrKeyfiles), code: exitKeyfiles},
+        {err: &core.ErrPartMissing{Index: 1, Filename: "abc", Err: os.ErrNotExist}, code: exitPartMissing},
+        {err: fmt.Errorf("error decrypting part 1 (abc): %w", core.ErrPartCorrupt), code: exitPartCorrupt},
+        {err: fmt.Errorf("container format \"rar\" recorded in masterlock is %w", core.ErrUnsupportedVersion), code: exitUnsupportedVersion},
+        {err: fmt.Errorf("error unzipping data: %w", core.ErrUnsafePath), code: exitUnsafePath},
+        {err: &core.CanceledError{Stage: core.StageEncrypt, Err: context.Canceled}, code: exitInterrupted},
+    }
+    seen := map[int]bool{}
+    for _, tt := range tests {
+        if got := exitCode(tt.err); got != tt.code {
+            t.Fatalf("expected exit code %d for %v, got %d", tt.code, tt.err, got)
+        }
+        if seen[tt.code] {
+            t.Fatalf("exit code %d used twice", tt.code)
+        }
+        seen[tt.code] = true
+    }
+}
+
+// A wrong password exits with its own code
+func TestMain_Unhide_WrongPasswordExitCode(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "file.txt")
+    if err := os.WriteFile(src, []byte("x"), 0o644); err != nil {
+        t.Fatalf("write src: %v", err)
+    }
+    enc := filepath.Join(tmp, "enc")
+    prettywriter.SetOutput(io.Discard)
+    t.Cleanup(func() { prettywriter.SetOutput(nil) })
+    if _, err := core.Hide(context.Background(), core.HideOptions{Paths: []string{src}, Parts: 2, Output: enc, Password: core.StaticPassword("right")}); err != nil {
+        t.Fatalf("hide: %v", err)
+    }
+    os.Setenv("TACHICRYPT_PASSWORD", "wrong")
+    t.Cleanup(func() { os.Unsetenv("TACHICRYPT_PASSWORD") })
+
+    oldExit := exitErrorCodeFn
+    code := 0
+    exitErrorCodeFn = func(message string, c int) {
+        code = c
+        panic("test-exit")
+    }
+    t.Cleanup(func() { exitErrorCodeFn = oldExit })
+
+    func() {
+        defer func() { _ = recover() }()
+        flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+        os.Args = []string{"tachicrypt", "--unhide", "--data", enc, "--output", filepath.Join(tmp, "out")}
+        main()
+    }()
+    if code != exitWrongPassword {
+        t.Fatalf("expected exit code %d, got %d", exitWrongPassword, code)
+    }
+}
+
+func TestInterruptContext(t *testing.T) {
+    var signals chan<- os.Signal
+    oldNotify, oldStop, oldExit := signalNotifyFn, signalStopFn, interruptExitFn
+    signalNotifyFn = func(c chan<- os.Signal, sig ...os.Signal) { signals = c }
+    signalStopFn = func(chan<- os.Signal) {}
+    exited := make(chan struct{}, 1)
+    interruptExitFn = func() { exited <- struct{}{} }
+    t.Cleanup(func() { signalNotifyFn, signalStopFn, interruptExitFn = oldNotify, oldStop, oldExit })
+
+    // the first interrupt cancels the run so it can roll back, a second one exits
+    ctx, stop := interruptContext()
+    signals <- os.Interrupt
+    select {
+    case <-ctx.Done():
+    case <-time.After(5 * time.Second):
+        t.Fatalf("expected the interrupt to cancel the context")
+    }
+    select {
+    case <-exited:
+        t.Fatalf("expected the first interrupt not to exit")
+    default:
+    }
+    signals <- os.Interrupt
+    select {
+    case <-exited:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("expected the second interrupt to exit")
+    }
+    stop()
+
+    // at a password prompt there is nothing to roll back
+    ctx, stop = interruptContext()
+    defer stop()
+    prompting.Store(true)
+    t.Cleanup(func() { prompting.Store(false) })
+    signals <- os.Interrupt
+    select {
+    case <-exited:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("expected an interrupt at a prompt to exit")
+    }
+    if ctx.Err() != nil {
+        t.Fatalf("expected the context of a prompt to stay alive")
+    }
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+// test hook for exiting with the code of a failed run; defaults to utils.ExitErrorCode
+var exitErrorCodeFn = utils.ExitErrorCode
+
+// exit codes of the cli, documented in the README. Scripts rely on them, so they are never
+// renumbered. 2 is used for flags and commands which can't be parsed.
+const (
+	exitFailure            = 1
+	exitUsage              = 2
+	exitWrongPassword      = 3
+	exitPartMissing        = 4
+	exitPartCorrupt        = 5
+	exitUnsupportedVersion = 6
+	exitUnsafePath         = 7
+	exitKeyfiles           = 8
+	// exitInterrupted follows the shell convention of 128 + SIGINT
+	exitInterrupted = 130
+)
+
+// exitCode returns the exit code documented for the kind of err
+func exitCode(err error) int {
+	var missing *core.ErrPartMissing
+	var canceled *core.CanceledError
+	switch {
+	case errors.As(err, &canceled):
+		return exitInterrupted
+	case errors.Is(err, core.ErrWrongPassword):
+		return exitWrongPassword
+	case errors.Is(err, core.ErrKeyfiles):
+		return exitKeyfiles
+	case errors.As(err, &missing):
+		return exitPartMissing
+	case errors.Is(err, core.ErrPartCorrupt):
+		return exitPartCorrupt
+	case errors.Is(err, core.ErrUnsupportedVersion):
+		return exitUnsupportedVersion
+	case errors.Is(err, core.ErrUnsafePath):
+		return exitUnsafePath
+	}
+	return exitFailure
+}
+
+// exitRunError reports the failure of a run and exits with the code of its kind
+func exitRunError(message string, err error) {
+	exitErrorCodeFn(fmt.Sprintf("%s: %v \n", message, err), exitCode(err))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/passphrase"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+// wordCount is the flag.Value of --generate-password. Given without a value it asks for
+// passphrase.DefaultWords words, 0 means no password is generated.
+type wordCount int
+
+func (w *wordCount) String() string {
+	return strconv.Itoa(int(*w))
+}
+
+func (w *wordCount) Set(value string) error {
+	switch value {
+	case "true":
+		*w = passphrase.DefaultWords
+		return nil
+	case "false":
+		*w = 0
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a number of words of at least 1, got %q", value)
+	}
+	*w = wordCount(n)
+	return nil
+}
+
+// IsBoolFlag lets --generate-password be given without a value
+func (w *wordCount) IsBoolFlag() bool {
+	return true
+}
+
+// generatePassword creates a passphrase of the given number of words for hiding. It's shown
+// once, or written to out if given, before anything is hidden so it can't get lost on failure.
+// Scripts only get events telling where it went, they have to give out, see setupHide.
+func generatePassword(words int, out string, rep *reporter) (string, error) {
+	phrase, err := passphrase.Generate(words)
+	if err != nil {
+		return "", err
+	}
+	if rep.json != nil {
+		if err := writePassphrase(out, phrase); err != nil {
+			return "", err
+		}
+		rep.json.write(jsonEvent{Event: core.EventInfo, Message: "Generated password written to " + out})
+		rep.json.write(jsonEvent{Event: core.EventInfo, Message: strings.TrimPrefix(entropyLine(words), "[==] ")})
+		return phrase, nil
+	}
+	if out != "" {
+		if err := writePassphrase(out, phrase); err != nil {
+			return "", err
+		}
+		prettywriter.Writeln("[**] Generated password written to "+out, prettywriter.BlackBG, prettywriter.Green)
+	} else {
+		// shown with --quiet as well, it would be lost otherwise
+		defer prettywriter.SetQuiet(prettywriter.Quiet())
+		prettywriter.SetQuiet(false)
+		prettywriter.WriteInBox(prettywriter.Width(), "Generated password", prettywriter.BlackBG, prettywriter.Green, prettywriter.DoubleLine)
+		prettywriter.Println(phrase)
+		prettywriter.Writeln("[!!] It's shown only this once, store it somewhere safe", prettywriter.Yellow, prettywriter.BlackBG)
+	}
+	prettywriter.Writeln(entropyLine(words), prettywriter.Green, prettywriter.BlackBG)
+	prettywriter.Println("")
+	return phrase, nil
+}
+
+// writePassphrase writes phrase to a new file only the owner can read. An existing file is
+// never overwritten, it may hold the password of earlier hidden data.
+func writePassphrase(path, phrase string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating password file: %w", err)
+	}
+	if _, err := f.WriteString(phrase + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing password file: %w", err)
+	}
+	return f.Close()
+}
+
+// entropyLine states the entropy of a generated passphrase
+func entropyLine(words int) string {
+	return fmt.Sprintf("[==] %d words from a list of %d, %.1f bits of entropy", words, passphrase.Size(), passphrase.Entropy(words))
+}
+
+var genpassCommand = &command{
+	name:    "genpass",
+	usage:   "tachicrypt genpass [--out FILE] [WORDS]",
+	summary: "Generate a diceware passphrase without hiding anything",
+	maxArgs: 1,
+	examples: []string{
+		"Generate a passphrase only: tachicrypt genpass [--out file] [words]",
+		"Store a long passphrase: tachicrypt genpass --out ~/backup.pass 10",
+	},
+	setup: setupGenpass,
+}
+
+// setupGenpass defines the flags of genpass. The passphrase alone goes to stdout so it can be
+// piped, everything else to stderr.
+func setupGenpass(fs *flag.FlagSet) func(args []string) {
+	out := fs.String("out", "", "Write the passphrase to a new file with 0600 permissions instead of stdout")
+	return func(args []string) {
+		prettywriter.SetOutput(os.Stderr)
+		words := passphrase.DefaultWords
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				exitErrorFn(fmt.Sprintf("Invalid number of words %q, expected at least 1. \n", args[0]))
+				return
+			}
+			words = n
+		}
+
+		phrase, err := passphrase.Generate(words)
+		if err != nil {
+			exitErrorFn(fmt.Sprintf("Error generating password: %v \n", err))
+			return
+		}
+		if *out != "" {
+			if err := writePassphrase(*out, phrase); err != nil {
+				exitErrorFn(err.Error() + ". \n")
+				return
+			}
+			prettywriter.Writeln("[**] Generated password written to "+*out, prettywriter.BlackBG, prettywriter.Green)
+		} else {
+			fmt.Fprintln(stdout, phrase)
+		}
+		prettywriter.Writeln(entropyLine(words), prettywriter.Green, prettywriter.BlackBG)
+	}
+}
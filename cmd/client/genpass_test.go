@@ -0,0 +1,104 @@
+package main
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/passphrase"
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+func TestWordCount_Set(t *testing.T) {
+    var w wordCount
+    if err := w.Set("true"); err != nil || int(w) != passphrase.DefaultWords {
+        t.Fatalf("expected the bare flag to ask for %d words, got %d (%v)", passphrase.DefaultWords, w, err)
+    }
+    if err := w.Set("8"); err != nil || w != 8 {
+        t.Fatalf("expected 8 words, got %d (%v)", w, err)
+    }
+    for _, bad := range []string{"0", "-1", "many"} {
+        if err := w.Set(bad); err == nil {
+            t.Fatalf("expected %q to be rejected", bad)
+        }
+    }
+}
+
+func TestMain_GeneratePassword(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "file.txt")
+    if err := os.WriteFile(src, []byte("ok"), 0o644); err != nil {
+        t.Fatalf("write src: %v", err)
+    }
+    secret := filepath.Join(tmp, "secret")
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+
+    if called, msg := runClient(t, "--hide", "--parts", "2", "--data", src, "--output", enc, "--generate-password=4", "--password-out", secret); called {
+        t.Fatalf("hide failed: %s", msg)
+    }
+    info, err := os.Stat(secret)
+    if err != nil || info.Mode().Perm() != 0o600 {
+        t.Fatalf("expected the password file with 0600 permissions, got %v (%v)", info, err)
+    }
+    b, _ := os.ReadFile(secret)
+    if words := strings.Fields(string(b)); len(words) != 4 {
+        t.Fatalf("expected a passphrase of 4 words, got %q", b)
+    }
+    if called, msg := runClient(t, "--unhide", "--data", enc, "--output", out, "--password-file", secret); called {
+        t.Fatalf("unhide with the generated password failed: %s", msg)
+    }
+    if b, err := os.ReadFile(filepath.Join(out, "file.txt")); err != nil || string(b) != "ok" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+
+    // an existing password file is never overwritten
+    if called, _ := runClient(t, "--hide", "--parts", "2", "--data", src, "--output", filepath.Join(tmp, "enc2"), "--generate-password", "--password-out", secret); !called {
+        t.Fatalf("expected an existing --password-out file to be refused")
+    }
+    if _, err := os.Stat(filepath.Join(tmp, "enc2")); err == nil {
+        t.Fatalf("expected nothing to be hidden without the password being stored")
+    }
+}
+
+func TestMain_GeneratePasswordRejected(t *testing.T) {
+    tests := [][]string{
+        {"--unhide", "--data", "x", "--output", "y", "--generate-password"},
+        {"--hide", "--parts", "2", "--data", "x", "--output", "y", "--generate-password", "--password-file", "a"},
+        {"--hide", "--parts", "2", "--data", "x", "--output", "y", "--password-out", "a"},
+        {"--hide", "--parts", "2", "--data", "x", "--output", "y", "--generate-password=3", "--min-entropy", "60"},
+    }
+    for _, args := range tests {
+        if called, _ := runClient(t, args...); !called {
+            t.Fatalf("expected %v to be rejected", args)
+        }
+    }
+}
+
+func TestMain_Genpass(t *testing.T) {
+    var buf bytes.Buffer
+    oldStdout := stdout
+    stdout = &buf
+    t.Cleanup(func() { stdout = oldStdout; prettywriter.SetOutput(nil) })
+
+    if called, msg := runClient(t, "genpass", "5"); called {
+        t.Fatalf("genpass failed: %s", msg)
+    }
+    if words := strings.Fields(buf.String()); len(words) != 5 {
+        t.Fatalf("expected only the 5 words on stdout, got %q", buf.String())
+    }
+
+    buf.Reset()
+    secret := filepath.Join(t.TempDir(), "secret")
+    if called, msg := runClient(t, "genpass", "--out", secret); called {
+        t.Fatalf("genpass --out failed: %s", msg)
+    }
+    if b, err := os.ReadFile(secret); err != nil || len(strings.Fields(string(b))) != passphrase.DefaultWords || buf.Len() != 0 {
+        t.Fatalf("expected the passphrase in the file only, got %q and stdout %q (%v)", b, buf.String(), err)
+    }
+    if called, _ := runClient(t, "genpass", "0"); !called {
+        t.Fatalf("expected a passphrase without words to be refused")
+    }
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/fileutils"
+	"github.com/voodooEntity/go-tachicrypt/src/passphrase"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+var hideCommand = &command{
+	name:    "hide",
+	usage:   "tachicrypt hide --parts N --data PATH --output DIR [options]",
+	summary: "Hide (encrypt) data in parts and a masterlock",
+	examples: []string{
+		"Encrypt data: tachicrypt hide --parts 10 --data /path/to/data --output /path/to/output",
+		"Encrypt several paths: tachicrypt hide --parts 10 --data ~/keys --data ~/notes.md --output /path/to/output",
+		"Encrypt a pipe: pg_dump db | tachicrypt hide --data - --name db.sql --parts 8 --output /path/to/output",
+		"Preview data: tachicrypt hide --dry-run --data /path/to/data --exclude node_modules/ --exclude '*.log'",
+		"Encrypt with a generated password: tachicrypt hide --parts 10 --data /path/to/data --output /path/to/output --generate-password --password-out ~/backup.pass",
+	},
+	setup: setupHide,
+}
+
+// setupHide defines the flags of hide
+func setupHide(fs *flag.FlagSet) func(args []string) {
+	var dataPaths stringList
+	fs.Var(&dataPaths, "data", "Path to the data file or directory to hide, or - for stdin (repeatable)")
+	dataFrom := fs.String("data-from", "", "File listing paths to hide, one per line, or - for stdin")
+	streamName := fs.String("name", "stdin", "File name data hidden from stdin (--data -) is stored as")
+	partCount := fs.Int("parts", -1, "Amount of parts that should be created")
+	outputDir := fs.String("output", "", "Output directory for the parts and masterlock")
+	stripMetadata := fs.Bool("strip-metadata", false, "Do not store permissions, ownership and timestamps")
+	followSymlinks := fs.Bool("follow-symlinks", false, "Hide the targets of symlinks instead of the links themselves")
+	containerFormat := fs.String("format", "zip", "Container format: zip, tar or tar.zst")
+	compression := fs.String("compression", "", "Compression method: store, deflate or zstd (zip only)")
+	level := fs.Int("level", 0, "Compression level (deflate 1-9, zstd 1-22), 0 uses the method's default")
+	var excludes stringList
+	fs.Var(&excludes, "exclude", "Gitignore style pattern of paths to leave out (repeatable)")
+	dryRun := fs.Bool("dry-run", false, "List what would be hidden without encrypting anything")
+	timestamps := fs.String("timestamps", "random", "Timestamps of the written parts: random, epoch[=DATE], range=FROM,TO or reference=DIR")
+	shredSource := fs.Bool("shred-source", false, "Wipe the hidden files after the stored data has been verified")
+	shredPasses := fs.Int("shred-passes", 3, "How often --shred-source overwrites every file with random data")
+	resume := fs.Bool("resume", false, "Keep the work of an interrupted hide and continue it into the same output directory")
+	jobs := fs.Int("jobs", 0, "Number of parts encrypted at once, 0 uses one per CPU")
+	force := fs.Bool("force", false, "Hide into an output directory which already contains files")
+	passwords := addPasswordFlags(fs, "", "masterlock password")
+	minEntropy := fs.Float64("min-entropy", 0, "Refuse passwords estimated to have fewer bits of entropy")
+	var generateWords wordCount
+	fs.Var(&generateWords, "generate-password", "Hide with a generated diceware passphrase, optionally =WORDS long (default 6)")
+	passwordOut := fs.String("password-out", "", "Write the generated password to a new file with 0600 permissions instead of showing it")
+	keyfileOnly := fs.Bool("keyfile-only", false, "Hide with the keyfiles alone, without a password")
+	format := addOutputFormat(fs)
+
+	return func([]string) {
+		rep := newReporter("hide", *format)
+		dataPaths := dataPaths
+		// with data piped in, stdout is kept clean and all output goes to stderr
+		readStdin := contains(dataPaths, "-")
+		if readStdin {
+			prettywriter.SetOutput(os.Stderr)
+		}
+		if readStdin && (len(dataPaths) > 1 || *dataFrom != "" || *dryRun) {
+			rep.invalid("--data - can only be used alone and without --dry-run. \n")
+			return
+		}
+		if *jobs < 0 {
+			rep.invalid("--jobs can't be negative. \n")
+			return
+		}
+		password, err := passwordProvider(os.Getenv("TACHICRYPT_PASSWORD"), passwords.sources, *minEntropy)
+		if err != nil {
+			rep.invalid(err.Error() + ". \n")
+			return
+		}
+		if generateWords > 0 && (*dryRun || passwords.sources.count() > 0 || os.Getenv("TACHICRYPT_PASSWORD") != "") {
+			rep.invalid("--generate-password only works without --dry-run and without another password source. \n")
+			return
+		}
+		if *keyfileOnly && (len(passwords.keyfiles) == 0 || passwords.sources.count() > 0 || generateWords > 0 || os.Getenv("TACHICRYPT_PASSWORD") != "") {
+			rep.invalid("--keyfile-only needs at least one --keyfile, and can't be combined with a password source. \n")
+			return
+		}
+		if *passwordOut != "" && generateWords == 0 {
+			rep.invalid("--password-out requires --generate-password. \n")
+			return
+		}
+		if generateWords > 0 && *passwordOut == "" && *format == formatJSON {
+			rep.invalid("--generate-password requires --password-out with --output-format json, the events aren't the place for a password. \n")
+			return
+		}
+		if generateWords > 0 && passphrase.Entropy(int(generateWords)) < *minEntropy {
+			rep.invalid(fmt.Sprintf("%d generated words have %.1f bits of entropy, less than --min-entropy requires. \n", int(generateWords), passphrase.Entropy(int(generateWords))))
+			return
+		}
+		if *minEntropy < 0 {
+			rep.invalid("--min-entropy can't be negative. \n")
+			return
+		}
+		if readStdin && passwords.sources.fd == 0 {
+			rep.invalid("--password-fd 0 can't be used with --data -, stdin carries the data. \n")
+			return
+		}
+
+		if *dataFrom != "" {
+			listed, err := readPathList(*dataFrom)
+			if err != nil {
+				rep.invalid(fmt.Sprintf("Error reading --data-from list: %v \n", err))
+				return
+			}
+			dataPaths = append(dataPaths, listed...)
+		}
+
+		if *dryRun {
+			if len(dataPaths) == 0 {
+				rep.invalid("--dry-run requires --data to be specified. \n")
+				return
+			}
+			opts := newHideOptions(*stripMetadata, *followSymlinks, *containerFormat, *compression, *level, excludes)
+			opts.Paths = dataPaths
+			names, err := dryRunFunc(opts)
+			if err != nil {
+				rep.invalid(fmt.Sprintf("Error listing data: %v \n", err))
+				return
+			}
+			if *format == formatJSON {
+				rep.finish("", dryRunJSON{Entries: names})
+				return
+			}
+			printDryRun(names)
+			return
+		}
+
+		if *partCount == -1 {
+			rep.invalid("Missing mandatory --parts parameter \n")
+			return
+		}
+		if len(dataPaths) == 0 || *outputDir == "" {
+			rep.invalid("Both --data and --output must be specified. \n")
+			return
+		}
+		ts, err := fileutils.ParseTimestamps(*timestamps)
+		if err != nil {
+			rep.invalid(fmt.Sprintf("Invalid --timestamps: %v \n", err))
+			return
+		}
+
+		rep.start()
+
+		opts := newHideOptions(*stripMetadata, *followSymlinks, *containerFormat, *compression, *level, excludes)
+		opts.Parts = *partCount
+		opts.Output = *outputDir
+		opts.Force = *force
+		opts.Jobs = *jobs
+		opts.Resume = *resume
+		opts.ShredSource = *shredSource
+		opts.ShredPasses = *shredPasses
+		opts.MinEntropy = *minEntropy
+		opts.Keyfiles = passwords.keyfiles
+		opts.KeyfileOnly = *keyfileOnly
+		opts.Timestamps = ts
+		if generateWords > 0 {
+			generated, err := generatePassword(int(generateWords), *passwordOut, rep)
+			if err != nil {
+				rep.invalid(fmt.Sprintf("Error generating password: %v \n", err))
+				return
+			}
+			password = core.StaticPassword(generated)
+		}
+		if readStdin {
+			opts.Reader, opts.Name = stdin, *streamName
+		} else {
+			opts.Paths = dataPaths
+		}
+		opts.Observer = rep.observer()
+		opts.Password = password
+		ctx, stop := interruptContext()
+		defer stop()
+		result, err := hideFunc(ctx, opts)
+		if err != nil {
+			rep.fail("Error hiding data", err)
+			return
+		}
+		rep.finish("Encryption finished", newHideJSON(result))
+	}
+}
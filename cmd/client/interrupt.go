@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// test hooks for the interrupt handling; default to real implementations
+var (
+	signalNotifyFn  = signal.Notify
+	signalStopFn    = signal.Stop
+	interruptExitFn = func() { os.Exit(exitInterrupted) }
+)
+
+// prompting is set while a password is read from the terminal, see promptPassword
+var prompting atomic.Bool
+
+// interruptContext returns the context a run is stopped with once the process gets an
+// interrupt or SIGTERM, core then rolls back what it staged and returns a *core.CanceledError.
+// Interrupts at a password prompt, where nothing is staged yet, and a second interrupt of a
+// run taking its time to roll back exit right away. stop releases the signals again.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signalNotifyFn(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if prompting.Load() || ctx.Err() != nil {
+					interruptExitFn()
+					return
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signalStopFn(signals)
+		close(done)
+		cancel()
+	}
+}
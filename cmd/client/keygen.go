@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+var keygenCommand = &command{
+	name:    "keygen",
+	usage:   "tachicrypt keygen [--size BYTES] FILE",
+	summary: "Create a keyfile of random bytes",
+	maxArgs: 1,
+	examples: []string{
+		"Create a keyfile: tachicrypt keygen /media/usb/backup.key",
+		"Encrypt with it: tachicrypt hide --parts 10 --data /path/to/data --output /path/to/output --keyfile /media/usb/backup.key",
+	},
+	setup: setupKeygen,
+}
+
+// setupKeygen defines the flags of keygen
+func setupKeygen(fs *flag.FlagSet) func(args []string) {
+	size := fs.Int("size", core.DefaultKeyfileSize, "Number of random bytes written to the keyfile")
+	return func(args []string) {
+		if len(args) != 1 {
+			exitErrorFn("Usage: tachicrypt keygen [--size BYTES] FILE \n")
+			return
+		}
+		if err := core.GenerateKeyfile(args[0], *size); err != nil {
+			exitErrorFn(err.Error() + ". \n")
+			return
+		}
+		prettywriter.Writeln("[**] Keyfile written to "+args[0], prettywriter.BlackBG, prettywriter.Green)
+		prettywriter.Writeln("[==] "+strconv.Itoa(*size)+" random bytes", prettywriter.Green, prettywriter.BlackBG)
+		prettywriter.Writeln("[!!] Data hidden with it can't be unhidden without it, keep a copy somewhere safe", prettywriter.Yellow, prettywriter.BlackBG)
+	}
+}
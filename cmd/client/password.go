@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+	"github.com/voodooEntity/go-tachicrypt/src/strength"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+// test hook for reading the masterlock password from the terminal
+var promptPasswordFn = utils.PromptForPassword
+
+// promptPassword asks for the masterlock password on the terminal
+type promptPassword struct {
+	// minEntropy refuses weaker passwords right away when hiding, instead of failing the run
+	minEntropy float64
+}
+
+// Password prompts for the password, worded after what it's needed for. A new password has to
+// be entered twice, as a typo would make the hidden data unrecoverable.
+func (p promptPassword) Password(purpose core.Purpose) (string, error) {
+	// prompts and their errors are shown with --quiet as well, nobody types a password blindly
+	defer prettywriter.SetQuiet(prettywriter.Quiet())
+	prettywriter.SetQuiet(false)
+	// nothing is staged yet, an interrupt exits right away instead of waiting for the input
+	prompting.Store(true)
+	defer prompting.Store(false)
+	prompt := "Please enter a password to encrypt the masterlock: "
+	switch purpose {
+	case core.PurposeUnhide:
+		password, err := promptPasswordFn("Enter the password to decrypt the masterlock: ")
+		prettywriter.Println("")
+		return password, err
+	case core.PurposeRekey:
+		prompt = "Please enter the new password of the masterlock: "
+	}
+	for attempt := 1; ; attempt++ {
+		password, err := promptPasswordFn(prompt)
+		if err != nil {
+			return "", err
+		}
+		var problem string
+		if bits := strength.Entropy(password); bits < p.minEntropy {
+			problem = fmt.Sprintf("Error: Password too weak, estimated %.0f bits of entropy but --min-entropy requires %.0f.", bits, p.minEntropy)
+		} else {
+			confirmation, err := promptPasswordFn("Please repeat the password: ")
+			if err != nil {
+				return "", err
+			}
+			if confirmation == password {
+				prettywriter.Println("")
+				return password, nil
+			}
+			problem = "Error: The passwords don't match."
+		}
+		prettywriter.Writeln(problem, prettywriter.Red, prettywriter.BlackBG)
+		if attempt == utils.PasswordAttempts {
+			return "", fmt.Errorf("no password confirmed after %d attempts", utils.PasswordAttempts)
+		}
+	}
+}
+
+// passwordSources are the non-interactive password sources given by flag
+type passwordSources struct {
+	file    string
+	fd      int
+	command string
+}
+
+// passwordFlags are the flags choosing where a masterlock password and keyfiles come from
+type passwordFlags struct {
+	sources  passwordSources
+	keyfiles stringList
+}
+
+// addPasswordFlags defines the password source and keyfile flags on fs. Their names start with
+// prefix, so rekey can take the flags of the new password next to those of the current one.
+func addPasswordFlags(fs *flag.FlagSet, prefix, what string) *passwordFlags {
+	p := &passwordFlags{}
+	fs.StringVar(&p.sources.file, prefix+"password-file", "", "Read the "+what+" from the first line of a file")
+	fs.IntVar(&p.sources.fd, prefix+"password-fd", -1, "Read the "+what+" from an inherited file descriptor, e.g. 3 with 3<secret")
+	fs.StringVar(&p.sources.command, prefix+"password-command", "", "Use the first line printed by a shell command as the "+what)
+	fs.Var(&p.keyfiles, prefix+"keyfile", "Keyfile mixed into the masterlock key together with the "+what+" (repeatable)")
+	return p
+}
+
+// readPasswordFlags returns where the password of an existing masterlock comes from. Invalid
+// flags are reported to rep and false is returned.
+func readPasswordFlags(p *passwordFlags, attempts int, rep *reporter) (core.PasswordProvider, bool) {
+	if attempts < 1 {
+		rep.invalid("--attempts has to be at least 1. \n")
+		return nil, false
+	}
+	password, err := passwordProvider(os.Getenv("TACHICRYPT_PASSWORD"), p.sources, 0)
+	if err != nil {
+		rep.invalid(err.Error() + ". \n")
+		return nil, false
+	}
+	return password, true
+}
+
+// count returns how many sources are given
+func (s passwordSources) count() int {
+	n := 0
+	for _, given := range []bool{s.file != "", s.fd >= 0, s.command != ""} {
+		if given {
+			n++
+		}
+	}
+	return n
+}
+
+// passwordProvider returns where the masterlock password comes from: the source given by flag,
+// else the TACHICRYPT_PASSWORD environment variable if it's set, else the terminal. The terminal
+// is never prompted while a source is given.
+func passwordProvider(prefilled string, sources passwordSources, minEntropy float64) (core.PasswordProvider, error) {
+	if sources.count() > 1 {
+		return nil, fmt.Errorf("only one of --password-file, --password-fd and --password-command can be given")
+	}
+	switch {
+	case sources.file != "":
+		return core.PasswordFile(sources.file), nil
+	case sources.fd >= 0:
+		return core.PasswordFD(sources.fd), nil
+	case sources.command != "":
+		return core.PasswordCommand(sources.command), nil
+	case prefilled != "":
+		return core.StaticPassword(prefilled), nil
+	}
+	return promptPassword{minEntropy: minEntropy}, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+    "bytes"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/core"
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+    "github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+func TestPasswordProvider(t *testing.T) {
+    none := passwordSources{fd: -1}
+    p, err := passwordProvider("from-env", none, 0)
+    if err != nil {
+        t.Fatalf("passwordProvider: %v", err)
+    }
+    if got, _ := p.Password(core.PurposeHide); got != "from-env" {
+        t.Fatalf("expected the environment password, got %q", got)
+    }
+
+    oldPrompt := promptPasswordFn
+    var prompts []string
+    promptPasswordFn = func(prompt string) (string, error) {
+        prompts = append(prompts, prompt)
+        return "typed", nil
+    }
+    var buf bytes.Buffer
+    prettywriter.SetOutput(&buf)
+    t.Cleanup(func() { promptPasswordFn = oldPrompt; prettywriter.SetOutput(nil) })
+
+    p, _ = passwordProvider("", none, 0)
+    hidePwd, _ := p.Password(core.PurposeHide)
+    unhidePwd, _ := p.Password(core.PurposeUnhide)
+    if hidePwd != "typed" || unhidePwd != "typed" || len(prompts) != 3 {
+        t.Fatalf("expected the terminal to be prompted three times, got %q %q %v", hidePwd, unhidePwd, prompts)
+    }
+    if !strings.Contains(prompts[0], "encrypt") || !strings.Contains(prompts[1], "repeat") || !strings.Contains(prompts[2], "decrypt") {
+        t.Fatalf("expected prompts worded after their purpose, got %v", prompts)
+    }
+}
+
+// stubPrompts answers the prompts with the given passwords in turn
+func stubPrompts(t *testing.T, answers ...string) *int {
+    t.Helper()
+    oldPrompt := promptPasswordFn
+    calls := 0
+    promptPasswordFn = func(prompt string) (string, error) {
+        if calls == len(answers) {
+            t.Fatalf("unexpected prompt %q", prompt)
+        }
+        calls++
+        return answers[calls-1], nil
+    }
+    prettywriter.SetOutput(io.Discard)
+    t.Cleanup(func() { promptPasswordFn = oldPrompt; prettywriter.SetOutput(nil) })
+    return &calls
+}
+
+func TestPromptPassword_ConfirmsNewPasswords(t *testing.T) {
+    // a typo in the repetition is asked again
+    stubPrompts(t, "first", "frist", "second", "second")
+    if got, err := (promptPassword{}).Password(core.PurposeHide); err != nil || got != "second" {
+        t.Fatalf("expected the confirmed password, got %q (%v)", got, err)
+    }
+
+    calls := stubPrompts(t, "a", "b", "c", "d", "e", "f")
+    if _, err := (promptPassword{}).Password(core.PurposeHide); err == nil || *calls != 6 {
+        t.Fatalf("expected to give up after %d attempts, got %v after %d prompts", utils.PasswordAttempts, err, *calls)
+    }
+}
+
+func TestPromptPassword_MinEntropy(t *testing.T) {
+    // a weak password is refused without asking for the repetition
+    stubPrompts(t, "password", "gumdrop unplug stuffed sushi", "gumdrop unplug stuffed sushi")
+    got, err := (promptPassword{minEntropy: 50}).Password(core.PurposeHide)
+    if err != nil || got != "gumdrop unplug stuffed sushi" {
+        t.Fatalf("expected the strong password, got %q (%v)", got, err)
+    }
+}
+
+func TestPasswordProvider_Sources(t *testing.T) {
+    tests := []struct {
+        sources passwordSources
+        want    core.PasswordProvider
+    }{
+        {sources: passwordSources{file: "/secret", fd: -1}, want: core.PasswordFile("/secret")},
+        {sources: passwordSources{fd: 3}, want: core.PasswordFD(3)},
+        {sources: passwordSources{command: "pass show x", fd: -1}, want: core.PasswordCommand("pass show x")},
+    }
+    for _, tt := range tests {
+        // a given source beats the environment variable
+        got, err := passwordProvider("from-env", tt.sources, 0)
+        if err != nil || got != tt.want {
+            t.Fatalf("expected %#v for %+v, got %#v (%v)", tt.want, tt.sources, got, err)
+        }
+    }
+    if _, err := passwordProvider("", passwordSources{file: "/secret", fd: 3}, 0); err == nil {
+        t.Fatalf("expected two sources to be rejected")
+    }
+}
+
+func TestMain_PasswordFile_NeverPrompts(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "file.txt")
+    if err := os.WriteFile(src, []byte("ok"), 0o644); err != nil {
+        t.Fatalf("write src: %v", err)
+    }
+    secret := filepath.Join(tmp, "secret")
+    if err := os.WriteFile(secret, []byte("from-file\n"), 0o600); err != nil {
+        t.Fatalf("write secret: %v", err)
+    }
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+
+    oldPrompt := promptPasswordFn
+    promptPasswordFn = func(prompt string) (string, error) {
+        t.Fatalf("unexpected prompt %q", prompt)
+        return "", nil
+    }
+    t.Cleanup(func() { promptPasswordFn = oldPrompt })
+
+    if called, msg := runClient(t, "--hide", "--parts", "2", "--data", src, "--output", enc, "--password-file", secret); called {
+        t.Fatalf("hide failed: %s", msg)
+    }
+    if called, msg := runClient(t, "--unhide", "--data", enc, "--output", out, "--password-file", secret); called {
+        t.Fatalf("unhide failed: %s", msg)
+    }
+    if b, err := os.ReadFile(filepath.Join(out, "file.txt")); err != nil || string(b) != "ok" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+
+    // the password really came from the file
+    if err := os.WriteFile(secret, []byte("other\n"), 0o600); err != nil {
+        t.Fatalf("write secret: %v", err)
+    }
+    if called, _ := runClient(t, "--unhide", "--data", enc, "--output", filepath.Join(tmp, "out2"), "--password-file", secret); !called {
+        t.Fatalf("expected unhide with another password to fail")
+    }
+}
+
+func TestMain_PasswordSourcesRejected(t *testing.T) {
+    tests := [][]string{
+        {"--hide", "--parts", "2", "--data", "x", "--output", "y", "--password-file", "a", "--password-command", "b"},
+        {"--hide", "--parts", "2", "--data", "-", "--output", "y", "--password-fd", "0"},
+    }
+    for _, args := range tests {
+        if called, _ := runClient(t, args...); !called {
+            t.Fatalf("expected %v to be rejected", args)
+        }
+    }
+}
+
+func TestMain_KeyfileOnly(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "file.txt")
+    keyfile := filepath.Join(tmp, "job.key")
+    for path, content := range map[string]string{src: "ok", keyfile: "random keyfile bytes"} {
+        if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+            t.Fatalf("write %s: %v", path, err)
+        }
+    }
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+
+    oldPrompt := promptPasswordFn
+    promptPasswordFn = func(prompt string) (string, error) {
+        t.Fatalf("unexpected prompt %q", prompt)
+        return "", nil
+    }
+    t.Cleanup(func() { promptPasswordFn = oldPrompt })
+
+    if called, msg := runClient(t, "--hide", "--parts", "2", "--data", src, "--output", enc, "--keyfile", keyfile, "--keyfile-only"); called {
+        t.Fatalf("hide failed: %s", msg)
+    }
+    if called, msg := runClient(t, "--unhide", "--data", enc, "--output", out, "--keyfile", keyfile); called {
+        t.Fatalf("unhide failed: %s", msg)
+    }
+    if b, err := os.ReadFile(filepath.Join(out, "file.txt")); err != nil || string(b) != "ok" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+
+    rejected := [][]string{
+        {"--hide", "--parts", "2", "--data", src, "--output", enc, "--keyfile-only"},
+        {"--hide", "--parts", "2", "--data", src, "--output", enc, "--keyfile", keyfile, "--keyfile-only", "--password-file", keyfile},
+        {"--unhide", "--data", enc, "--output", out, "--keyfile", keyfile, "--keyfile-only"},
+    }
+    for _, args := range rejected {
+        if called, _ := runClient(t, args...); !called {
+            t.Fatalf("expected %v to be rejected", args)
+        }
+    }
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+var rekeyCommand = &command{
+	name:    "rekey",
+	usage:   "tachicrypt rekey --data DIR [options]",
+	summary: "Change the password or keyfiles of the masterlock, leaving the parts as they are",
+	examples: []string{
+		"Change the password: tachicrypt rekey --data /path/to/encrypted/data",
+		"Add a keyfile: tachicrypt rekey --data /path/to/encrypted/data --new-keyfile /media/usb/backup.key",
+	},
+	setup: setupRekey,
+}
+
+// setupRekey defines the flags of rekey
+func setupRekey(fs *flag.FlagSet) func(args []string) {
+	dataDir := fs.String("data", "", "Directory holding the parts and masterlock")
+	passwords := addPasswordFlags(fs, "", "current masterlock password")
+	attempts := fs.Int("attempts", utils.PasswordAttempts, "Attempts for the current password when it's typed in")
+	newPasswords := addPasswordFlags(fs, "new-", "new masterlock password")
+	newKeyfileOnly := fs.Bool("new-keyfile-only", false, "Encrypt the masterlock with the new keyfiles alone, without a password")
+	minEntropy := fs.Float64("min-entropy", 0, "Refuse new passwords estimated to have fewer bits of entropy")
+	format := addOutputFormat(fs)
+
+	return func([]string) {
+		rep := newReporter("rekey", *format)
+		password, ok := readPasswordFlags(passwords, *attempts, rep)
+		if !ok {
+			return
+		}
+		if newPasswords.sources.count() > 1 {
+			rep.invalid("only one of --new-password-file, --new-password-fd and --new-password-command can be given. \n")
+			return
+		}
+		if passwords.sources.fd >= 0 && passwords.sources.fd == newPasswords.sources.fd {
+			rep.invalid("--password-fd and --new-password-fd can't be the same descriptor, it's used up by reading the current password. \n")
+			return
+		}
+		if *newKeyfileOnly && (len(newPasswords.keyfiles) == 0 || newPasswords.sources.count() > 0) {
+			rep.invalid("--new-keyfile-only needs at least one --new-keyfile, and can't be combined with a new password source. \n")
+			return
+		}
+		if *minEntropy < 0 {
+			rep.invalid("--min-entropy can't be negative. \n")
+			return
+		}
+		// the environment holds the current password, the new one never comes from there
+		newPassword, err := passwordProvider("", newPasswords.sources, *minEntropy)
+		if err != nil {
+			rep.invalid(err.Error() + ". \n")
+			return
+		}
+		if *dataDir == "" {
+			rep.invalid("Missing mandatory --data parameter \n")
+			return
+		}
+
+		rep.start()
+
+		opts := core.RekeyOptions{
+			Input:          *dataDir,
+			Password:       password,
+			Keyfiles:       passwords.keyfiles,
+			NewPassword:    newPassword,
+			NewKeyfiles:    newPasswords.keyfiles,
+			NewKeyfileOnly: *newKeyfileOnly,
+			MinEntropy:     *minEntropy,
+			Observer:       rep.observer(),
+		}
+		if _, typed := password.(promptPassword); typed {
+			opts.PasswordAttempts = *attempts
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		if err := rekeyFunc(ctx, opts); err != nil {
+			rep.fail("Error rekeying data", err)
+			return
+		}
+		rep.finish("Rekeying finished", rekeyJSON{Masterlock: filepath.Join(*dataDir, "masterlock")})
+	}
+}
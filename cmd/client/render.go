@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+// stageTitles are the headings of the boxes each stage starts with
+var stageTitles = map[core.Stage]string{
+	core.StageConfigure:  "Configuration",
+	core.StagePack:       "Packing input data",
+	core.StageEncrypt:    "Encrypting parts",
+	core.StageMasterlock: "Handling masterlock",
+	core.StagePublish:    "Publishing",
+	core.StageTimestamps: "Final Shenanigans",
+	core.StageVerify:     "Verifying stored data",
+	core.StageShred:      "Wiping source",
+	core.StageDecrypt:    "Decrypting parts",
+	core.StageUnpack:     "Unpacking data",
+}
+
+// renderer draws the events of a run with prettywriter
+type renderer struct {
+	// progressing is set while a progress line waits to be overwritten or finished
+	progressing bool
+}
+
+// Observe renders e
+func (r *renderer) Observe(e core.Event) {
+	if e.Kind == core.EventProgress {
+		// logs get the finished count only instead of a line rewritten for every step
+		if !prettywriter.Terminal() {
+			if e.Done == e.Total {
+				prettywriter.Writeln("[>>] "+e.Message+" : "+strconv.Itoa(e.Done)+"/"+strconv.Itoa(e.Total), prettywriter.Green, prettywriter.BlackBG)
+			}
+			return
+		}
+		prettywriter.Print("\r")
+		prettywriter.Write("[>>] "+e.Message+" : "+strconv.Itoa(e.Done)+"/"+strconv.Itoa(e.Total), prettywriter.Green, prettywriter.BlackBG)
+		r.progressing = true
+		return
+	}
+	if r.progressing {
+		prettywriter.Println("")
+		r.progressing = false
+	}
+	switch e.Kind {
+	case core.EventStageStarted:
+		if e.Stage == core.StageConfigure {
+			prettywriter.WriteInBox(prettywriter.Width(), stageTitles[e.Stage], prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
+		} else {
+			prettywriter.WriteInBox(prettywriter.Width(), stageTitles[e.Stage], prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
+		}
+	case core.EventStageFinished:
+		if e.Message != "" {
+			prettywriter.Writeln("[**] "+e.Message, prettywriter.BlackBG, prettywriter.Green)
+		}
+		prettywriter.Println("")
+	case core.EventInfo:
+		prefix := "[>>] "
+		if e.Stage == core.StageConfigure {
+			prefix = "[==] "
+		}
+		prettywriter.Writeln(prefix+e.Message, prettywriter.Green, prettywriter.BlackBG)
+	case core.EventWarning:
+		prettywriter.Writeln("[!!] "+e.Message, prettywriter.Yellow, prettywriter.BlackBG)
+	}
+}
+
+// finish ends a pending progress line and closes the run with a box saying so
+func (r *renderer) finish(title string) {
+	if r.progressing {
+		prettywriter.Println("")
+		r.progressing = false
+	}
+	prettywriter.WriteInBox(prettywriter.Width(), title, prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
+}
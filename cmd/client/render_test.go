@@ -0,0 +1,41 @@
+package main
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/core"
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+func TestRenderer_DrawsEvents(t *testing.T) {
+    var buf bytes.Buffer
+    prettywriter.SetOutput(&buf)
+    t.Cleanup(func() { prettywriter.SetOutput(nil) })
+
+    r := &renderer{}
+    r.Observe(core.Event{Kind: core.EventStageStarted, Stage: core.StageConfigure})
+    r.Observe(core.Event{Kind: core.EventInfo, Stage: core.StageConfigure, Message: "Output path: /tmp/out"})
+    r.Observe(core.Event{Kind: core.EventStageStarted, Stage: core.StageEncrypt})
+    r.Observe(core.Event{Kind: core.EventProgress, Stage: core.StageEncrypt, Message: "Encrypt and store parts", Done: 1, Total: 2})
+    r.Observe(core.Event{Kind: core.EventProgress, Stage: core.StageEncrypt, Message: "Encrypt and store parts", Done: 2, Total: 2})
+    r.Observe(core.Event{Kind: core.EventWarning, Stage: core.StageEncrypt, Message: "careful"})
+    r.Observe(core.Event{Kind: core.EventStageFinished, Stage: core.StageEncrypt, Message: "All parts stored"})
+    r.finish("Encryption finished")
+
+    out := buf.String()
+    for _, want := range []string{"Configuration", "[==] Output path: /tmp/out", "Encrypting parts", "parts : 2/2", "\n", "[!!] careful", "[**] All parts stored", "Encryption finished"} {
+        if !strings.Contains(out, want) {
+            t.Fatalf("expected %q in rendered output %q", want, out)
+        }
+    }
+    // written to no terminal, only the finished count is logged, on a line of its own
+    if strings.Contains(out, "1/2") || strings.Contains(out, "\r") {
+        t.Fatalf("expected no intermediate progress outside a terminal, got %q", out)
+    }
+    // the progress line is ended before the warning
+    if !strings.Contains(out[strings.Index(out, "2/2"):strings.Index(out, "careful")], "\n") {
+        t.Fatalf("expected the progress line to be ended, got %q", out)
+    }
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+// outputFormat is the flag.Value of --output-format
+type outputFormat string
+
+const (
+	formatText outputFormat = "text"
+	formatJSON outputFormat = "json"
+)
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(value string) error {
+	switch outputFormat(value) {
+	case formatText, formatJSON:
+		*f = outputFormat(value)
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use text or json", value)
+}
+
+// addOutputFormat defines --output-format on fs
+func addOutputFormat(fs *flag.FlagSet) *outputFormat {
+	format := formatText
+	fs.Var(&format, "output-format", "Output for people (text) or scripts (json): events as JSON lines on stderr, the result as JSON on stdout")
+	return &format
+}
+
+// reporter presents the run of a command, drawn with prettywriter for people or as JSON for
+// scripts. In JSON mode prettywriter only writes to stderr, e.g. password prompts, see command.run.
+type reporter struct {
+	command string
+	json    *jsonEvents
+	render  *renderer
+}
+
+// newReporter returns the reporter of command for the given format
+func newReporter(command string, format outputFormat) *reporter {
+	r := &reporter{command: command}
+	if format == formatJSON {
+		r.json = newJSONEvents(stderr)
+	} else {
+		r.render = &renderer{}
+	}
+	return r
+}
+
+// observer returns what the events of the run are reported to
+func (r *reporter) observer() core.Observer {
+	if r.json != nil {
+		return r.json
+	}
+	return r.render
+}
+
+// start opens the run with the application header, scripts don't get one
+func (r *reporter) start() {
+	if r.json == nil {
+		utils.PrintApplicationHeader(version)
+	}
+}
+
+// fail reports the failure of the run and exits with the code of its kind. Scripts get an
+// error event and a result object saying so instead of the error box.
+func (r *reporter) fail(message string, err error) {
+	if r.json == nil {
+		exitRunError(message, err)
+		return
+	}
+	r.exit(fmt.Sprintf("%s: %v", message, err), exitCode(err))
+}
+
+// invalid rejects flags found wrong before the run started, message being the error shown to
+// people. Scripts get the error event and result object of a failed run.
+func (r *reporter) invalid(message string) {
+	if r.json == nil {
+		exitErrorFn(message)
+		return
+	}
+	r.exit(strings.TrimSpace(message), exitFailure)
+}
+
+// exit writes the error event and result object of a run failed with text and exits with code
+func (r *reporter) exit(text string, code int) {
+	r.json.write(jsonEvent{Event: "error", Message: text, ExitCode: code})
+	r.json.result(r.command, text, code, nil)
+	prettywriter.SetOutput(io.Discard)
+	exitErrorCodeFn(text+" \n", code)
+}
+
+// finish closes a successful run with a box titled title, or writes result for scripts
+func (r *reporter) finish(title string, result interface{}) {
+	if r.json == nil {
+		r.render.finish(title)
+		return
+	}
+	r.json.result(r.command, "", 0, result)
+}
+
+// jsonEvents writes the events of a run as JSON, one object per line, and times its stages
+type jsonEvents struct {
+	mu        sync.Mutex
+	w         io.Writer
+	started   time.Time
+	stages    map[core.Stage]time.Time
+	durations map[core.Stage]int64
+}
+
+// jsonEvent is a line written by jsonEvents. Done and Total are only set for progress,
+// DurationMS for finished stages and ExitCode for errors.
+type jsonEvent struct {
+	Time       string         `json:"time"`
+	Event      core.EventKind `json:"event"`
+	Stage      core.Stage     `json:"stage,omitempty"`
+	Message    string         `json:"message,omitempty"`
+	Done       *int           `json:"done,omitempty"`
+	Total      *int           `json:"total,omitempty"`
+	DurationMS *int64         `json:"duration_ms,omitempty"`
+	ExitCode   int            `json:"exit_code,omitempty"`
+}
+
+// jsonResult is the object written to stdout once a run is over. Result is specific to the
+// command and only set on success.
+type jsonResult struct {
+	Command          string               `json:"command"`
+	OK               bool                 `json:"ok"`
+	Error            string               `json:"error,omitempty"`
+	ExitCode         int                  `json:"exit_code"`
+	DurationMS       int64                `json:"duration_ms"`
+	StageDurationsMS map[core.Stage]int64 `json:"stage_durations_ms"`
+	Result           interface{}          `json:"result,omitempty"`
+}
+
+func newJSONEvents(w io.Writer) *jsonEvents {
+	return &jsonEvents{w: w, started: time.Now(), stages: map[core.Stage]time.Time{}, durations: map[core.Stage]int64{}}
+}
+
+// Observe writes e as a line of JSON
+func (j *jsonEvents) Observe(e core.Event) {
+	line := jsonEvent{Event: e.Kind, Stage: e.Stage, Message: e.Message}
+	j.mu.Lock()
+	switch e.Kind {
+	case core.EventStageStarted:
+		j.stages[e.Stage] = time.Now()
+	case core.EventStageFinished:
+		if started, ok := j.stages[e.Stage]; ok {
+			ms := time.Since(started).Milliseconds()
+			j.durations[e.Stage] += ms
+			line.DurationMS = &ms
+		}
+	case core.EventProgress:
+		done, total := e.Done, e.Total
+		line.Done, line.Total = &done, &total
+	}
+	j.mu.Unlock()
+	j.write(line)
+}
+
+// write writes a single event line
+func (j *jsonEvents) write(line jsonEvent) {
+	line.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	json.NewEncoder(j.w).Encode(line)
+}
+
+// result writes the result object of the run to stdout, failed if message is given
+func (j *jsonEvents) result(command, message string, code int, result interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	json.NewEncoder(stdout).Encode(jsonResult{
+		Command:          command,
+		OK:               message == "",
+		Error:            message,
+		ExitCode:         code,
+		DurationMS:       time.Since(j.started).Milliseconds(),
+		StageDurationsMS: j.durations,
+		Result:           result,
+	})
+}
+
+// hideJSON is the result of hide for scripts
+type hideJSON struct {
+	Output      string   `json:"output"`
+	Masterlock  string   `json:"masterlock"`
+	Parts       []string `json:"parts"`
+	DataBytes   int64    `json:"data_bytes"`
+	StoredBytes int64    `json:"stored_bytes"`
+}
+
+// dryRunJSON is the result of hide --dry-run for scripts
+type dryRunJSON struct {
+	Entries []string `json:"entries"`
+}
+
+// unhideJSON is the result of unhide for scripts
+type unhideJSON struct {
+	Output    string         `json:"output"`
+	Files     []string       `json:"files"`
+	Conflicts []conflictJSON `json:"conflicts"`
+}
+
+type conflictJSON struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Target string `json:"target,omitempty"`
+}
+
+// inspectJSON is the result of verify and list for scripts
+type inspectJSON struct {
+	Input       string      `json:"input"`
+	Format      string      `json:"format"`
+	Compression string      `json:"compression"`
+	Entries     []entryJSON `json:"entries"`
+}
+
+type entryJSON struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime string `json:"mod_time"`
+}
+
+// rekeyJSON is the result of rekey for scripts
+type rekeyJSON struct {
+	Masterlock string `json:"masterlock"`
+}
+
+func newHideJSON(result *core.HideResult) interface{} {
+	if result == nil {
+		return nil
+	}
+	return hideJSON{Output: result.Output, Masterlock: result.Masterlock, Parts: result.Parts, DataBytes: result.DataBytes, StoredBytes: result.StoredBytes}
+}
+
+func newUnhideJSON(result *core.UnhideResult) interface{} {
+	if result == nil {
+		return nil
+	}
+	out := unhideJSON{Output: result.Output, Files: result.Files, Conflicts: []conflictJSON{}}
+	if out.Files == nil {
+		out.Files = []string{}
+	}
+	for _, c := range result.Conflicts {
+		out.Conflicts = append(out.Conflicts, conflictJSON{Name: c.Name, Action: c.Action, Target: c.Target})
+	}
+	return out
+}
+
+func newInspectJSON(result *core.InspectResult) interface{} {
+	if result == nil {
+		return nil
+	}
+	out := inspectJSON{Input: result.Input, Format: result.Format, Compression: result.Compression, Entries: []entryJSON{}}
+	for _, e := range result.Entries {
+		out.Entries = append(out.Entries, entryJSON{Name: e.Name, Size: e.Size, Mode: e.Mode.String(), ModTime: e.ModTime.UTC().Format(time.RFC3339)})
+	}
+	return out
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+)
+
+// jsonLines decodes every line of buf, failing the test on anything which isn't a JSON object
+func jsonLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+    t.Helper()
+    var lines []map[string]interface{}
+    scanner := bufio.NewScanner(buf)
+    for scanner.Scan() {
+        var line map[string]interface{}
+        if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+            t.Fatalf("expected a JSON line, got %q: %v", scanner.Text(), err)
+        }
+        lines = append(lines, line)
+    }
+    return lines
+}
+
+func TestMain_OutputFormatJSON(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "docs")
+    if err := os.MkdirAll(src, 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("alpha"), 0o644); err != nil {
+        t.Fatalf("write src: %v", err)
+    }
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+    os.Setenv("TACHICRYPT_PASSWORD", "json-pass")
+    var results, events bytes.Buffer
+    oldStdout, oldStderr := stdout, stderr
+    stdout, stderr = &results, &events
+    t.Cleanup(func() {
+        os.Unsetenv("TACHICRYPT_PASSWORD")
+        stdout, stderr = oldStdout, oldStderr
+        prettywriter.SetOutput(nil)
+    })
+
+    // nothing but the result may reach stdout
+    printed := captureStdout(t, func() {
+        if called, msg := runClient(t, "hide", "--parts", "3", "--data", src, "--output", enc, "--output-format", "json"); called {
+            t.Fatalf("hide failed: %s", msg)
+        }
+    })
+    if printed != "" {
+        t.Fatalf("expected nothing on stdout besides the result, got %q", printed)
+    }
+    var hide struct {
+        Command string           `json:"command"`
+        OK      bool             `json:"ok"`
+        Stages  map[string]int64 `json:"stage_durations_ms"`
+        Result  hideJSON         `json:"result"`
+    }
+    if err := json.Unmarshal(results.Bytes(), &hide); err != nil {
+        t.Fatalf("expected the hide result as JSON, got %q: %v", results.String(), err)
+    }
+    if !hide.OK || hide.Command != "hide" || len(hide.Result.Parts) != 3 || hide.Result.Masterlock != filepath.Join(enc, "masterlock") || hide.Result.StoredBytes <= hide.Result.DataBytes {
+        t.Fatalf("unexpected hide result %+v", hide)
+    }
+    if _, ok := hide.Stages["encrypt"]; !ok {
+        t.Fatalf("expected the duration of the encrypt stage, got %v", hide.Stages)
+    }
+    kinds := map[string]int{}
+    for _, line := range jsonLines(t, &events) {
+        kinds[line["event"].(string)]++
+        if line["event"] == "progress" && line["stage"] == "encrypt" && line["total"] != 3.0 {
+            t.Fatalf("unexpected progress event %v", line)
+        }
+    }
+    if kinds["stage-started"] == 0 || kinds["stage-started"] != kinds["stage-finished"] || kinds["progress"] < 3 {
+        t.Fatalf("unexpected events %v", kinds)
+    }
+
+    results.Reset()
+    if called, msg := runClient(t, "unhide", "--data", enc, "--output", out, "--output-format", "json"); called {
+        t.Fatalf("unhide failed: %s", msg)
+    }
+    var unhide struct {
+        OK     bool       `json:"ok"`
+        Result unhideJSON `json:"result"`
+    }
+    if err := json.Unmarshal(results.Bytes(), &unhide); err != nil || !unhide.OK || strings.Join(unhide.Result.Files, ",") != "docs/a.txt" {
+        t.Fatalf("unexpected unhide result %q: %v", results.String(), err)
+    }
+
+    // failures are reported as JSON as well, with the exit code of their kind
+    results.Reset()
+    events.Reset()
+    os.Setenv("TACHICRYPT_PASSWORD", "wrong")
+    oldExitCode := exitErrorCodeFn
+    code := 0
+    exitErrorCodeFn = func(m string, c int) { code = c }
+    t.Cleanup(func() { exitErrorCodeFn = oldExitCode })
+    os.Args = []string{"tachicrypt", "verify", "--data", enc, "--output-format", "json"}
+    main()
+    var failed jsonResult
+    if err := json.Unmarshal(results.Bytes(), &failed); err != nil || failed.OK || failed.ExitCode != exitWrongPassword || code != exitWrongPassword {
+        t.Fatalf("unexpected failure result %q (exit %d): %v", results.String(), code, err)
+    }
+    lines := jsonLines(t, &events)
+    if last := lines[len(lines)-1]; last["event"] != "error" {
+        t.Fatalf("expected an error event last, got %v", last)
+    }
+}
+
+func TestMain_OutputFormatJSON_InvalidFlags(t *testing.T) {
+    var results, events bytes.Buffer
+    oldStdout, oldStderr, oldExit, oldExitCode := stdout, stderr, exitErrorFn, exitErrorCodeFn
+    stdout, stderr = &results, &events
+    code := 0
+    exitErrorFn = func(m string) { t.Fatalf("expected no error box, got %q", m) }
+    exitErrorCodeFn = func(m string, c int) { code = c }
+    t.Cleanup(func() {
+        stdout, stderr, exitErrorFn, exitErrorCodeFn = oldStdout, oldStderr, oldExit, oldExitCode
+        prettywriter.SetOutput(nil)
+    })
+
+    for _, tt := range []struct {
+        args []string
+        code int
+    }{
+        {[]string{"hide", "--data", "/tmp/a", "--output", "/tmp/out", "--output-format", "json"}, exitFailure},
+        {[]string{"unhide", "--output", "/tmp/out", "--attempts", "0", "--output-format", "json"}, exitFailure},
+        {[]string{"verify", "--output-format", "json"}, exitFailure},
+        {[]string{"list", "--jobs", "-1", "--output-format", "json"}, exitFailure},
+        {[]string{"rekey", "--new-keyfile-only", "--output-format", "json"}, exitFailure},
+        {[]string{"verify", "--output-format", "json", "stray"}, exitUsage},
+        {[]string{"hide", "--parts", "2", "--data", "/tmp/a", "--output", "/tmp/out", "--generate-password", "--output-format", "json"}, exitFailure},
+    } {
+        results.Reset()
+        events.Reset()
+        code = 0
+        os.Args = append([]string{"tachicrypt"}, tt.args...)
+        main()
+        var failed jsonResult
+        if err := json.Unmarshal(results.Bytes(), &failed); err != nil || failed.OK || failed.ExitCode != tt.code || code != tt.code || failed.Command != tt.args[0] {
+            t.Fatalf("%v: unexpected failure result %q (exit %d): %v", tt.args, results.String(), code, err)
+        }
+        lines := jsonLines(t, &events)
+        if len(lines) != 1 || lines[0]["event"] != "error" || lines[0]["message"] != failed.Error {
+            t.Fatalf("%v: expected a single error event, got %v", tt.args, lines)
+        }
+    }
+}
+
+func TestMain_OutputFormatJSON_LegacyAlias(t *testing.T) {
+    var results, events bytes.Buffer
+    oldStdout, oldStderr, oldExitCode := stdout, stderr, exitErrorCodeFn
+    stdout, stderr = &results, &events
+    code := 0
+    exitErrorCodeFn = func(m string, c int) { code = c }
+    t.Cleanup(func() {
+        stdout, stderr, exitErrorCodeFn = oldStdout, oldStderr, oldExitCode
+        prettywriter.SetOutput(nil)
+    })
+
+    os.Args = []string{"tachicrypt", "--hide", "--data", "/tmp/a", "--output", "/tmp/out", "--output-format", "json"}
+    main()
+    lines := jsonLines(t, &events)
+    if code != exitFailure || len(lines) != 2 || lines[0]["event"] != "warning" || !strings.Contains(lines[0]["message"].(string), "deprecated") || lines[1]["event"] != "error" {
+        t.Fatalf("expected the deprecation as warning event before the error, got %q (exit %d)", events.String(), code)
+    }
+
+    // people still get the notice as text
+    events.Reset()
+    oldExit := exitErrorFn
+    exitErrorFn = func(string) {}
+    t.Cleanup(func() { exitErrorFn = oldExit })
+    os.Args = []string{"tachicrypt", "--hide", "--data", "/tmp/a", "--output", "/tmp/out"}
+    main()
+    if !strings.HasPrefix(events.String(), "[!!] --hide is deprecated") {
+        t.Fatalf("expected the deprecation notice, got %q", events.String())
+    }
+}
+
+func TestMain_OutputFormatJSON_GeneratePassword(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "file.txt")
+    if err := os.WriteFile(src, []byte("ok"), 0o644); err != nil {
+        t.Fatalf("write src: %v", err)
+    }
+    secret := filepath.Join(tmp, "secret")
+    // what prettywriter writes in JSON mode ends up in the same stream as the events
+    text, err := os.Create(filepath.Join(tmp, "stderr"))
+    if err != nil {
+        t.Fatalf("create: %v", err)
+    }
+    var results, events bytes.Buffer
+    oldStdout, oldStderr, oldOSStderr := stdout, stderr, os.Stderr
+    stdout, stderr, os.Stderr = &results, &events, text
+    t.Cleanup(func() {
+        stdout, stderr, os.Stderr = oldStdout, oldStderr, oldOSStderr
+        text.Close()
+        prettywriter.SetOutput(nil)
+    })
+
+    if called, msg := runClient(t, "hide", "--parts", "2", "--data", src, "--output", filepath.Join(tmp, "enc"), "--generate-password", "--password-out", secret, "--output-format", "json"); called {
+        t.Fatalf("hide failed: %s", msg)
+    }
+    if b, _ := os.ReadFile(text.Name()); len(b) != 0 {
+        t.Fatalf("expected no text besides the events, got %q", b)
+    }
+    phrase, _ := os.ReadFile(secret)
+    found := false
+    for _, line := range jsonLines(t, &events) {
+        message, _ := line["message"].(string)
+        if strings.Contains(message, strings.TrimSpace(string(phrase))) {
+            t.Fatalf("the password leaked into the events: %v", line)
+        }
+        found = found || line["event"] == "info" && message == "Generated password written to "+secret
+    }
+    if !found {
+        t.Fatalf("expected an event telling where the password went, got %q", events.String())
+    }
+    var result jsonResult
+    if err := json.Unmarshal(results.Bytes(), &result); err != nil || !result.OK {
+        t.Fatalf("unexpected result %q: %v", results.String(), err)
+    }
+}
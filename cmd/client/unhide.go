@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+var unhideCommand = &command{
+	name:    "unhide",
+	usage:   "tachicrypt unhide --data DIR --output DIR [options]",
+	summary: "Unhide (decrypt) data from its parts and masterlock",
+	examples: []string{
+		"Decrypt data: tachicrypt unhide --data /path/to/encrypted/data --output /path/to/output",
+		"Decrypt to a pipe: tachicrypt unhide --data /path/to/encrypted/data --output - | psql db",
+		"Decrypt unattended: tachicrypt unhide --data /path/to/encrypted/data --output /path/to/output --password-command \"pass show backup\"",
+	},
+	setup: setupUnhide,
+}
+
+// setupUnhide defines the flags of unhide
+func setupUnhide(fs *flag.FlagSet) func(args []string) {
+	var dataPaths stringList
+	fs.Var(&dataPaths, "data", "Directory holding the parts and masterlock")
+	outputDir := fs.String("output", "", "Output directory for the decrypted data, or - for stdout")
+	onConflict := fs.String("on-conflict", "fail", "What to do with files that already exist: fail, skip, overwrite or rename")
+	resume := fs.Bool("resume", false, "Keep the work of an interrupted unhide and continue it into the same output directory")
+	jobs := fs.Int("jobs", 0, "Number of parts decrypted at once, 0 uses one per CPU")
+	passwords := addPasswordFlags(fs, "", "masterlock password")
+	attempts := fs.Int("attempts", utils.PasswordAttempts, "Password attempts with a password typed in")
+	format := addOutputFormat(fs)
+
+	return func([]string) {
+		rep := newReporter("unhide", *format)
+		// with data piped out, stdout is kept clean and all output goes to stderr
+		writeStdout := *outputDir == "-"
+		if writeStdout {
+			prettywriter.SetOutput(os.Stderr)
+		}
+		if *jobs < 0 {
+			rep.invalid("--jobs can't be negative. \n")
+			return
+		}
+		if writeStdout && *format == formatJSON {
+			rep.invalid("--output-format json writes its result to stdout, it can't be used with --output -. \n")
+			return
+		}
+		if writeStdout && *resume {
+			rep.invalid("--resume needs an output directory, it can't be used with --output -. \n")
+			return
+		}
+		password, ok := readPasswordFlags(passwords, *attempts, rep)
+		if !ok {
+			return
+		}
+		if len(dataPaths) > 1 {
+			rep.invalid("unhide takes a single --data directory. \n")
+			return
+		}
+		if len(dataPaths) == 0 || *outputDir == "" {
+			rep.invalid("Both --data and --output must be specified. \n")
+			return
+		}
+
+		rep.start()
+
+		opts := core.UnhideOptions{
+			Input:    dataPaths[0],
+			Jobs:     *jobs,
+			Observer: rep.observer(),
+			Password: password,
+			Keyfiles: passwords.keyfiles,
+		}
+		// passwords from a file, descriptor, command or the environment don't change on retry
+		if _, typed := password.(promptPassword); typed {
+			opts.PasswordAttempts = *attempts
+		}
+		if writeStdout {
+			opts.Writer = stdout
+		} else {
+			opts.Output = *outputDir
+			opts.OnConflict = *onConflict
+			opts.Resume = *resume
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		result, err := unhideFunc(ctx, opts)
+		if err != nil {
+			rep.fail("Error unhiding data", err)
+			return
+		}
+		rep.finish("Decryption finished", newUnhideJSON(result))
+	}
+}
@@ -1,52 +1,62 @@
 package main
 
-import "testing"
-
-func TestValidateFlags_MissingParts(t *testing.T) {
-    old := exitErrorFn
-    called := false
-    exitErrorFn = func(string) { called = true }
-    t.Cleanup(func() { exitErrorFn = old })
-
-    ok := validateFlags(true, false, -1, "/x", "/y")
-    if ok || !called {
-        t.Fatalf("expected validation to fail and call exitErrorFn")
+import (
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestCommands_Validation(t *testing.T) {
+    tests := []struct {
+        args []string
+        want string
+    }{
+        {args: []string{"hide", "--data", "/x", "--output", "/y"}, want: "--parts"},
+        {args: []string{"hide", "--parts", "2", "--output", "/y"}, want: "Both --data and --output"},
+        {args: []string{"unhide", "--data", "/x"}, want: "Both --data and --output"},
+        {args: []string{"--hide", "--unhide", "--parts", "2", "--data", "/x", "--output", "/y"}, want: "Cannot use both"},
+        {args: []string{"verify"}, want: "--data"},
+        {args: []string{"list", "--data", "/x", "--attempts", "0"}, want: "--attempts"},
+        {args: []string{"rekey", "--data", "/x", "--new-keyfile-only"}, want: "--new-keyfile-only"},
+        {args: []string{"rekey", "--data", "/x", "--password-fd", "3", "--new-password-fd", "3"}, want: "same descriptor"},
+        {args: []string{"keygen"}, want: "Usage: tachicrypt keygen"},
     }
-}
-
-func TestValidateFlags_BothHideAndUnhide(t *testing.T) {
-    old := exitErrorFn
-    called := false
-    exitErrorFn = func(string) { called = true }
-    t.Cleanup(func() { exitErrorFn = old })
-
-    ok := validateFlags(true, true, 3, "/x", "/y")
-    if ok || !called {
-        t.Fatalf("expected validation to fail for both flags and call exitErrorFn")
+    for _, tt := range tests {
+        called, msg := runClient(t, tt.args...)
+        if !called || !strings.Contains(msg, tt.want) {
+            t.Fatalf("expected %v to be rejected with %q, got %q", tt.args, tt.want, msg)
+        }
     }
 }
 
-func TestValidateFlags_MissingDataOrOutput(t *testing.T) {
-    old := exitErrorFn
-    called := false
-    exitErrorFn = func(string) { called = true }
-    t.Cleanup(func() { exitErrorFn = old })
-
-    ok := validateFlags(true, false, 2, "", "/y")
-    if ok || !called {
-        t.Fatalf("expected validation to fail due to missing data/output and call exitErrorFn")
+func TestCommands_UsageErrors(t *testing.T) {
+    tests := [][]string{
+        {"hide", "--no-such-flag"},
+        {"unhide", "--parts", "2"},
+        {"verify", "--data", "/x", "extra"},
+        {"no-such-command"},
+        {"help", "no-such-command"},
     }
-}
-
-func TestValidateFlags_Success(t *testing.T) {
-    // Should not call exitErrorFn and return true
-    old := exitErrorFn
-    called := false
-    exitErrorFn = func(string) { called = true }
-    t.Cleanup(func() { exitErrorFn = old })
-
-    ok := validateFlags(true, false, 2, "/x", "/y")
-    if !ok || called {
-        t.Fatalf("expected validation to succeed without calling exitErrorFn")
+    oldExit, oldExitCode := exitErrorFn, exitErrorCodeFn
+    code := 0
+    exitErrorFn = func(m string) { t.Fatalf("unexpected exit: %s", m) }
+    exitErrorCodeFn = func(m string, c int) { code = c }
+    t.Cleanup(func() { exitErrorFn, exitErrorCodeFn = oldExit, oldExitCode })
+
+    for _, args := range tests {
+        code = 0
+        os.Args = append([]string{"tachicrypt"}, args...)
+        main()
+        if code != exitUsage {
+            t.Fatalf("expected %v to exit with %d, got %d", args, exitUsage, code)
+        }
+    }
+    // the word count of --generate-password can't be given separated by a space
+    var msg string
+    exitErrorCodeFn = func(m string, c int) { msg, code = m, c }
+    os.Args = []string{"tachicrypt", "hide", "--parts", "2", "--data", "/x", "--output", "/y", "--generate-password", "8"}
+    main()
+    if code != exitUsage || !strings.Contains(msg, "--generate-password=8") {
+        t.Fatalf("expected a hint at --generate-password=8, got %q (exit %d)", msg, code)
     }
 }
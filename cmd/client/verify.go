@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/core"
+	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+var verifyCommand = &command{
+	name:    "verify",
+	usage:   "tachicrypt verify --data DIR [options]",
+	summary: "Check hidden data can be unhidden, without writing anything",
+	examples: []string{
+		"Check a backup: tachicrypt verify --data /path/to/encrypted/data",
+		"Check unattended: tachicrypt verify --data /path/to/encrypted/data --password-file ~/backup.pass",
+	},
+	setup: setupVerify,
+}
+
+var listCommand = &command{
+	name:    "list",
+	usage:   "tachicrypt list --data DIR [options]",
+	summary: "List the hidden files without extracting them",
+	examples: []string{
+		"List hidden files: tachicrypt list --data /path/to/encrypted/data",
+		"Search hidden files: tachicrypt list --data /path/to/encrypted/data | grep notes",
+	},
+	setup: setupList,
+}
+
+// inspectFlags are the flags verify and list share
+type inspectFlags struct {
+	dataDir   *string
+	jobs      *int
+	attempts  *int
+	passwords *passwordFlags
+	format    *outputFormat
+}
+
+// addInspectFlags defines the flags verify and list share
+func addInspectFlags(fs *flag.FlagSet) *inspectFlags {
+	return &inspectFlags{
+		dataDir:   fs.String("data", "", "Directory holding the parts and masterlock"),
+		jobs:      fs.Int("jobs", 0, "Number of parts decrypted at once, 0 uses one per CPU"),
+		passwords: addPasswordFlags(fs, "", "masterlock password"),
+		attempts:  fs.Int("attempts", utils.PasswordAttempts, "Password attempts with a password typed in"),
+		format:    addOutputFormat(fs),
+	}
+}
+
+// options returns the options configured by the flags. Invalid flags are reported to rep and
+// false is returned.
+func (f *inspectFlags) options(rep *reporter) (core.InspectOptions, bool) {
+	if *f.jobs < 0 {
+		rep.invalid("--jobs can't be negative. \n")
+		return core.InspectOptions{}, false
+	}
+	password, ok := readPasswordFlags(f.passwords, *f.attempts, rep)
+	if !ok {
+		return core.InspectOptions{}, false
+	}
+	if *f.dataDir == "" {
+		rep.invalid("Missing mandatory --data parameter \n")
+		return core.InspectOptions{}, false
+	}
+	opts := core.InspectOptions{
+		Input:    *f.dataDir,
+		Password: password,
+		Keyfiles: f.passwords.keyfiles,
+		Jobs:     *f.jobs,
+	}
+	// passwords from a file, descriptor, command or the environment don't change on retry
+	if _, typed := password.(promptPassword); typed {
+		opts.PasswordAttempts = *f.attempts
+	}
+	return opts, true
+}
+
+// setupVerify defines the flags of verify
+func setupVerify(fs *flag.FlagSet) func(args []string) {
+	flags := addInspectFlags(fs)
+	return func([]string) {
+		rep := newReporter("verify", *flags.format)
+		opts, ok := flags.options(rep)
+		if !ok {
+			return
+		}
+		rep.start()
+		opts.Observer = rep.observer()
+		ctx, stop := interruptContext()
+		defer stop()
+		result, err := verifyFunc(ctx, opts)
+		if err != nil {
+			rep.fail("Error verifying data", err)
+			return
+		}
+		if *flags.format == formatText {
+			prettywriter.Writeln("[**] All parts decrypted, "+strconv.Itoa(len(result.Entries))+" entries intact", prettywriter.BlackBG, prettywriter.Green)
+			prettywriter.Println("")
+		}
+		rep.finish("Verification finished", newInspectJSON(result))
+	}
+}
+
+// setupList defines the flags of list. The entries alone go to stdout so they can be piped,
+// everything else to stderr.
+func setupList(fs *flag.FlagSet) func(args []string) {
+	flags := addInspectFlags(fs)
+	return func([]string) {
+		prettywriter.SetOutput(os.Stderr)
+		rep := newReporter("list", *flags.format)
+		opts, ok := flags.options(rep)
+		if !ok {
+			return
+		}
+		// people get the entries alone, without the events of decrypting them
+		if *flags.format == formatJSON {
+			opts.Observer = rep.observer()
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		result, err := listFunc(ctx, opts)
+		if err != nil {
+			rep.fail("Error listing data", err)
+			return
+		}
+		if *flags.format == formatJSON {
+			rep.finish("", newInspectJSON(result))
+			return
+		}
+		for _, entry := range result.Entries {
+			fmt.Fprintf(stdout, "%s %12d %s %s\n", entry.Mode, entry.Size, entry.ModTime.Format("2006-01-02 15:04"), entry.Name)
+		}
+	}
+}
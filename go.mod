@@ -2,6 +2,8 @@ module github.com/voodooEntity/go-tachicrypt
 
 go 1.19
 
-require golang.org/x/term v0.25.0
-
-require golang.org/x/sys v0.26.0 // indirect
+require (
+	github.com/klauspost/compress v1.17.6
+	golang.org/x/sys v0.26.0
+	golang.org/x/term v0.25.0
+)
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/voodooEntity/go-tachicrypt/src/fileutils"
+	"github.com/voodooEntity/go-tachicrypt/src/zipper"
+)
+
+// Purpose tells a PasswordProvider what the password is needed for
+type Purpose string
+
+const (
+	// PurposeHide asks for the password a new masterlock gets encrypted with
+	PurposeHide Purpose = "hide"
+	// PurposeUnhide asks for the password of an existing masterlock
+	PurposeUnhide Purpose = "unhide"
+	// PurposeRekey asks for the new password an existing masterlock gets encrypted with
+	PurposeRekey Purpose = "rekey"
+)
+
+// PasswordProvider supplies the password protecting the masterlock. It's asked once per run.
+type PasswordProvider interface {
+	Password(purpose Purpose) (string, error)
+}
+
+// StaticPassword is a PasswordProvider always returning the same password
+type StaticPassword string
+
+// Password returns the password
+func (p StaticPassword) Password(Purpose) (string, error) {
+	return string(p), nil
+}
+
+// HideOptions describe what Hide hides, where to and how
+type HideOptions struct {
+	// Paths are the files and directories to hide, each becomes a top-level entry
+	Paths []string
+	// Reader is hidden as a single file called Name instead of Paths, e.g. a database dump
+	Reader io.Reader
+	Name   string
+	// Parts is the number of parts the encrypted data is split into
+	Parts int
+	// Output is the directory the parts and masterlock are written to
+	Output   string
+	Password PasswordProvider
+	// Observer receives the events of the run, nil discards them
+	Observer Observer
+
+	// the settings below are described at the fields of Core of the same name
+	Keyfiles         []string
+	KeyfileOnly      bool
+	Format           string
+	Compression      string
+	CompressionLevel int
+	Excludes         []string
+	StripMetadata    bool
+	FollowSymlinks   bool
+	Force            bool
+	Resume           bool
+	Jobs             int
+	Timestamps       fileutils.Timestamps
+	ShredSource      bool
+	// ShredPasses defaults to 3 when ShredSource is set
+	ShredPasses int
+	MinEntropy  float64
+}
+
+// HideResult describes what Hide stored
+type HideResult struct {
+	Output string
+	// Parts are the file names of the parts inside Output, sorted by name so their order stays secret
+	Parts      []string
+	Masterlock string
+	// DataBytes is the size of the packed data before encryption, StoredBytes the size of the
+	// parts and masterlock written
+	DataBytes   int64
+	StoredBytes int64
+}
+
+// UnhideOptions describe what Unhide restores, where to and how
+type UnhideOptions struct {
+	// Input is the directory holding the parts and masterlock
+	Input string
+	// Output is the directory the hidden data is extracted into
+	Output string
+	// Writer receives the content of data hidden as a single file instead of extracting it to Output
+	Writer   io.Writer
+	Password PasswordProvider
+	// Observer receives the events of the run, nil discards them
+	Observer Observer
+
+	// the settings below are described at the fields of Core of the same name
+	Keyfiles         []string
+	OnConflict       string
+	Resume           bool
+	Jobs             int
+	PasswordAttempts int
+}
+
+// UnhideResult describes what Unhide restored
+type UnhideResult struct {
+	Output string
+	// Files are the files, links and special files extracted, by their slash separated path
+	// inside Output. It's empty for data written to a Writer.
+	Files []string
+	// Conflicts lists the entries which clashed with existing paths and how they were handled
+	Conflicts []zipper.Conflict
+}
+
+// Hide encrypts opts.Paths, or everything read from opts.Reader, into opts.Output. Nothing is
+// printed or read from the terminal, the password comes from opts.Password and progress is
+// reported to opts.Observer. Once ctx is done Hide stops, removes what it staged and returns a
+// *CanceledError naming the interrupted stage, which unwraps to the error of ctx. With Resume set
+// the parts already stored are kept for the next run. The hidden data is complete once
+// published, so ctx only stops verifying and wiping the source after that.
+func Hide(ctx context.Context, opts HideOptions) (*HideResult, error) {
+	if err := canceled(ctx, StageConfigure); err != nil {
+		return nil, err
+	}
+	c := opts.core()
+	if opts.Reader == nil {
+		if len(opts.Paths) == 0 {
+			return nil, fmt.Errorf("nothing to hide, no paths given")
+		}
+		return c.hidePaths(ctx, opts.Paths, opts.Parts, opts.Output, "")
+	}
+	if len(opts.Paths) > 0 {
+		return nil, fmt.Errorf("hide either paths or a reader, not both")
+	}
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a name to store the data read from the reader as is needed")
+	}
+	return c.hideReader(ctx, opts.Reader, opts.Name, opts.Parts, opts.Output, "")
+}
+
+// Unhide decrypts the data hidden in opts.Input and extracts it into opts.Output, or writes it
+// to opts.Writer. Like Hide it prints nothing and reports to opts.Observer. Once ctx is done
+// Unhide stops, removes what it extracted so far and returns a *CanceledError, files already
+// extracted by a resumable run are kept for the next one.
+func Unhide(ctx context.Context, opts UnhideOptions) (*UnhideResult, error) {
+	if err := canceled(ctx, StageConfigure); err != nil {
+		return nil, err
+	}
+	c := New()
+	c.Password = opts.Password
+	c.Observer = opts.Observer
+	c.Keyfiles = opts.Keyfiles
+	c.OnConflict = opts.OnConflict
+	c.Resume = opts.Resume
+	c.Jobs = opts.Jobs
+	c.PasswordAttempts = opts.PasswordAttempts
+	if opts.Writer != nil {
+		return c.unhideTo(ctx, opts.Input, opts.Writer, "")
+	}
+	return c.unhideDir(ctx, opts.Input, opts.Output, "")
+}
+
+// DryRun returns every entry hiding opts.Paths would include, without reading or writing any data
+func DryRun(opts HideOptions) ([]string, error) {
+	return opts.core().DryRun(opts.Paths...)
+}
+
+// core returns a Core configured by the options
+func (opts HideOptions) core() *Core {
+	c := New()
+	c.Password = opts.Password
+	c.Observer = opts.Observer
+	c.Keyfiles = opts.Keyfiles
+	c.KeyfileOnly = opts.KeyfileOnly
+	c.Format = opts.Format
+	c.Compression = opts.Compression
+	c.CompressionLevel = opts.CompressionLevel
+	c.Excludes = opts.Excludes
+	c.StripMetadata = opts.StripMetadata
+	c.FollowSymlinks = opts.FollowSymlinks
+	c.Force = opts.Force
+	c.Resume = opts.Resume
+	c.Jobs = opts.Jobs
+	c.Timestamps = opts.Timestamps
+	c.ShredSource = opts.ShredSource
+	c.MinEntropy = opts.MinEntropy
+	if opts.ShredPasses != 0 {
+		c.ShredPasses = opts.ShredPasses
+	}
+	return c
+}
@@ -0,0 +1,283 @@
+package core
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// recorder is an Observer keeping every event
+type recorder struct {
+    mu     sync.Mutex
+    events []Event
+}
+
+func (r *recorder) Observe(e Event) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.events = append(r.events, e)
+}
+
+// stages returns the stages in the order they got started
+func (r *recorder) stages() []Stage {
+    var stages []Stage
+    for _, e := range r.events {
+        if e.Kind == EventStageStarted {
+            stages = append(stages, e.Stage)
+        }
+    }
+    return stages
+}
+
+// lastProgress returns the last progress event of stage
+func (r *recorder) lastProgress(stage Stage) Event {
+    var last Event
+    for _, e := range r.events {
+        if e.Kind == EventProgress && e.Stage == stage {
+            last = e
+        }
+    }
+    return last
+}
+
+// silenceStdout fails the test if anything gets written to stdout while fn runs
+func silenceStdout(t *testing.T, fn func()) {
+    t.Helper()
+    old := os.Stdout
+    r, w, err := os.Pipe()
+    if err != nil {
+        t.Fatalf("pipe: %v", err)
+    }
+    os.Stdout = w
+    fn()
+    os.Stdout = old
+    _ = w.Close()
+    out, _ := io.ReadAll(r)
+    if len(out) > 0 {
+        t.Fatalf("expected nothing on stdout, got %q", out)
+    }
+}
+
+func TestAPI_HideAndUnhide(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "docs")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(src, "b.txt"), []byte("beta"))
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+
+    hidden, restored := &recorder{}, &recorder{}
+    var hideResult *HideResult
+    var unhideResult *UnhideResult
+    silenceStdout(t, func() {
+        var err error
+        hideResult, err = Hide(context.Background(), HideOptions{
+            Paths:    []string{src},
+            Parts:    3,
+            Output:   enc,
+            Password: StaticPassword("api-pass"),
+            Observer: hidden,
+            Format:   "tar",
+        })
+        if err != nil {
+            t.Fatalf("Hide: %v", err)
+        }
+        unhideResult, err = Unhide(context.Background(), UnhideOptions{
+            Input:    enc,
+            Output:   out,
+            Password: StaticPassword("api-pass"),
+            Observer: restored,
+        })
+        if err != nil {
+            t.Fatalf("Unhide: %v", err)
+        }
+    })
+
+    if hideResult.Masterlock != filepath.Join(enc, "masterlock") || len(hideResult.Parts) != 3 || !sort.StringsAreSorted(hideResult.Parts) {
+        t.Fatalf("unexpected hide result: %+v", hideResult)
+    }
+    stored := int64(0)
+    for _, name := range append(hideResult.Parts, "masterlock") {
+        info, err := os.Stat(filepath.Join(enc, name))
+        if err != nil {
+            t.Fatalf("part of the result missing: %v", err)
+        }
+        stored += info.Size()
+    }
+    if hideResult.StoredBytes != stored || hideResult.DataBytes <= 0 || hideResult.DataBytes >= stored {
+        t.Fatalf("unexpected byte counts %d and %d, %d stored", hideResult.DataBytes, hideResult.StoredBytes, stored)
+    }
+    sort.Strings(unhideResult.Files)
+    if unhideResult.Output != out || len(unhideResult.Conflicts) != 0 || strings.Join(unhideResult.Files, ",") != "docs/a.txt,docs/b.txt" {
+        t.Fatalf("unexpected unhide result: %+v", unhideResult)
+    }
+    if got := collectFiles(t, filepath.Join(out, "docs")); string(got["a.txt"]) != "alpha" || string(got["b.txt"]) != "beta" {
+        t.Fatalf("unexpected restored files: %v", got)
+    }
+
+    wantHide := []Stage{StageConfigure, StagePack, StageEncrypt, StageMasterlock, StagePublish, StageTimestamps}
+    if got := hidden.stages(); !equalStages(got, wantHide) {
+        t.Fatalf("unexpected hide stages %v", got)
+    }
+    if p := hidden.lastProgress(StageEncrypt); p.Done != 3 || p.Total != 3 {
+        t.Fatalf("expected the encryption progress to finish, got %+v", p)
+    }
+    wantUnhide := []Stage{StageConfigure, StageMasterlock, StageDecrypt, StageUnpack}
+    if got := restored.stages(); !equalStages(got, wantUnhide) {
+        t.Fatalf("unexpected unhide stages %v", got)
+    }
+    if p := restored.lastProgress(StageDecrypt); p.Done != 3 || p.Total != 3 {
+        t.Fatalf("expected the decryption progress to finish, got %+v", p)
+    }
+}
+
+func TestAPI_Stream(t *testing.T) {
+    enc := filepath.Join(t.TempDir(), "enc")
+    if _, err := Hide(context.Background(), HideOptions{
+        Reader:   strings.NewReader("streamed"),
+        Name:     "dump.sql",
+        Parts:    2,
+        Output:   enc,
+        Password: StaticPassword("p"),
+    }); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    var buf bytes.Buffer
+    if _, err := Unhide(context.Background(), UnhideOptions{Input: enc, Writer: &buf, Password: StaticPassword("p")}); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if buf.String() != "streamed" {
+        t.Fatalf("unexpected stream content %q", buf.String())
+    }
+}
+
+func TestAPI_InvalidOptions(t *testing.T) {
+    tmp := t.TempDir()
+    enc := filepath.Join(tmp, "enc")
+    canceled, cancel := context.WithCancel(context.Background())
+    cancel()
+    tests := []struct {
+        ctx  context.Context
+        opts HideOptions
+        err  string
+    }{
+        {ctx: context.Background(), opts: HideOptions{Parts: 2, Output: enc, Password: StaticPassword("p")}, err: "no paths"},
+        {ctx: context.Background(), opts: HideOptions{Paths: []string{tmp}, Reader: strings.NewReader("x"), Name: "x", Parts: 2, Output: enc}, err: "not both"},
+        {ctx: context.Background(), opts: HideOptions{Reader: strings.NewReader("x"), Parts: 2, Output: enc}, err: "name"},
+        {ctx: context.Background(), opts: HideOptions{Paths: []string{tmp}, Parts: 0, Output: enc, Password: StaticPassword("p")}, err: "at least one part"},
+        {ctx: context.Background(), opts: HideOptions{Paths: []string{tmp}, Parts: 2, Output: enc}, err: "no password"},
+        {ctx: canceled, opts: HideOptions{Paths: []string{tmp}, Parts: 2, Output: enc, Password: StaticPassword("p")}, err: "canceled"},
+    }
+    for _, tt := range tests {
+        if _, err := Hide(tt.ctx, tt.opts); err == nil || !strings.Contains(err.Error(), tt.err) {
+            t.Fatalf("expected error containing %q, got %v", tt.err, err)
+        }
+    }
+    if _, err := os.Stat(enc); !os.IsNotExist(err) {
+        t.Fatalf("expected nothing to be written, got %v", err)
+    }
+    if _, err := Unhide(canceled, UnhideOptions{Input: enc, Output: tmp}); err == nil {
+        t.Fatalf("expected a canceled context to stop unhide")
+    }
+}
+
+func TestAPI_CancelStopsAndCleansUp(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "docs")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    enc := filepath.Join(tmp, "enc")
+
+    // canceled as soon as the given stage starts
+    cancelAt := func(stage Stage) (context.Context, Observer) {
+        ctx, cancel := context.WithCancel(context.Background())
+        t.Cleanup(cancel)
+        return ctx, ObserverFunc(func(e Event) {
+            if e.Kind == EventStageStarted && e.Stage == stage {
+                cancel()
+            }
+        })
+    }
+    for _, resume := range []bool{false, true} {
+        for _, stage := range []Stage{StagePack, StageEncrypt, StageMasterlock} {
+            ctx, observer := cancelAt(stage)
+            _, err := Hide(ctx, HideOptions{Paths: []string{src}, Parts: 3, Output: enc, Password: StaticPassword("p"), Observer: observer, Jobs: 1, Resume: resume})
+            var canceled *CanceledError
+            if !errors.As(err, &canceled) || canceled.Stage != stage || !errors.Is(err, context.Canceled) {
+                t.Fatalf("expected hide to be interrupted in %s, got %v", stage, err)
+            }
+            if resume && stage == StageMasterlock {
+                // the stored parts are kept for resuming, but nothing is published
+                if names := publishedNames(t, enc); len(names) != 0 || len(dirNames(t, enc)) == 0 {
+                    t.Fatalf("expected only the resumable work to be kept, got %v", dirNames(t, enc))
+                }
+                if err := os.RemoveAll(enc); err != nil {
+                    t.Fatalf("remove: %v", err)
+                }
+                continue
+            }
+            if _, err := os.Stat(enc); !os.IsNotExist(err) {
+                t.Fatalf("expected the output of the hide interrupted in %s (resume %v) to be removed, got %v", stage, resume, err)
+            }
+        }
+    }
+
+    if _, err := Hide(context.Background(), HideOptions{Paths: []string{src}, Parts: 3, Output: enc, Password: StaticPassword("p")}); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    out := filepath.Join(tmp, "out")
+    for _, stage := range []Stage{StageMasterlock, StageDecrypt, StageUnpack} {
+        ctx, observer := cancelAt(stage)
+        _, err := Unhide(ctx, UnhideOptions{Input: enc, Output: out, Password: StaticPassword("p"), Observer: observer, Jobs: 1})
+        var canceled *CanceledError
+        if !errors.As(err, &canceled) || canceled.Stage != stage || !errors.Is(err, context.Canceled) {
+            t.Fatalf("expected unhide to be interrupted in %s, got %v", stage, err)
+        }
+        if _, err := os.Stat(out); !os.IsNotExist(err) {
+            t.Fatalf("expected the output of the unhide interrupted in %s to be removed, got %v", stage, err)
+        }
+    }
+}
+
+func TestAPI_Timeout(t *testing.T) {
+    enc := filepath.Join(t.TempDir(), "enc")
+    ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+    defer cancel()
+    <-ctx.Done()
+    _, err := Hide(ctx, HideOptions{Reader: strings.NewReader("x"), Name: "x", Parts: 2, Output: enc, Password: StaticPassword("p")})
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected the deadline to be reported, got %v", err)
+    }
+}
+
+func TestAPI_DryRun(t *testing.T) {
+    src := filepath.Join(t.TempDir(), "tree")
+    writeFile(t, filepath.Join(src, "keep.txt"), []byte("k"))
+    writeFile(t, filepath.Join(src, "skip.log"), []byte("s"))
+    names, err := DryRun(HideOptions{Paths: []string{src}, Excludes: []string{"*.log"}})
+    if err != nil {
+        t.Fatalf("DryRun: %v", err)
+    }
+    if strings.Join(names, ",") != "tree/,tree/keep.txt" {
+        t.Fatalf("unexpected entries %v", names)
+    }
+}
+
+func equalStages(a, b []Stage) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
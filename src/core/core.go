@@ -1,18 +1,24 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/voodooEntity/go-tachicrypt/src/encryptor"
 	"github.com/voodooEntity/go-tachicrypt/src/fileutils"
 	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
-	"github.com/voodooEntity/go-tachicrypt/src/prettywriter"
 	"github.com/voodooEntity/go-tachicrypt/src/splitter"
+	"github.com/voodooEntity/go-tachicrypt/src/strength"
 	"github.com/voodooEntity/go-tachicrypt/src/utils"
 	"github.com/voodooEntity/go-tachicrypt/src/zipper"
 )
@@ -31,7 +37,8 @@ var (
 	jsonUnmarshalFn           = json.Unmarshal
 	osReadFileFn              = os.ReadFile
 	decryptWithRandomKeyFn    = encryptor.DecryptWithRandomKey
-	promptPasswordFn          = utils.PromptForPassword
+	newStagingFn              = newStaging
+	randomPermutationFn       = utils.RandomPermutation
 )
 
 type Core struct {
@@ -39,54 +46,275 @@ type Core struct {
 	KeySize   int
 	SaltSize  int
 	PartCount int
+	// StripMetadata hides without permissions, ownership and timestamps of the input
+	StripMetadata bool
+	// FollowSymlinks hides the files symlinks point to instead of storing the links
+	FollowSymlinks bool
+	// Format is the container format the data is packed into (zip, tar or tar.zst), defaults to zip
+	Format string
+	// Compression is the method used to compress the hidden data (store, deflate or zstd),
+	// defaults to what the format uses
+	Compression string
+	// CompressionLevel is the level for the chosen compression method, 0 selects its default
+	CompressionLevel int
+	// Excludes are gitignore style patterns of files and directories to leave out when hiding,
+	// on top of the ones found in .tachiignore files
+	Excludes []string
+	// Force allows hiding into an output directory which already contains files. Existing files
+	// are never replaced and keep their timestamps.
+	Force bool
+	// OnConflict decides what unhiding does with entries whose path already exists in the
+	// output directory: fail (the default), skip, overwrite or rename
+	OnConflict string
+	// ShredSource wipes the hidden files after the stored data has been verified
+	ShredSource bool
+	// ShredPasses is how often ShredSource overwrites every file with random data
+	ShredPasses int
+	// Jobs is the number of parts encrypted or decrypted concurrently, 0 means one per CPU
+	Jobs int
+	// Resume continues the run an interrupt or failure left in the output directory, skipping
+	// the parts already stored (hide) or the files already extracted (unhide)
+	Resume bool
+	// Timestamps is the strategy picking the timestamps of the written parts and masterlock,
+	// random around now by default
+	Timestamps fileutils.Timestamps
+	// Password supplies the masterlock password when none is passed in
+	Password PasswordProvider
+	// Keyfiles are paths of files whose content is mixed into the masterlock key together with
+	// the password, so unhiding needs both. Unhiding needs as many keyfiles as hiding used.
+	Keyfiles []string
+	// KeyfileOnly hides with the Keyfiles alone, without asking for a password, e.g. for unattended
+	// jobs. Unhiding reads from the masterlock whether a password is required.
+	KeyfileOnly bool
+	// MinEntropy refuses hiding with passwords estimated weaker than this many bits, see
+	// strength.Entropy. Passwords weaker than strength.Recommended are only warned about.
+	MinEntropy float64
+	// PasswordAttempts is how often unhiding asks Password for the password in all, as long as
+	// it's wrong. Values below 1 mean once, so does a PasswordFD which is used up by reading it.
+	PasswordAttempts int
+	// Observer receives the events of runs, nil discards them. Core never prints anything itself.
+	Observer Observer
 }
 
 func New() *Core {
 	return &Core{
-		SaltSize: 100,
-		KeySize:  32,
+		SaltSize:    100,
+		KeySize:     32,
+		ShredPasses: 3,
 	}
 }
 
+// DryRun returns everything hiding dataPaths would include, without reading or writing any data
+func (c *Core) DryRun(dataPaths ...string) ([]string, error) {
+	opts := c.containerOptions(c.archiveInfo())
+	names, err := opts.List(dataPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing input data: %w", err)
+	}
+	return names, nil
+}
+
 func (c *Core) Hide(dataPath string, partCount int, outputDir string, prefilledPassword string) error {
-	prettywriter.WriteInBox(40, "Configuration", prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
-	prettywriter.Writeln("[==] Chosen mode: hide (encrypting)", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[==] Input path: "+dataPath, prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[==] Output path: "+outputDir, prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[==] Amount of parts: "+strconv.Itoa(partCount), prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println("")
-
-	prettywriter.WriteInBox(40, "Starting Encryption Process", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	prettywriter.Writeln("[>>] Zipping input data", prettywriter.Green, prettywriter.BlackBG)
+	return c.HidePaths([]string{dataPath}, partCount, outputDir, prefilledPassword)
+}
+
+// HidePaths hides several files and directories in a single run. Each of them becomes a top-level
+// entry of the container; inputs sharing a base name get a counter appended (notes.md, notes_2.md).
+// With ShredSource set the hidden files are wiped afterwards, once the stored data got verified.
+func (c *Core) HidePaths(dataPaths []string, partCount int, outputDir string, prefilledPassword string) error {
+	_, err := c.hidePaths(context.Background(), dataPaths, partCount, outputDir, prefilledPassword)
+	return err
+}
+
+func (c *Core) hidePaths(ctx context.Context, dataPaths []string, partCount int, outputDir string, prefilledPassword string) (*HideResult, error) {
+	if c.ShredSource && c.FollowSymlinks {
+		return nil, fmt.Errorf("shredding the source can't be combined with following symlinks, it would wipe the link targets")
+	}
+	if c.ShredSource && c.ShredPasses < 1 {
+		return nil, fmt.Errorf("shredding the source needs at least one overwrite pass")
+	}
+	if c.ShredSource {
+		if err := checkOutsideInputs(outputDir, dataPaths); err != nil {
+			return nil, err
+		}
+	}
+	pack := func(ctx context.Context, container zipper.Container) ([]byte, error) {
+		return container.PackContext(ctx, dataPaths...)
+	}
+	// only what got packed exactly as it is now on disk may be wiped later on
+	var hidden []hiddenSource
+	packed := func(path string, info os.FileInfo) {
+		hidden = append(hidden, hiddenSource{path: path, info: info})
+	}
+	result, err := c.hide(ctx, dataPaths, pack, packed, partCount, outputDir, prefilledPassword)
+	if err != nil || !c.ShredSource {
+		return result, err
+	}
+
+	c.startStage(StageShred)
+	c.warn(StageShred, "Overwriting is best effort: on copy-on-write (btrfs, ZFS, APFS) or journaling file systems, snapshots and flash storage older copies of the data may survive.")
+	failures, err := c.shredSources(ctx, hidden)
+	if err != nil {
+		return result, err
+	}
+	if len(failures) == 0 {
+		c.finishStage(StageShred, "Source wiped")
+		return result, nil
+	}
+	paths := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		c.warn(StageShred, "Not wiped: "+failure.path+": "+failure.err.Error())
+		paths = append(paths, failure.path)
+	}
+	return result, fmt.Errorf("data hidden, but %d source paths could not be wiped: %s", len(failures), strings.Join(paths, ", "))
+}
+
+// HideReader hides everything read from r, e.g. a database dump piped into stdin, as a single
+// file called name
+func (c *Core) HideReader(r io.Reader, name string, partCount int, outputDir string, prefilledPassword string) error {
+	_, err := c.hideReader(context.Background(), r, name, partCount, outputDir, prefilledPassword)
+	return err
+}
+
+func (c *Core) hideReader(ctx context.Context, r io.Reader, name string, partCount int, outputDir string, prefilledPassword string) (*HideResult, error) {
+	if c.ShredSource {
+		return nil, fmt.Errorf("shredding the source needs files to hide, not a stream")
+	}
+	pack := func(ctx context.Context, container zipper.Container) ([]byte, error) {
+		return container.PackStreamContext(ctx, name, r)
+	}
+	return c.hide(ctx, []string{"stream (stored as " + name + ")"}, pack, nil, partCount, outputDir, prefilledPassword)
+}
+
+// hide packs the input using pack, then encrypts and stores it. inputs describe the input for the user,
+// packed, if set, gets every file system object read from disk while packing.
+// Once ctx is done the stage running is stopped and what got staged is removed, except for the parts
+// a resumable run already stored. From publishing on the run isn't stopped anymore, apart from
+// verifying and wiping the source.
+func (c *Core) hide(ctx context.Context, inputs []string, pack func(ctx context.Context, container zipper.Container) ([]byte, error), packed func(path string, info os.FileInfo), partCount int, outputDir string, prefilledPassword string) (*HideResult, error) {
+	if partCount < 1 {
+		return nil, fmt.Errorf("at least one part is needed, got %d", partCount)
+	}
+	if err := checkOutputDir(outputDir, c.Force, c.Resume); err != nil {
+		return nil, err
+	}
+	if err := c.Timestamps.Validate(); err != nil {
+		return nil, err
+	}
+	c.startStage(StageConfigure)
+	c.info(StageConfigure, "Chosen mode: hide (encrypting)")
+	for _, input := range inputs {
+		c.info(StageConfigure, "Input path: "+input)
+	}
+	c.info(StageConfigure, "Output path: "+outputDir)
+	c.info(StageConfigure, "Amount of parts: "+strconv.Itoa(partCount))
+	if c.StripMetadata {
+		c.info(StageConfigure, "Metadata: stripped")
+	}
+	archiveInfo := c.archiveInfo()
+	c.info(StageConfigure, "Format: "+archiveInfo.Format+" ("+archiveInfo.Compression+")")
+	if c.Timestamps.Strategy != "" {
+		c.info(StageConfigure, "Timestamps: "+c.Timestamps.Strategy)
+	}
+	if len(c.Keyfiles) > 0 {
+		c.info(StageConfigure, "Keyfiles: "+strconv.Itoa(len(c.Keyfiles)))
+	}
+	c.finishStage(StageConfigure, "")
+
+	// the password and keyfiles are needed up front, they also key the journal which makes the
+	// run resumable
+	creds, err := c.newCredentials(prefilledPassword, PurposeHide)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := canceled(ctx, StagePack); err != nil {
+		return nil, err
+	}
+	c.startStage(StagePack)
+	c.info(StagePack, "Zipping input data")
 	// Step 1: Create the zip data
 	c.PartCount = partCount
 
-	zipr := zipper.New()
-	zipData, err := zipr.Zip(dataPath)
+	opts := c.containerOptions(archiveInfo)
+	opts.Packed = packed
+	container, err := zipper.NewContainer(archiveInfo.Format, opts)
+	if err != nil {
+		return nil, err
+	}
+	zipData, err := pack(ctx, container)
+	if err != nil {
+		return nil, interrupted(ctx, StagePack, fmt.Errorf("error zipping and encoding: %w", err))
+	}
+
+	// everything is written into a staging directory first and only published once the
+	// masterlock is written, a failed or interrupted run leaves no orphaned parts behind.
+	// Stored parts are recorded in the journal, so a resumed run only has to store the rest.
+	st, err := newStagingFn(outputDir, c.Resume, c.Observer)
+	if err != nil {
+		return nil, err
+	}
+	defer st.close()
+	rj, err := openJournal(st, creds)
 	if err != nil {
-		return fmt.Errorf("error zipping and encoding: %w", err)
+		return nil, err
+	}
+	defer rj.close()
+	header, resumed, err := rj.header(recordHide, dataSum(zipData))
+	if err == nil && resumed && header.Parts != partCount {
+		err = fmt.Errorf("the interrupted run in %s used %d parts, remove it to start over", st.dir, header.Parts)
+	}
+	if err != nil {
+		return nil, err
 	}
-	prettywriter.Writeln("[>>] Splitting zip into padded parts", prettywriter.Green, prettywriter.BlackBG)
 
+	c.info(StagePack, "Splitting zip into padded parts")
 	// to tackle known cleartext attack on the zip header we are going to add a random amount of random data at the beginning. this
 	// might not be the perfect solution tho it requires an attacker to use either allow of brute force or figure some very smart
 	// frequency analysis to find it.
-	randomFrontPadding, err := genRandomBytesFn(1000, 10000)
-	if err != nil {
-		return fmt.Errorf("error generating random front padding: %w", err)
+	randomFrontPadding := header.FrontPadding
+	if !resumed {
+		randomFrontPadding, err = genRandomBytesFn(1000, 10000)
+		if err != nil {
+			return nil, fmt.Errorf("error generating random front padding: %w", err)
+		}
+		if err := rj.add(journalRecord{Kind: recordHide, Sum: dataSum(zipData), FrontPadding: randomFrontPadding, Parts: partCount}); err != nil {
+			return nil, err
+		}
 	}
 	paddedZipData := append(randomFrontPadding, zipData...)
 	frontPaddingAmount := len(randomFrontPadding)
 
 	// Step 2: Split the zip slice into parts with padding
 	parts, backPadding := splitter.SplitBytesWithPadding(paddedZipData, c.PartCount)
+	c.finishStage(StagePack, "")
 
-	// Step 3: Run encryption on all the parts, store them encrypted and add the info to masterlock
-	var partInfos []masterlock.PartInfo
-	for i, part := range parts {
-		fmt.Print("\r")
-		prettywriter.Write("[>>] Encrypt and store parts : "+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(parts)), prettywriter.Green, prettywriter.BlackBG)
-		encryptedPart, key, err := encryptWithRandomKeyFn(part)
+	// Step 3: Run encryption on all the parts, store them encrypted and add the info to masterlock.
+	// The parts are written in random order, so the order they got created on disk in (e.g. the
+	// inode numbers) doesn't tell their sequence either.
+	order, err := randomPermutationFn(len(parts))
+	if err != nil {
+		return nil, fmt.Errorf("error shuffling parts: %w", err)
+	}
+	partInfos := make([]masterlock.PartInfo, len(parts))
+	if err := canceled(ctx, StageEncrypt); err != nil {
+		return nil, err
+	}
+	c.startStage(StageEncrypt)
+	stored := rj.storedParts()
+	if len(stored) > 0 {
+		st.retain()
+		c.info(StageEncrypt, "Resuming, "+strconv.Itoa(len(stored))+" parts already stored")
+	}
+	progress := func(done int) {
+		c.progress(StageEncrypt, "Encrypt and store parts", done, len(parts))
+	}
+	err = runPool(ctx, order, c.Jobs, func(i int) error {
+		if partInfo, ok := stored[i]; ok {
+			partInfos[i] = partInfo
+			return nil
+		}
+		encryptedPart, key, err := encryptWithRandomKeyFn(parts[i])
 		if err != nil {
 			return fmt.Errorf("error encrypting part: %w", err)
 		}
@@ -96,140 +324,483 @@ func (c *Core) Hide(dataPath string, partCount int, outputDir string, prefilledP
 			return fmt.Errorf("error generating filename: %w", err)
 		}
 
-		partPath := filepath.Join(outputDir, filename)
+		partPath := st.path(filename)
 		err = writeToFileFn(partPath, encryptedPart)
 		if err != nil {
 			return fmt.Errorf("error writing encrypted part to file: %w", err)
 		}
 
-		partInfos = append(partInfos, masterlock.PartInfo{
+		partInfos[i] = masterlock.PartInfo{
 			Index:    i,
 			Filename: filename,
 			Key:      key,
-		})
+		}
+		return rj.add(journalRecord{Kind: recordPart, Sum: dataSum(encryptedPart), Part: &partInfos[i]})
+	}, progress)
+	if err != nil {
+		return nil, interrupted(ctx, StageEncrypt, err)
 	}
-	fmt.Println("")
-	prettywriter.Writeln("[**] All parts successfully encrypted and stored.", prettywriter.BlackBG, prettywriter.Green)
-	fmt.Println("")
-	// Step 4: Create Masterlock, prompt user for pwd and encrypt and store the masterlock
-	masterLockData, err := createMasterLockFn(partInfos, frontPaddingAmount, backPadding)
+	if err := removeUnlisted(st, partInfos); err != nil {
+		return nil, err
+	}
+	c.finishStage(StageEncrypt, "All parts successfully encrypted and stored.")
+	// Step 4: Create Masterlock, encrypt and store it
+	masterLockData, err := createMasterLockFn(partInfos, frontPaddingAmount, backPadding, archiveInfo)
 	if err != nil {
-		return fmt.Errorf("error creating master lock file: %w", err)
+		return nil, fmt.Errorf("error creating master lock file: %w", err)
 	}
 
-	password := ""
-	if "" == prefilledPassword {
-		password = promptPasswordFn("Please enter a password to encrypt the masterlock: ")
-	} else {
-		password = prefilledPassword
+	if err := canceled(ctx, StageMasterlock); err != nil {
+		return nil, err
+	}
+	c.startStage(StageMasterlock)
+	c.info(StageMasterlock, "Encrypting masterlock")
+	encryptedMasterLock, err := encryptWithPasswordFn(masterLockData, creds.password, creds.keyfiles...)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting master lock file: %w", err)
 	}
-	fmt.Println("")
-	prettywriter.WriteInBox(40, "Handle masterlock file", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	prettywriter.Writeln("[>>] Encrypting masterlock", prettywriter.BlackBG, prettywriter.Green)
-	encryptedMasterLock, err := encryptWithPasswordFn(masterLockData, password)
+	// the factors it needs are recorded in front, so unhiding knows what to ask for
+	encryptedMasterLock, err = masterlock.AddHeader(creds.factors(), encryptedMasterLock)
 	if err != nil {
-		return fmt.Errorf("error encrypting master lock file: %w", err)
+		return nil, err
 	}
-	masterLockPath := filepath.Join(outputDir, "masterlock")
-	prettywriter.Writeln("[>>] Writing masterlock", prettywriter.BlackBG, prettywriter.Green)
+	// the last chance to stop, once published the run is completed
+	if err := canceled(ctx, StageMasterlock); err != nil {
+		return nil, err
+	}
+	masterLockPath := st.path("masterlock")
+	c.info(StageMasterlock, "Writing masterlock")
 	err = writeToFileFn(masterLockPath, encryptedMasterLock)
 	if err != nil {
-		return fmt.Errorf("error writing master lock file: %w", err)
+		return nil, fmt.Errorf("error writing master lock file: %w", err)
+	}
+	c.finishStage(StageMasterlock, "Masterlock successful written")
+	c.startStage(StagePublish)
+	c.info(StagePublish, "Publishing parts and masterlock")
+	existing, err := st.existing()
+	if err != nil {
+		return nil, fmt.Errorf("error checking output directory: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("output directory already contains %s", strings.Join(existing, ", "))
+	}
+	if err := st.publish("masterlock"); err != nil {
+		return nil, err
+	}
+	result := &HideResult{Output: outputDir, Masterlock: filepath.Join(outputDir, "masterlock"), DataBytes: int64(len(zipData))}
+	published := make([]string, 0, len(partInfos)+1)
+	for _, partInfo := range partInfos {
+		result.Parts = append(result.Parts, partInfo.Filename)
+		published = append(published, filepath.Join(outputDir, partInfo.Filename))
 	}
-	prettywriter.Writeln("[**] Masterlock successful written", prettywriter.BlackBG, prettywriter.Green)
-	fmt.Println("")
-	prettywriter.WriteInBox(40, "Final Shenanigans", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	prettywriter.Writeln("[>>] Obfuscating timestamps ", prettywriter.BlackBG, prettywriter.Green)
+	sort.Strings(result.Parts)
+	published = append(published, result.Masterlock)
+	for _, path := range published {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error checking published file: %w", err)
+		}
+		result.StoredBytes += info.Size()
+	}
+	c.finishStage(StagePublish, "")
+	c.startStage(StageTimestamps)
+	c.info(StageTimestamps, "Obfuscating timestamps")
 	// Step 5: Obfuscate timestamps to hide theoriginal encrypted parts order
-	err = obfuscateFileTimestampsFn(outputDir)
+	err = obfuscateFileTimestampsFn(published, c.Timestamps)
 	if err != nil {
-		return fmt.Errorf("error obfuscating file timestamps: %w", err)
+		return nil, fmt.Errorf("error obfuscating file timestamps: %w", err)
 	}
-	prettywriter.Writeln("[**] Timestamps successful altered", prettywriter.BlackBG, prettywriter.Green)
-	fmt.Println("")
-	prettywriter.WriteInBox(40, "Encryption finished", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	return nil
+	c.finishStage(StageTimestamps, "Timestamps successful altered")
+	if c.ShredSource {
+		// the source is only wiped once what got stored has proven to decrypt to the hidden data
+		if err := canceled(ctx, StageVerify); err != nil {
+			return nil, fmt.Errorf("data hidden, source kept: %w", err)
+		}
+		c.startStage(StageVerify)
+		if err := verify(outputDir, creds, zipData); err != nil {
+			return nil, fmt.Errorf("verification failed, source kept: %w", err)
+		}
+		c.finishStage(StageVerify, "Stored data verified")
+	}
+	return result, nil
 }
 
 func (c *Core) Unhide(partsDir, outputPath string, prefilledPassword string) error {
-	prettywriter.WriteInBox(40, "Configuration", prettywriter.Green, prettywriter.BlackBG, prettywriter.DoubleLine)
-	prettywriter.Writeln("[==] Chosen mode: unhide (decrypting)", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[==] Input path: "+partsDir, prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[==] Output path: "+outputPath, prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println("")
+	_, err := c.unhideDir(context.Background(), partsDir, outputPath, prefilledPassword)
+	return err
+}
 
-	prettywriter.WriteInBox(40, "Starting Decryption Process", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	fmt.Println("")
+func (c *Core) unhideDir(ctx context.Context, partsDir, outputPath string, prefilledPassword string) (*UnhideResult, error) {
+	if err := zipper.ValidateConflictPolicy(c.OnConflict); err != nil {
+		return nil, err
+	}
+	result := &UnhideResult{Output: outputPath}
+	// the data is extracted into a staging directory and only moved into place once complete
+	// and extracted files are recorded in the journal, so a resumed run only extracts the rest
+	unpack := func(archive masterlock.ArchiveInfo, data []byte, creds credentials) error {
+		st, err := newStagingFn(outputPath, c.Resume, c.Observer)
+		if err != nil {
+			return err
+		}
+		defer st.close()
+		rj, err := openJournal(st, creds)
+		if err != nil {
+			return err
+		}
+		defer rj.close()
+		_, resumed, err := rj.header(recordUnhide, dataSum(data))
+		if err != nil {
+			return err
+		}
+		if !resumed {
+			if err := rj.add(journalRecord{Kind: recordUnhide, Sum: dataSum(data)}); err != nil {
+				return err
+			}
+		}
+		opts := zipper.Options{OnConflict: c.OnConflict, StagingDir: st.dir, Journal: newFileJournal(rj)}
+		container, err := zipper.NewContainer(archive.Format, opts)
+		if err != nil {
+			return err
+		}
+		if err := container.UnpackContext(ctx, data, outputPath); err != nil {
+			return err
+		}
+		if err := st.publish(""); err != nil {
+			return err
+		}
+		result.Files, result.Conflicts = container.Extracted(), container.Conflicts()
+		c.reportConflicts(result.Conflicts)
+		return nil
+	}
+	if err := c.unhide(ctx, "unhide (decrypting)", partsDir, outputPath, unpack, prefilledPassword); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	// Step 1: Decrypt Master Lock File
-	password := ""
-	if "" == prefilledPassword {
-		password = promptPasswordFn("Enter the password to decrypt the masterlock: ")
-	} else {
-		password = prefilledPassword
+// UnhideTo writes the content of data hidden as a single file, e.g. by HideReader, to w
+// instead of extracting it into a directory
+func (c *Core) UnhideTo(partsDir string, w io.Writer, prefilledPassword string) error {
+	_, err := c.unhideTo(context.Background(), partsDir, w, prefilledPassword)
+	return err
+}
+
+func (c *Core) unhideTo(ctx context.Context, partsDir string, w io.Writer, prefilledPassword string) (*UnhideResult, error) {
+	if c.Resume {
+		return nil, fmt.Errorf("resuming needs an output directory, not a stream")
+	}
+	unpack := func(archive masterlock.ArchiveInfo, data []byte, _ credentials) error {
+		container, err := zipper.NewContainer(archive.Format, zipper.Options{})
+		if err != nil {
+			return err
+		}
+		_, err = container.UnpackStreamContext(ctx, data, w)
+		return err
 	}
+	if err := c.unhide(ctx, "unhide (decrypting)", partsDir, "stream", unpack, prefilledPassword); err != nil {
+		return nil, err
+	}
+	return &UnhideResult{}, nil
+}
+
+// unhide decrypts the hidden data and hands the container data to unpack. mode and output
+// describe the run and its destination for the user, there's no destination if output is empty.
+// Once ctx is done the stage running is stopped, unpack is expected to stop as well and remove
+// what it extracted so far.
+func (c *Core) unhide(ctx context.Context, mode string, partsDir string, output string, unpack func(archive masterlock.ArchiveInfo, data []byte, creds credentials) error, prefilledPassword string) error {
+	c.startStage(StageConfigure)
+	c.info(StageConfigure, "Chosen mode: "+mode)
+	c.info(StageConfigure, "Input path: "+partsDir)
+	if output != "" {
+		c.info(StageConfigure, "Output path: "+output)
+	}
+	c.finishStage(StageConfigure, "")
 
-	fmt.Println()
-	prettywriter.WriteInBox(40, "Handling masterlock", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	prettywriter.Writeln("[>>] Reading masterlock", prettywriter.BlackBG, prettywriter.Green)
+	// Step 1: Decrypt Master Lock File
+	if err := canceled(ctx, StageMasterlock); err != nil {
+		return err
+	}
+	c.startStage(StageMasterlock)
+	c.info(StageMasterlock, "Reading masterlock")
 	encryptedMasterLock, err := readFileFn(filepath.Join(partsDir, "masterlock"))
 	if err != nil {
 		return fmt.Errorf("error reading encrypted master lock file: %w", err)
 	}
-
-	prettywriter.Writeln("[>>] Decrypting masterlock", prettywriter.BlackBG, prettywriter.Green)
-	decryptedMasterLock, err := decryptWithPasswordFn(encryptedMasterLock, password)
+	creds, decryptedMasterLock, err := c.openMasterlock(ctx, encryptedMasterLock, prefilledPassword)
 	if err != nil {
-		return fmt.Errorf("error decrypting master lock file: %w", err)
+		return err
 	}
 
-	prettywriter.Writeln("[>>] Unpacking masterlock", prettywriter.BlackBG, prettywriter.Green)
+	c.info(StageMasterlock, "Unpacking masterlock")
 	var mlock masterlock.MasterLock
 	err = jsonUnmarshalFn(decryptedMasterLock, &mlock)
 	if err != nil {
 		return fmt.Errorf("error unmarshaling master lock file: %w", err)
 	}
-	prettywriter.Writeln("[**] Masterlock handled successful", prettywriter.BlackBG, prettywriter.Green)
+	if err := checkArchiveInfo(mlock.Archive); err != nil {
+		return err
+	}
+	c.finishStage(StageMasterlock, "Masterlock handled successful")
 
 	// Step 2: Decrypt Each Part
-	var allParts [][]byte
-	fmt.Println("")
-	prettywriter.WriteInBox(40, "Handling encrypted parts", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
-	for i, partInfo := range mlock.Parts {
-		fmt.Print("\r")
-		prettywriter.Write("[>>] Decrypting parts : "+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(mlock.Parts)), prettywriter.Green, prettywriter.BlackBG)
-		partPath := filepath.Join(partsDir, partInfo.Filename)
-		encryptedPart, err := osReadFileFn(partPath)
-		if err != nil {
-			return fmt.Errorf("error reading encrypted part file: %w", err)
-		}
-
-		decryptedPart, err := decryptWithRandomKeyFn(encryptedPart, partInfo.Key)
+	allParts := make([][]byte, len(mlock.Parts))
+	if err := canceled(ctx, StageDecrypt); err != nil {
+		return err
+	}
+	c.startStage(StageDecrypt)
+	order := make([]int, len(mlock.Parts))
+	for i := range order {
+		order[i] = i
+	}
+	progress := func(done int) {
+		c.progress(StageDecrypt, "Decrypting parts", done, len(mlock.Parts))
+	}
+	err = runPool(ctx, order, c.Jobs, func(i int) error {
+		decryptedPart, err := decryptPart(partsDir, i, mlock.Parts[i])
 		if err != nil {
-			return fmt.Errorf("error decrypting part: %w", err)
+			return err
 		}
 
-		allParts = append(allParts, decryptedPart)
+		allParts[i] = decryptedPart
+		return nil
+	}, progress)
+	if err != nil {
+		return interrupted(ctx, StageDecrypt, err)
 	}
-	fmt.Println("")
-	prettywriter.Writeln("[**] Parts decrypted successful ", prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println("")
+	c.finishStage(StageDecrypt, "Parts decrypted successful")
 
 	// Step 3: Reconstruct zip Data
-	prettywriter.WriteInBox(40, "Handling zip", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
+	if err := canceled(ctx, StageUnpack); err != nil {
+		return err
+	}
+	c.startStage(StageUnpack)
 	paddedData := utils.ConcatByteSlices(allParts)
 	paddedDataLen := len(paddedData)
+	if mlock.FrontPadding < 0 || mlock.BackPadding < 0 || mlock.FrontPadding+mlock.BackPadding > paddedDataLen {
+		return fmt.Errorf("parts hold less data than their padding: %w", ErrPartCorrupt)
+	}
 	unpaddedData := paddedData[mlock.FrontPadding : paddedDataLen-mlock.BackPadding]
-	prettywriter.Writeln("[**] Reconstructed zip data without padding ", prettywriter.Green, prettywriter.BlackBG)
-	prettywriter.Writeln("[>>] Unpacking zip data ", prettywriter.Green, prettywriter.BlackBG)
-	zipper := zipper.New()
-	err = zipper.Extract(unpaddedData, outputPath)
+	c.info(StageUnpack, "Reconstructed zip data without padding")
+	c.info(StageUnpack, "Unpacking zip data")
+	err = unpack(mlock.Archive, unpaddedData, creds)
 	if err != nil {
-		return fmt.Errorf("error unzipping data: %w", err)
+		return interrupted(ctx, StageUnpack, fmt.Errorf("error unzipping data: %w", err))
 	}
-	prettywriter.Writeln("[**] Successfully unpacked zip data ", prettywriter.Green, prettywriter.BlackBG)
-	fmt.Println()
-	prettywriter.WriteInBox(40, "Decryption finished", prettywriter.Black, prettywriter.Green, prettywriter.DoubleLine)
+	c.finishStage(StageUnpack, "Successfully unpacked zip data")
 
 	return nil
 }
+
+// openMasterlock decrypts the masterlock read from disk and returns the credentials it took. The
+// header of the masterlock tells whether a password and how many keyfiles are required. After a
+// wrong password the password provider is asked again, up to PasswordAttempts times in all.
+func (c *Core) openMasterlock(ctx context.Context, data []byte, prefilledPassword string) (credentials, []byte, error) {
+	factors, encryptedMasterLock, err := masterlock.SplitHeader(data)
+	if errors.Is(err, masterlock.ErrUnknownHeader) {
+		return credentials{}, nil, fmt.Errorf("%v is %w", err, ErrUnsupportedVersion)
+	}
+	if err != nil {
+		return credentials{}, nil, fmt.Errorf("error reading master lock file: %w", err)
+	}
+	keyfiles, err := c.unhideKeyfiles(factors)
+	if err != nil {
+		return credentials{}, nil, err
+	}
+	attempts := c.PasswordAttempts
+	// a descriptor is read until its end and closed, there's nothing left for another attempt
+	_, fromFD := c.Password.(PasswordFD)
+	if attempts < 1 || prefilledPassword != "" || !factors.Password || fromFD {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		creds := credentials{keyfiles: keyfiles}
+		if factors.Password {
+			creds.password, err = c.password(prefilledPassword, PurposeUnhide)
+			if err != nil {
+				return credentials{}, nil, err
+			}
+		}
+		if err := canceled(ctx, StageMasterlock); err != nil {
+			return credentials{}, nil, err
+		}
+
+		c.info(StageMasterlock, "Decrypting masterlock")
+		decryptedMasterLock, err := decryptWithPasswordFn(encryptedMasterLock, creds.password, creds.keyfiles...)
+		if err == nil {
+			return creds, decryptedMasterLock, nil
+		}
+		if !errors.Is(err, encryptor.ErrDecrypt) {
+			return credentials{}, nil, fmt.Errorf("error decrypting master lock file: %w", err)
+		}
+		if attempt == attempts {
+			return credentials{}, nil, fmt.Errorf("error decrypting master lock file: %w", ErrWrongPassword)
+		}
+		c.warn(StageMasterlock, "Wrong password, "+strconv.Itoa(attempts-attempt)+" attempts left")
+	}
+}
+
+// decryptPart reads and decrypts the part described by partInfo, the i-th of the hidden data,
+// from partsDir
+func decryptPart(partsDir string, i int, partInfo masterlock.PartInfo) ([]byte, error) {
+	encryptedPart, err := osReadFileFn(filepath.Join(partsDir, partInfo.Filename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &ErrPartMissing{Index: i, Filename: partInfo.Filename, Err: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading encrypted part file: %w", err)
+	}
+
+	decryptedPart, err := decryptWithRandomKeyFn(encryptedPart, partInfo.Key)
+	if errors.Is(err, encryptor.ErrDecrypt) {
+		err = ErrPartCorrupt
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting part %d (%s): %w", i, partInfo.Filename, err)
+	}
+	return decryptedPart, nil
+}
+
+// password returns the prefilled password, or asks the password provider for one
+func (c *Core) password(prefilled string, purpose Purpose) (string, error) {
+	if prefilled != "" {
+		return prefilled, nil
+	}
+	if c.Password == nil {
+		return "", fmt.Errorf("no password given")
+	}
+	password, err := c.Password.Password(purpose)
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %w", err)
+	}
+	if password == "" {
+		return "", fmt.Errorf("invalid empty password")
+	}
+	return password, nil
+}
+
+// checkStrength refuses passwords estimated weaker than MinEntropy and warns about ones weaker
+// than recommended
+func (c *Core) checkStrength(password string) error {
+	bits := strength.Entropy(password)
+	if bits < c.MinEntropy {
+		return fmt.Errorf("%w: estimated %.0f bits of entropy, at least %.0f required", ErrWeakPassword, bits, c.MinEntropy)
+	}
+	// a keyfile protects against guessing, a weak password is only worth a warning without one
+	if bits < strength.Recommended && len(c.Keyfiles) == 0 {
+		c.warn(StageConfigure, fmt.Sprintf("The password is %s, estimated %.0f bits of entropy. At least %d are recommended, e.g. a passphrase of 5 random words.", strength.Describe(bits), bits, strength.Recommended))
+	}
+	return nil
+}
+
+// archiveInfo returns the container format and compression this core hides with, filling in
+// the defaults of the format for unset values
+func (c *Core) archiveInfo() masterlock.ArchiveInfo {
+	info := masterlock.ArchiveInfo{Format: c.Format, Compression: c.Compression}
+	if info.Format == "" {
+		info.Format = zipper.FormatZip
+	}
+	if info.Compression == "" {
+		switch info.Format {
+		case zipper.FormatTar:
+			info.Compression = zipper.CompressionStore
+		case zipper.FormatTarZstd:
+			info.Compression = zipper.CompressionZstd
+		default:
+			info.Compression = zipper.CompressionDeflate
+		}
+	}
+	return info
+}
+
+// removeUnlisted removes staged files which aren't one of the parts, e.g. a part an interrupted
+// run wrote but didn't get to record anymore
+func removeUnlisted(st *staging, partInfos []masterlock.PartInfo) error {
+	listed := map[string]bool{}
+	for _, partInfo := range partInfos {
+		listed[partInfo.Filename] = true
+	}
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !listed[entry.Name()] {
+			if err := os.RemoveAll(st.path(entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkOutputDir makes sure hiding doesn't mix new parts into a directory already holding
+// other files, e.g. an older archive, unless forced. A missing directory gets created. An
+// interrupted run found there has to be resumed or removed.
+func checkOutputDir(outputDir string, force bool, resume bool) error {
+	entries, err := os.ReadDir(outputDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading output directory: %w", err)
+	}
+	// the staging directory and journal of an interrupted run don't count, they're ours
+	other := 0
+	for _, entry := range entries {
+		switch entry.Name() {
+		case stagingName, stagingName + ".journal":
+			if !resume {
+				return interruptedRunError(filepath.Join(outputDir, stagingName))
+			}
+		default:
+			other++
+		}
+	}
+	if other > 0 && !force {
+		return fmt.Errorf("output directory %s is not empty, use force to hide into it anyway", outputDir)
+	}
+	return nil
+}
+
+// reportConflicts tells how entries clashing with existing paths were handled
+func (c *Core) reportConflicts(conflicts []zipper.Conflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+	for _, conflict := range conflicts {
+		switch conflict.Action {
+		case zipper.ConflictSkip:
+			c.warn(StageUnpack, "Skipped existing "+conflict.Name)
+		case zipper.ConflictRename:
+			c.warn(StageUnpack, "Renamed "+conflict.Name+" to "+conflict.Target)
+		case zipper.ConflictOverwrite:
+			c.warn(StageUnpack, "Overwrote existing "+conflict.Name)
+		}
+	}
+	c.info(StageUnpack, strconv.Itoa(len(conflicts))+" conflicts with existing files resolved")
+}
+
+// containerOptions returns the options the input data is packed with
+func (c *Core) containerOptions(archiveInfo masterlock.ArchiveInfo) zipper.Options {
+	return zipper.Options{
+		StripMetadata:  c.StripMetadata,
+		FollowSymlinks: c.FollowSymlinks,
+		Compression:    archiveInfo.Compression,
+		Level:          c.CompressionLevel,
+		Excludes:       c.Excludes,
+	}
+}
+
+// checkArchiveInfo makes sure the archive described in the masterlock can be unpacked by this version
+func checkArchiveInfo(info masterlock.ArchiveInfo) error {
+	switch info.Format {
+	case "", zipper.FormatZip, zipper.FormatTar, zipper.FormatTarZstd:
+	default:
+		return fmt.Errorf("container format %q recorded in masterlock is %w", info.Format, ErrUnsupportedVersion)
+	}
+	switch info.Compression {
+	case "", zipper.CompressionStore, zipper.CompressionDeflate, zipper.CompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("compression %q recorded in masterlock is %w", info.Compression, ErrUnsupportedVersion)
+}
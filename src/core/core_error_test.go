@@ -1,9 +1,13 @@
 package core
 
 import (
+    "errors"
+    "io/fs"
     "os"
     "path/filepath"
     "testing"
+
+    ml "github.com/voodooEntity/go-tachicrypt/src/masterlock"
 )
 
 func TestCore_Hide_InvalidPathReturnsError(t *testing.T) {
@@ -35,8 +39,8 @@ func TestCore_Unhide_WrongPassword(t *testing.T) {
     }
 
     // Try to unhide with the wrong password
-    if err := c.Unhide(enc, out, "wrong-pass"); err == nil {
-        t.Fatalf("expected error when decrypting masterlock with wrong password")
+    if err := c.Unhide(enc, out, "wrong-pass"); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword when decrypting masterlock with wrong password, got %v", err)
     }
 }
 
@@ -72,18 +76,57 @@ func TestCore_Unhide_MissingPartFile_ShouldError(t *testing.T) {
     // Remove one random non-masterlock file from enc to simulate missing part
     entries, err := os.ReadDir(enc)
     if err != nil { t.Fatalf("readdir: %v", err) }
-    removed := false
+    removed := ""
     for _, e := range entries {
         if e.Name() == "masterlock" { continue }
         _ = os.Remove(filepath.Join(enc, e.Name()))
-        removed = true
+        removed = e.Name()
         break
     }
-    if !removed {
+    if removed == "" {
         t.Fatalf("did not find part file to remove")
     }
 
-    if err := c.Unhide(enc, out, "pw"); err == nil {
-        t.Fatalf("expected error when a part file is missing")
+    err = c.Unhide(enc, out, "pw")
+    var missing *ErrPartMissing
+    if !errors.As(err, &missing) || missing.Filename != removed || !errors.Is(err, fs.ErrNotExist) {
+        t.Fatalf("expected ErrPartMissing for %s, got %v", removed, err)
+    }
+}
+
+func TestCore_Unhide_CorruptPart(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "in.txt")
+    if err := os.WriteFile(src, []byte("abc"), 0o644); err != nil { t.Fatalf("write src: %v", err) }
+    enc := filepath.Join(tmp, "enc")
+
+    c := New()
+    if err := c.Hide(src, 2, enc, "pw"); err != nil { t.Fatalf("hide: %v", err) }
+    entries, err := os.ReadDir(enc)
+    if err != nil { t.Fatalf("readdir: %v", err) }
+    for _, e := range entries {
+        if e.Name() == "masterlock" { continue }
+        part := filepath.Join(enc, e.Name())
+        data, err := os.ReadFile(part)
+        if err != nil { t.Fatalf("read part: %v", err) }
+        data[len(data)-1] ^= 0xFF
+        if err := os.WriteFile(part, data, 0o644); err != nil { t.Fatalf("write part: %v", err) }
+        break
+    }
+
+    if err := c.Unhide(enc, filepath.Join(tmp, "out"), "pw"); !errors.Is(err, ErrPartCorrupt) {
+        t.Fatalf("expected ErrPartCorrupt for a tampered part, got %v", err)
+    }
+}
+
+func TestCore_Unhide_UnsupportedArchiveInfo(t *testing.T) {
+    if err := checkArchiveInfo(ml.ArchiveInfo{Compression: "lzma"}); !errors.Is(err, ErrUnsupportedVersion) {
+        t.Fatalf("expected unsupported compression to be rejected, got %v", err)
+    }
+    if err := checkArchiveInfo(ml.ArchiveInfo{Format: "rar"}); !errors.Is(err, ErrUnsupportedVersion) {
+        t.Fatalf("expected unsupported format to be rejected, got %v", err)
+    }
+    if err := checkArchiveInfo(ml.ArchiveInfo{}); err != nil {
+        t.Fatalf("expected masterlocks without archive info to be accepted: %v", err)
     }
 }
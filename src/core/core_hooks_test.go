@@ -5,8 +5,10 @@ import (
     "errors"
     "os"
     "path/filepath"
+    "strings"
     "testing"
 
+    "github.com/voodooEntity/go-tachicrypt/src/fileutils"
     ml "github.com/voodooEntity/go-tachicrypt/src/masterlock"
 )
 
@@ -25,21 +27,35 @@ func mkInputEnv(t *testing.T) (src string, encDir string, outDir string) {
     return
 }
 
+// passwordFunc lets a function be used as PasswordProvider
+type passwordFunc func(purpose Purpose) (string, error)
+
+func (f passwordFunc) Password(purpose Purpose) (string, error) { return f(purpose) }
+
 // restore all hooks to real implementations
 // note: we prefer per-test t.Cleanup restorations rather than a global reset
 
-func TestCore_Hide_UsesPromptPasswordBranch(t *testing.T) {
+func TestCore_Hide_UsesPasswordProvider(t *testing.T) {
     src, enc, _ := mkInputEnv(t)
-    // stub prompt to avoid TTY
-    oldPrompt := promptPasswordFn
-    promptPasswordFn = func(string) string { return "prompt-pass" }
     oldObf := obfuscateFileTimestampsFn
-    obfuscateFileTimestampsFn = func(string) error { return nil }
-    t.Cleanup(func() { promptPasswordFn = oldPrompt; obfuscateFileTimestampsFn = oldObf })
+    obfuscateFileTimestampsFn = func([]string, fileutils.Timestamps) error { return nil }
+    t.Cleanup(func() { obfuscateFileTimestampsFn = oldObf })
 
     c := New()
+    if err := c.Hide(src, 2, enc, ""); err == nil || !strings.Contains(err.Error(), "no password") {
+        t.Fatalf("expected hide without any password to fail, got %v", err)
+    }
+    c.Password = StaticPassword("")
+    if err := c.Hide(src, 2, enc, ""); err == nil || !strings.Contains(err.Error(), "empty password") {
+        t.Fatalf("expected an empty password to be refused, got %v", err)
+    }
+    var purpose Purpose
+    c.Password = passwordFunc(func(p Purpose) (string, error) { purpose = p; return "provided-pass", nil })
     if err := c.Hide(src, 2, enc, ""); err != nil {
-        t.Fatalf("Hide with prompt branch failed: %v", err)
+        t.Fatalf("Hide with password provider failed: %v", err)
+    }
+    if purpose != PurposeHide {
+        t.Fatalf("expected the password to be asked for hiding, got %q", purpose)
     }
 }
 
@@ -94,7 +110,7 @@ func TestCore_Hide_ErrorFromWritePart(t *testing.T) {
 func TestCore_Hide_ErrorFromCreateMasterlock(t *testing.T) {
     src, enc, _ := mkInputEnv(t)
     old := createMasterLockFn
-    createMasterLockFn = func(_ []ml.PartInfo, _ int, _ int) ([]byte, error) { return nil, errors.New("mk mlock") }
+    createMasterLockFn = func(_ []ml.PartInfo, _ int, _ int, _ ml.ArchiveInfo) ([]byte, error) { return nil, errors.New("mk mlock") }
     t.Cleanup(func() { createMasterLockFn = old })
     c := New()
     if err := c.Hide(src, 2, enc, "p"); err == nil {
@@ -105,7 +121,7 @@ func TestCore_Hide_ErrorFromCreateMasterlock(t *testing.T) {
 func TestCore_Hide_ErrorFromEncryptMasterlock(t *testing.T) {
     src, enc, _ := mkInputEnv(t)
     old := encryptWithPasswordFn
-    encryptWithPasswordFn = func(data []byte, pwd string) ([]byte, error) { return nil, errors.New("enc mlock") }
+    encryptWithPasswordFn = func(data []byte, pwd string, keyfiles ...[]byte) ([]byte, error) { return nil, errors.New("enc mlock") }
     t.Cleanup(func() { encryptWithPasswordFn = old })
     c := New()
     if err := c.Hide(src, 2, enc, "p"); err == nil {
@@ -130,7 +146,7 @@ func TestCore_Hide_ErrorFromWriteMasterlock(t *testing.T) {
 func TestCore_Hide_ErrorFromObfuscateTimestamps(t *testing.T) {
     src, enc, _ := mkInputEnv(t)
     old := obfuscateFileTimestampsFn
-    obfuscateFileTimestampsFn = func(string) error { return errors.New("obf") }
+    obfuscateFileTimestampsFn = func([]string, fileutils.Timestamps) error { return errors.New("obf") }
     t.Cleanup(func() { obfuscateFileTimestampsFn = old })
     c := New()
     if err := c.Hide(src, 2, enc, "p"); err == nil {
@@ -155,7 +171,7 @@ func TestCore_Unhide_ErrorDecryptingMasterlock(t *testing.T) {
     // place a dummy masterlock file (content irrelevant due to hook)
     if err := os.WriteFile(filepath.Join(enc, "masterlock"), []byte{1,2,3}, 0o644); err != nil { t.Fatalf("write mlock: %v", err) }
     old := decryptWithPasswordFn
-    decryptWithPasswordFn = func([]byte, string) ([]byte, error) { return nil, errors.New("dec mlock") }
+    decryptWithPasswordFn = func([]byte, string, ...[]byte) ([]byte, error) { return nil, errors.New("dec mlock") }
     t.Cleanup(func() { decryptWithPasswordFn = old })
     c := New()
     if err := c.Unhide(enc, out, "p"); err == nil {
@@ -167,7 +183,7 @@ func TestCore_Unhide_ErrorJSONUnmarshal(t *testing.T) {
     _, enc, out := mkInputEnv(t)
     if err := os.WriteFile(filepath.Join(enc, "masterlock"), []byte{1}, 0o644); err != nil { t.Fatalf("write mlock: %v", err) }
     oldDec := decryptWithPasswordFn
-    decryptWithPasswordFn = func([]byte, string) ([]byte, error) { return []byte("not json"), nil }
+    decryptWithPasswordFn = func([]byte, string, ...[]byte) ([]byte, error) { return []byte("not json"), nil }
     oldUn := jsonUnmarshalFn
     jsonUnmarshalFn = func([]byte, interface{}) error { return errors.New("bad json") }
     t.Cleanup(func() { decryptWithPasswordFn = oldDec; jsonUnmarshalFn = oldUn })
@@ -185,7 +201,7 @@ func TestCore_Unhide_ErrorDecryptingPart(t *testing.T) {
     if err := os.WriteFile(filepath.Join(enc, "masterlock"), []byte{1}, 0o644); err != nil { t.Fatalf("write mlock: %v", err) }
     // Hook to return our JSON regardless of file contents
     oldDec := decryptWithPasswordFn
-    decryptWithPasswordFn = func([]byte, string) ([]byte, error) { return data, nil }
+    decryptWithPasswordFn = func([]byte, string, ...[]byte) ([]byte, error) { return data, nil }
     t.Cleanup(func() { decryptWithPasswordFn = oldDec })
     // Create part file p1
     if err := os.WriteFile(filepath.Join(enc, "p1"), []byte{9,9,9}, 0o644); err != nil { t.Fatalf("write part: %v", err) }
@@ -199,19 +215,22 @@ func TestCore_Unhide_ErrorDecryptingPart(t *testing.T) {
     }
 }
 
-func TestCore_Unhide_UsesPromptPasswordBranch(t *testing.T) {
+func TestCore_Unhide_UsesPasswordProvider(t *testing.T) {
     src, enc, out := mkInputEnv(t)
     // First, create encrypted set with known password using normal flow
     c := New()
     if err := c.Hide(src, 2, enc, "known-pass"); err != nil {
         t.Fatalf("hide failed: %v", err)
     }
-    // Now unhide but force prompt branch to supply the password
-    oldPrompt := promptPasswordFn
-    promptPasswordFn = func(string) string { return "known-pass" }
-    t.Cleanup(func() { promptPasswordFn = oldPrompt })
+    // Now unhide with the password supplied by the provider
+    c.Password = passwordFunc(func(p Purpose) (string, error) {
+        if p != PurposeUnhide {
+            return "", errors.New("unexpected purpose " + string(p))
+        }
+        return "known-pass", nil
+    })
     if err := c.Unhide(enc, out, ""); err != nil {
-        t.Fatalf("unhide with prompt branch failed: %v", err)
+        t.Fatalf("unhide with password provider failed: %v", err)
     }
     // Verify round-trip
     restored := filepath.Join(out, filepath.Base(src))
@@ -222,6 +241,85 @@ func TestCore_Unhide_UsesPromptPasswordBranch(t *testing.T) {
     }
 }
 
+func TestCore_Unhide_PasswordAttempts(t *testing.T) {
+    src, enc, out := mkInputEnv(t)
+    if err := New().Hide(src, 2, enc, "known-pass"); err != nil {
+        t.Fatalf("hide failed: %v", err)
+    }
+    reads := 0
+    oldRead := readFileFn
+    readFileFn = func(name string) ([]byte, error) { reads++; return oldRead(name) }
+    t.Cleanup(func() { readFileFn = oldRead })
+
+    typed := []string{"typo", "again wrong", "known-pass"}
+    asked := 0
+    events := &recorder{}
+    c := New()
+    c.Observer = events
+    c.PasswordAttempts = 3
+    c.Password = passwordFunc(func(Purpose) (string, error) { asked++; return typed[asked-1], nil })
+    if err := c.Unhide(enc, out, ""); err != nil {
+        t.Fatalf("expected the third attempt to succeed: %v", err)
+    }
+    if asked != 3 || reads != 1 {
+        t.Fatalf("expected three attempts reading the masterlock once, got %d attempts and %d reads", asked, reads)
+    }
+    warnings := 0
+    for _, e := range events.events {
+        if e.Kind == EventWarning && strings.Contains(e.Message, "Wrong password") {
+            warnings++
+        }
+    }
+    if warnings != 2 {
+        t.Fatalf("expected a warning per wrong attempt, got %d", warnings)
+    }
+
+    asked = 0
+    c.PasswordAttempts = 2
+    if err := c.Unhide(enc, filepath.Join(t.TempDir(), "out"), ""); !errors.Is(err, ErrWrongPassword) || asked != 2 {
+        t.Fatalf("expected to give up after two attempts, got %v after %d", err, asked)
+    }
+}
+
+func TestCore_Hide_PasswordStrength(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    c := New()
+    c.MinEntropy = 40
+    if err := c.Hide(src, 2, enc, "password1"); !errors.Is(err, ErrWeakPassword) {
+        t.Fatalf("expected a weak password to be refused, got %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(enc, "masterlock")); !os.IsNotExist(err) {
+        t.Fatalf("expected nothing to be written, got %v", err)
+    }
+
+    events := &recorder{}
+    c.Observer = events
+    if err := c.Hide(src, 2, enc, "kT9#vQ2!mZx"); err != nil {
+        t.Fatalf("hide failed: %v", err)
+    }
+    for _, e := range events.events {
+        if e.Kind == EventWarning && strings.Contains(e.Message, "entropy") {
+            t.Fatalf("expected no warning about a strong password, got %q", e.Message)
+        }
+    }
+}
+
+func TestCore_Hide_WarnsAboutWeakPassword(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    events := &recorder{}
+    c := New()
+    c.Observer = events
+    if err := c.Hide(src, 2, enc, "summer2019"); err != nil {
+        t.Fatalf("hide failed: %v", err)
+    }
+    for _, e := range events.events {
+        if e.Kind == EventWarning && strings.Contains(e.Message, "very weak") {
+            return
+        }
+    }
+    t.Fatalf("expected a warning about the weak password, got %+v", events.events)
+}
+
 func TestCore_Unhide_ErrorOnExtract(t *testing.T) {
     _, enc, out := mkInputEnv(t)
     // Make a masterlock with one part
@@ -230,7 +328,7 @@ func TestCore_Unhide_ErrorOnExtract(t *testing.T) {
     if err := os.WriteFile(filepath.Join(enc, "masterlock"), []byte{1}, 0o644); err != nil { t.Fatalf("write mlock: %v", err) }
     // Decrypt masterlock returns our JSON
     oldDec := decryptWithPasswordFn
-    decryptWithPasswordFn = func([]byte, string) ([]byte, error) { return data, nil }
+    decryptWithPasswordFn = func([]byte, string, ...[]byte) ([]byte, error) { return data, nil }
     t.Cleanup(func() { decryptWithPasswordFn = oldDec })
     // Create part file p1 and make part decryption return garbage (invalid zip)
     if err := os.WriteFile(filepath.Join(enc, "p1"), []byte{9}, 0o644); err != nil { t.Fatalf("write part: %v", err) }
@@ -5,7 +5,11 @@ import (
     "io/fs"
     "os"
     "path/filepath"
+    "strings"
     "testing"
+    "time"
+
+    "github.com/voodooEntity/go-tachicrypt/src/fileutils"
 )
 
 // writeFile is a small helper to create a file with content, ensuring parent dirs exist.
@@ -126,3 +130,278 @@ func TestCore_RoundTrip_Directory(t *testing.T) {
         }
     }
 }
+
+func TestCore_RoundTrip_ZstdCompression(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "dump")
+    writeFile(t, filepath.Join(srcDir, "db.sql"), bytes.Repeat([]byte("INSERT INTO t VALUES (1);\n"), 200))
+    encDir := filepath.Join(tmp, "enc")
+    outDir := filepath.Join(tmp, "out")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+
+    c := New()
+    c.Compression = "zstd"
+    c.CompressionLevel = 3
+    if err := c.Hide(srcDir, 3, encDir, "zstd-pass"); err != nil {
+        t.Fatalf("hide: %v", err)
+    }
+    if err := New().Unhide(encDir, outDir, "zstd-pass"); err != nil {
+        t.Fatalf("unhide: %v", err)
+    }
+    want := collectFiles(t, srcDir)
+    got := collectFiles(t, filepath.Join(outDir, "dump"))
+    if !bytes.Equal(got["db.sql"], want["db.sql"]) {
+        t.Fatalf("restored content mismatch")
+    }
+}
+
+func TestCore_Hide_InvalidCompression(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "in.txt")
+    writeFile(t, src, []byte("data"))
+    c := New()
+    c.Compression = "lzma"
+    if err := c.Hide(src, 2, tmp, "p"); err == nil {
+        t.Fatalf("expected error for unknown compression")
+    }
+}
+
+func TestCore_RoundTrip_TarFormats(t *testing.T) {
+    for _, format := range []string{"tar", "tar.zst"} {
+        tmp := t.TempDir()
+        srcDir := filepath.Join(tmp, "tree")
+        writeFile(t, filepath.Join(srcDir, "a", "one.txt"), []byte("one"))
+        writeFile(t, filepath.Join(srcDir, "two.txt"), []byte("two"))
+        encDir := filepath.Join(tmp, "enc")
+        outDir := filepath.Join(tmp, "out")
+        if err := os.MkdirAll(encDir, 0o755); err != nil {
+            t.Fatalf("mkdir enc: %v", err)
+        }
+
+        c := New()
+        c.Format = format
+        if err := c.Hide(srcDir, 3, encDir, "tar-pass"); err != nil {
+            t.Fatalf("%s hide: %v", format, err)
+        }
+        // unhide takes the format from the masterlock
+        if err := New().Unhide(encDir, outDir, "tar-pass"); err != nil {
+            t.Fatalf("%s unhide: %v", format, err)
+        }
+        got := collectFiles(t, filepath.Join(outDir, "tree"))
+        if string(got[filepath.Join("a", "one.txt")]) != "one" || string(got["two.txt"]) != "two" {
+            t.Fatalf("%s: restored content mismatch: %v", format, got)
+        }
+    }
+}
+
+func TestCore_RoundTrip_Excludes(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "proj")
+    writeFile(t, filepath.Join(srcDir, "main.go"), []byte("package main"))
+    writeFile(t, filepath.Join(srcDir, "node_modules", "dep.js"), []byte("dep"))
+    writeFile(t, filepath.Join(srcDir, "build", "out.o"), []byte("obj"))
+    writeFile(t, filepath.Join(srcDir, ".tachiignore"), []byte("/build\n"))
+    encDir := filepath.Join(tmp, "enc")
+    outDir := filepath.Join(tmp, "out")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+
+    c := New()
+    c.Excludes = []string{"node_modules/"}
+    listed, err := c.DryRun(srcDir)
+    if err != nil {
+        t.Fatalf("DryRun: %v", err)
+    }
+    for _, name := range listed {
+        if strings.Contains(name, "node_modules") || strings.Contains(name, "build") {
+            t.Fatalf("dry run lists excluded entry %s", name)
+        }
+    }
+    if err := c.Hide(srcDir, 2, encDir, "exclude-pass"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    if err := New().Unhide(encDir, outDir, "exclude-pass"); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+
+    got := collectFiles(t, filepath.Join(outDir, "proj"))
+    if len(got) != 2 || string(got["main.go"]) != "package main" || got[".tachiignore"] == nil {
+        t.Fatalf("unexpected restored files: %v", got)
+    }
+}
+
+func TestCore_DryRun_MissingInput(t *testing.T) {
+    if _, err := New().DryRun(filepath.Join(t.TempDir(), "missing")); err == nil {
+        t.Fatalf("expected error for missing input")
+    }
+}
+
+func TestCore_RoundTrip_MultiplePaths(t *testing.T) {
+    tmp := t.TempDir()
+    keys := filepath.Join(tmp, "keys")
+    notes := filepath.Join(tmp, "docs", "notes.md")
+    otherNotes := filepath.Join(tmp, "old", "notes.md")
+    writeFile(t, filepath.Join(keys, "id"), []byte("key"))
+    writeFile(t, notes, []byte("notes"))
+    writeFile(t, otherNotes, []byte("old notes"))
+    encDir := filepath.Join(tmp, "enc")
+    outDir := filepath.Join(tmp, "out")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+
+    if err := New().HidePaths([]string{keys, notes, otherNotes}, 3, encDir, "multi-pass"); err != nil {
+        t.Fatalf("HidePaths: %v", err)
+    }
+    if err := New().Unhide(encDir, outDir, "multi-pass"); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+
+    got := collectFiles(t, outDir)
+    want := map[string]string{filepath.Join("keys", "id"): "key", "notes.md": "notes", "notes_2.md": "old notes"}
+    if len(got) != len(want) {
+        t.Fatalf("unexpected restored files: %v", got)
+    }
+    for name, content := range want {
+        if string(got[name]) != content {
+            t.Fatalf("%s mismatch: %q", name, got[name])
+        }
+    }
+}
+
+func TestCore_RoundTrip_Stream(t *testing.T) {
+    tmp := t.TempDir()
+    encDir := filepath.Join(tmp, "enc")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+    payload := bytes.Repeat([]byte("row;"), 5000)
+
+    c := New()
+    c.Format = "tar.zst"
+    if err := c.HideReader(bytes.NewReader(payload), "dump.sql", 4, encDir, "stream-pass"); err != nil {
+        t.Fatalf("HideReader: %v", err)
+    }
+    var out bytes.Buffer
+    if err := New().UnhideTo(encDir, &out, "stream-pass"); err != nil {
+        t.Fatalf("UnhideTo: %v", err)
+    }
+    if !bytes.Equal(out.Bytes(), payload) {
+        t.Fatalf("stream content mismatch: %d bytes", out.Len())
+    }
+}
+
+func TestCore_UnhideTo_RejectsDirectories(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "dir")
+    writeFile(t, filepath.Join(srcDir, "a.txt"), []byte("a"))
+    encDir := filepath.Join(tmp, "enc")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+    if err := New().Hide(srcDir, 2, encDir, "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    var out bytes.Buffer
+    if err := New().UnhideTo(encDir, &out, "p"); err == nil {
+        t.Fatalf("expected error writing a directory to a stream")
+    }
+}
+
+func TestCore_Unhide_ConflictPolicies(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "docs")
+    writeFile(t, filepath.Join(srcDir, "a.txt"), []byte("hidden a"))
+    encDir := filepath.Join(tmp, "enc")
+    outDir := filepath.Join(tmp, "out")
+    if err := os.MkdirAll(encDir, 0o755); err != nil {
+        t.Fatalf("mkdir enc: %v", err)
+    }
+    if err := New().Hide(srcDir, 2, encDir, "conflict-pass"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    writeFile(t, filepath.Join(outDir, "docs", "a.txt"), []byte("precious"))
+
+    if err := New().Unhide(encDir, outDir, "conflict-pass"); err == nil {
+        t.Fatalf("expected the default policy to refuse overwriting")
+    }
+    c := New()
+    c.OnConflict = "rename"
+    if err := c.Unhide(encDir, outDir, "conflict-pass"); err != nil {
+        t.Fatalf("Unhide rename: %v", err)
+    }
+    got := collectFiles(t, outDir)
+    if string(got[filepath.Join("docs", "a.txt")]) != "precious" || string(got[filepath.Join("docs", "a_2.txt")]) != "hidden a" {
+        t.Fatalf("unexpected files after rename: %v", got)
+    }
+
+    c.OnConflict = "clobber"
+    if err := c.Unhide(encDir, outDir, "conflict-pass"); err == nil {
+        t.Fatalf("expected unknown policy to be rejected")
+    }
+}
+
+func TestCore_Hide_ShuffledWriteOrderAndEpochTimestamps(t *testing.T) {
+    tmp := t.TempDir()
+    payload := bytes.Repeat([]byte("0123456789"), 500)
+    encDir := filepath.Join(tmp, "enc")
+
+    // parts are written last to first, the masterlock must still list them in sequence
+    oldPerm, oldWrite := randomPermutationFn, writeToFileFn
+    randomPermutationFn = func(n int) ([]int, error) {
+        order := make([]int, n)
+        for i := range order {
+            order[i] = n - 1 - i
+        }
+        return order, nil
+    }
+    var written []string
+    writeToFileFn = func(path string, data []byte) error {
+        written = append(written, filepath.Base(path))
+        return oldWrite(path, data)
+    }
+    t.Cleanup(func() { randomPermutationFn, writeToFileFn = oldPerm, oldWrite })
+
+    c := New()
+    c.Jobs = 1
+    c.Timestamps = fileutils.Timestamps{Strategy: fileutils.TimestampsEpoch}
+    if err := c.HideReader(bytes.NewReader(payload), "data.bin", 4, encDir, "p"); err != nil {
+        t.Fatalf("HideReader: %v", err)
+    }
+    if len(written) != 5 {
+        t.Fatalf("expected four parts and a masterlock to be written, got %v", written)
+    }
+    for _, name := range dirNames(t, encDir) {
+        info, err := os.Stat(filepath.Join(encDir, name))
+        if err != nil {
+            t.Fatalf("stat: %v", err)
+        }
+        if !info.ModTime().Equal(time.Unix(0, 0)) {
+            t.Fatalf("expected %s at the unix epoch, got %v", name, info.ModTime())
+        }
+    }
+
+    var out bytes.Buffer
+    if err := New().UnhideTo(encDir, &out, "p"); err != nil {
+        t.Fatalf("UnhideTo: %v", err)
+    }
+    if !bytes.Equal(out.Bytes(), payload) {
+        t.Fatalf("content mismatch after shuffled write: %d bytes", out.Len())
+    }
+}
+
+func TestCore_Hide_InvalidTimestampsWritesNothing(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    c := New()
+    c.Timestamps = fileutils.Timestamps{Strategy: fileutils.TimestampsReference, Reference: filepath.Join(t.TempDir(), "missing")}
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "timestamp reference") {
+        t.Fatalf("expected reference error, got %v", err)
+    }
+    if names := dirNames(t, enc); len(names) != 0 {
+        t.Fatalf("expected nothing written, got %v", names)
+    }
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/voodooEntity/go-tachicrypt/src/zipper"
+)
+
+// the kinds of failures hiding and unhiding can run into, to be told apart with errors.Is
+var (
+	// ErrWrongPassword is returned when the masterlock can't be decrypted. A damaged masterlock
+	// or wrong keyfile can't be told apart from a wrong password, as all fail the authentication.
+	ErrWrongPassword = errors.New("wrong password or keyfile, or damaged masterlock")
+	// ErrKeyfiles is returned when unhiding with fewer or more keyfiles than the masterlock requires
+	ErrKeyfiles = errors.New("keyfiles don't match the masterlock")
+	// ErrPartCorrupt is returned when a part fails to decrypt or is too short to hold its data
+	ErrPartCorrupt = errors.New("part is corrupt")
+	// ErrUnsupportedVersion is returned for data hidden in a way this version can't unpack,
+	// e.g. with a container format added later
+	ErrUnsupportedVersion = errors.New("not supported by this version of tachicrypt")
+	// ErrUnsafePath is returned for hidden entries which would be extracted outside of the output
+	ErrUnsafePath = zipper.ErrUnsafePath
+	// ErrWeakPassword is returned when hiding with a password weaker than MinEntropy
+	ErrWeakPassword = errors.New("password too weak")
+)
+
+// ErrPartMissing is returned when a part listed in the masterlock can't be found. It unwraps
+// to the error of reading it, fs.ErrNotExist.
+type ErrPartMissing struct {
+	Index    int
+	Filename string
+	Err      error
+}
+
+func (e *ErrPartMissing) Error() string {
+	return fmt.Sprintf("part %d (%s) is missing: %v", e.Index, e.Filename, e.Err)
+}
+
+func (e *ErrPartMissing) Unwrap() error {
+	return e.Err
+}
+
+// CanceledError is returned by runs stopped because their context was canceled or timed out.
+// It unwraps to context.Canceled or context.DeadlineExceeded.
+type CanceledError struct {
+	// Stage is the stage the run was in when it got stopped
+	Stage Stage
+	Err   error
+}
+
+func (e *CanceledError) Error() string {
+	return string(e.Stage) + " interrupted: " + e.Err.Error()
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// canceled returns a CanceledError for stage if ctx is done, nil otherwise
+func canceled(ctx context.Context, stage Stage) error {
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Stage: stage, Err: err}
+	}
+	return nil
+}
+
+// interrupted returns err of a failed stage, replaced by a CanceledError if the failure was
+// caused by ctx being done
+func interrupted(ctx context.Context, stage Stage, err error) error {
+	if stop := canceled(ctx, stage); stop != nil {
+		return stop
+	}
+	return err
+}
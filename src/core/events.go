@@ -0,0 +1,85 @@
+package core
+
+// EventKind tells what an Event reports
+type EventKind string
+
+const (
+	// EventStageStarted and EventStageFinished enclose the events of a stage
+	EventStageStarted  EventKind = "stage-started"
+	EventStageFinished EventKind = "stage-finished"
+	// EventProgress counts the work of a stage done so far
+	EventProgress EventKind = "progress"
+	// EventInfo describes the settings of a run or a step it takes
+	EventInfo EventKind = "info"
+	// EventWarning is something the user should know about, which doesn't fail the run
+	EventWarning EventKind = "warning"
+)
+
+// Stage is a step of hiding or unhiding
+type Stage string
+
+const (
+	StageConfigure  Stage = "configure"
+	StagePack       Stage = "pack"
+	StageEncrypt    Stage = "encrypt"
+	StageMasterlock Stage = "masterlock"
+	StagePublish    Stage = "publish"
+	StageTimestamps Stage = "timestamps"
+	StageVerify     Stage = "verify"
+	StageShred      Stage = "shred"
+	StageDecrypt    Stage = "decrypt"
+	StageUnpack     Stage = "unpack"
+)
+
+// Event is reported to the Observer of a run as it goes
+type Event struct {
+	Kind EventKind
+	// Stage is the stage the event belongs to, empty for events concerning the whole run
+	Stage Stage
+	// Message describes the event for humans, it may be empty for stage events
+	Message string
+	// Done and Total count the units of work of progress events, e.g. parts
+	Done  int
+	Total int
+}
+
+// Observer receives the events of a run. Observe may be called from other goroutines than the
+// one running it, e.g. for the progress of parts processed concurrently.
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc lets an ordinary function be used as an Observer
+type ObserverFunc func(e Event)
+
+// Observe calls f(e)
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// emit reports e to the observer, if there is one
+func (c *Core) emit(e Event) {
+	if c.Observer != nil {
+		c.Observer.Observe(e)
+	}
+}
+
+func (c *Core) startStage(stage Stage) {
+	c.emit(Event{Kind: EventStageStarted, Stage: stage})
+}
+
+func (c *Core) finishStage(stage Stage, message string) {
+	c.emit(Event{Kind: EventStageFinished, Stage: stage, Message: message})
+}
+
+func (c *Core) info(stage Stage, message string) {
+	c.emit(Event{Kind: EventInfo, Stage: stage, Message: message})
+}
+
+func (c *Core) warn(stage Stage, message string) {
+	c.emit(Event{Kind: EventWarning, Stage: stage, Message: message})
+}
+
+func (c *Core) progress(stage Stage, message string, done, total int) {
+	c.emit(Event{Kind: EventProgress, Stage: stage, Message: message, Done: done, Total: total})
+}
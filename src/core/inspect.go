@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
+	"github.com/voodooEntity/go-tachicrypt/src/zipper"
+)
+
+// InspectOptions describe the hidden data Verify and List read
+type InspectOptions struct {
+	// Input is the directory holding the parts and masterlock
+	Input    string
+	Password PasswordProvider
+	// Observer receives the events of the run, nil discards them
+	Observer Observer
+
+	// the settings below are described at the fields of Core of the same name
+	Keyfiles         []string
+	Jobs             int
+	PasswordAttempts int
+}
+
+// InspectResult describes the hidden data read by Verify or List
+type InspectResult struct {
+	Input string
+	// Format and Compression are how the hidden data is packed
+	Format      string
+	Compression string
+	// Entries are the entries of the hidden data in the order they're stored in
+	Entries []zipper.Entry
+}
+
+// Verify decrypts the masterlock and all parts in opts.Input and reads the hidden data
+// completely, without writing anything. It fails like Unhide would on a wrong password, missing
+// or corrupt parts and damaged content, e.g. to check a backup is still intact.
+func Verify(ctx context.Context, opts InspectOptions) (*InspectResult, error) {
+	return opts.core().inspect(ctx, "verify (decrypting without writing)", opts.Input)
+}
+
+// List returns the entries of the hidden data in opts.Input without extracting them. Like Verify
+// it has to decrypt and read all of the data.
+func List(ctx context.Context, opts InspectOptions) (*InspectResult, error) {
+	return opts.core().inspect(ctx, "list (decrypting without writing)", opts.Input)
+}
+
+// core returns a Core configured by the options
+func (opts InspectOptions) core() *Core {
+	c := New()
+	c.Password = opts.Password
+	c.Observer = opts.Observer
+	c.Keyfiles = opts.Keyfiles
+	c.Jobs = opts.Jobs
+	c.PasswordAttempts = opts.PasswordAttempts
+	return c
+}
+
+// inspect decrypts the data hidden in partsDir and lists its entries
+func (c *Core) inspect(ctx context.Context, mode string, partsDir string) (*InspectResult, error) {
+	if err := canceled(ctx, StageConfigure); err != nil {
+		return nil, err
+	}
+	result := &InspectResult{Input: partsDir}
+	read := func(archive masterlock.ArchiveInfo, data []byte, _ credentials) error {
+		container, err := zipper.NewContainer(archive.Format, zipper.Options{})
+		if err != nil {
+			return err
+		}
+		entries, err := container.Entries(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Format, result.Compression, result.Entries = archive.Format, archive.Compression, entries
+		c.info(StageUnpack, strconv.Itoa(len(entries))+" entries read")
+		return nil
+	}
+	if err := c.unhide(ctx, mode, partsDir, "", read, ""); err != nil {
+		return nil, err
+	}
+	if result.Format == "" {
+		result.Format = zipper.FormatZip
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestAPI_VerifyAndList(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "docs")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(src, "sub", "b.txt"), []byte("bravo!"))
+    enc := filepath.Join(tmp, "enc")
+    if _, err := Hide(context.Background(), HideOptions{
+        Paths: []string{src}, Parts: 3, Output: enc, Password: StaticPassword("inspect-pass"),
+    }); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    opts := InspectOptions{Input: enc, Password: StaticPassword("inspect-pass")}
+    if _, err := Verify(context.Background(), opts); err != nil {
+        t.Fatalf("Verify: %v", err)
+    }
+    result, err := List(context.Background(), opts)
+    if err != nil {
+        t.Fatalf("List: %v", err)
+    }
+    sizes := map[string]int64{}
+    for _, entry := range result.Entries {
+        sizes[filepath.ToSlash(entry.Name)] = entry.Size
+    }
+    if sizes["docs/a.txt"] != 5 || sizes["docs/sub/b.txt"] != 6 {
+        t.Fatalf("unexpected entries: %+v", result.Entries)
+    }
+    // nothing gets extracted next to the parts
+    if _, err := os.Stat(filepath.Join(enc, "docs")); !os.IsNotExist(err) {
+        t.Fatalf("expected no extracted data, got %v", err)
+    }
+
+    if _, err := Verify(context.Background(), InspectOptions{Input: enc, Password: StaticPassword("wrong")}); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword, got %v", err)
+    }
+    entries, err := os.ReadDir(enc)
+    if err != nil {
+        t.Fatalf("ReadDir: %v", err)
+    }
+    for _, entry := range entries {
+        if entry.Name() != "masterlock" {
+            if err := os.Remove(filepath.Join(enc, entry.Name())); err != nil {
+                t.Fatalf("Remove: %v", err)
+            }
+            break
+        }
+    }
+    var missing *ErrPartMissing
+    if _, err := Verify(context.Background(), opts); !errors.As(err, &missing) {
+        t.Fatalf("expected ErrPartMissing, got %v", err)
+    }
+}
+
+func TestAPI_Rekey(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "a.txt")
+    writeFile(t, src, []byte("rekeyed"))
+    keyfile := filepath.Join(tmp, "new.key")
+    if err := GenerateKeyfile(keyfile, DefaultKeyfileSize); err != nil {
+        t.Fatalf("GenerateKeyfile: %v", err)
+    }
+    enc := filepath.Join(tmp, "enc")
+    if _, err := Hide(context.Background(), HideOptions{
+        Paths: []string{src}, Parts: 2, Output: enc, Password: StaticPassword("old-pass"),
+    }); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    lock := filepath.Join(enc, "masterlock")
+    before, err := os.Stat(lock)
+    if err != nil {
+        t.Fatalf("Stat: %v", err)
+    }
+
+    if err := Rekey(context.Background(), RekeyOptions{
+        Input: enc, Password: StaticPassword("old-pass"), NewPassword: StaticPassword("new-pass"), NewKeyfiles: []string{keyfile},
+    }); err != nil {
+        t.Fatalf("Rekey: %v", err)
+    }
+    after, err := os.Stat(lock)
+    if err != nil {
+        t.Fatalf("Stat: %v", err)
+    }
+    if !after.ModTime().Equal(before.ModTime()) {
+        t.Fatalf("expected the masterlock timestamps to be kept, got %v instead of %v", after.ModTime(), before.ModTime())
+    }
+
+    unhide := func(out string, password string, keyfiles ...string) error {
+        _, err := Unhide(context.Background(), UnhideOptions{
+            Input: enc, Output: filepath.Join(tmp, out), Password: StaticPassword(password), Keyfiles: keyfiles,
+        })
+        return err
+    }
+    if err := unhide("out1", "old-pass"); !errors.Is(err, ErrKeyfiles) {
+        t.Fatalf("expected ErrKeyfiles with the old credentials, got %v", err)
+    }
+    if err := unhide("out2", "new-pass", keyfile); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if b, err := os.ReadFile(filepath.Join(tmp, "out2", "a.txt")); err != nil || string(b) != "rekeyed" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+
+    if err := Rekey(context.Background(), RekeyOptions{
+        Input: enc, Password: StaticPassword("old-pass"), Keyfiles: []string{keyfile}, NewPassword: noPassword{t},
+    }); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword, got %v", err)
+    }
+}
+
+func TestGenerateKeyfile(t *testing.T) {
+    tmp := t.TempDir()
+    path := filepath.Join(tmp, "a.key")
+    if err := GenerateKeyfile(path, 32); err != nil {
+        t.Fatalf("GenerateKeyfile: %v", err)
+    }
+    info, err := os.Stat(path)
+    if err != nil {
+        t.Fatalf("Stat: %v", err)
+    }
+    if info.Size() != 32 || info.Mode().Perm() != 0o600 {
+        t.Fatalf("unexpected keyfile size %d mode %v", info.Size(), info.Mode().Perm())
+    }
+    if err := GenerateKeyfile(path, 32); err == nil {
+        t.Fatalf("expected an existing keyfile not to be overwritten")
+    }
+    if err := GenerateKeyfile(filepath.Join(tmp, "b.key"), 8); err == nil {
+        t.Fatalf("expected a too small keyfile to be refused")
+    }
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/encryptor"
+	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
+)
+
+// DefaultKeyfileSize is the number of random bytes GenerateKeyfile writes unless asked otherwise
+const DefaultKeyfileSize = 64
+
+// test hook for the random content of generated keyfiles; defaults to crypto/rand
+var keyfileRandReader io.Reader = crand.Reader
+
+// GenerateKeyfile writes size random bytes to a new file at path which only the owner can read.
+// An existing file is never overwritten, it may be the keyfile of hidden data.
+func GenerateKeyfile(path string, size int) error {
+	if size < 16 {
+		return fmt.Errorf("a keyfile needs at least 16 random bytes, got %d", size)
+	}
+	content := make([]byte, size)
+	if _, err := io.ReadFull(keyfileRandReader, content); err != nil {
+		return fmt.Errorf("error generating keyfile: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating keyfile: %w", err)
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("error writing keyfile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error writing keyfile: %w", err)
+	}
+	return nil
+}
+
+// credentials unlock the masterlock: a password, the hashes of keyfiles, or both
+type credentials struct {
+	password string
+	keyfiles [][]byte
+}
+
+// factors returns what unlocking a masterlock encrypted with the credentials requires
+func (cr credentials) factors() masterlock.Factors {
+	return masterlock.Factors{Password: cr.password != "", Keyfiles: len(cr.keyfiles)}
+}
+
+// newCredentials returns the credentials a new masterlock gets encrypted with: the password and
+// the Keyfiles, or the Keyfiles alone with KeyfileOnly set. purpose is what the password is
+// asked for.
+func (c *Core) newCredentials(prefilledPassword string, purpose Purpose) (credentials, error) {
+	if c.KeyfileOnly && len(c.Keyfiles) == 0 {
+		return credentials{}, fmt.Errorf("hiding with keyfiles only needs at least one keyfile")
+	}
+	if c.KeyfileOnly && prefilledPassword != "" {
+		return credentials{}, fmt.Errorf("a password can't be given when hiding with keyfiles only")
+	}
+	if len(c.Keyfiles) > 255 {
+		return credentials{}, fmt.Errorf("at most 255 keyfiles are supported, got %d", len(c.Keyfiles))
+	}
+	keyfiles, err := c.readKeyfiles()
+	if err != nil {
+		return credentials{}, err
+	}
+	if c.KeyfileOnly {
+		c.warn(StageConfigure, "Hiding without a password, whoever has the keyfiles can unhide the data.")
+		return credentials{keyfiles: keyfiles}, nil
+	}
+	password, err := c.password(prefilledPassword, purpose)
+	if err != nil {
+		return credentials{}, err
+	}
+	if err := c.checkStrength(password); err != nil {
+		return credentials{}, err
+	}
+	return credentials{password: password, keyfiles: keyfiles}, nil
+}
+
+// unhideKeyfiles returns the hashes of the Keyfiles, which have to be as many as the masterlock
+// requires
+func (c *Core) unhideKeyfiles(factors masterlock.Factors) ([][]byte, error) {
+	if len(c.Keyfiles) != factors.Keyfiles {
+		return nil, fmt.Errorf("the masterlock requires %d keyfiles, %d given: %w", factors.Keyfiles, len(c.Keyfiles), ErrKeyfiles)
+	}
+	if factors.Keyfiles > 0 {
+		c.info(StageMasterlock, "Unlocking with "+strconv.Itoa(factors.Keyfiles)+" keyfiles")
+	}
+	return c.readKeyfiles()
+}
+
+// readKeyfiles returns the hashes of the Keyfiles. Empty files are refused, everybody could
+// guess their content.
+func (c *Core) readKeyfiles() ([][]byte, error) {
+	hashes := make([][]byte, 0, len(c.Keyfiles))
+	for _, path := range c.Keyfiles {
+		hash, err := hashKeyfile(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func hashKeyfile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening keyfile: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error opening keyfile: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return nil, fmt.Errorf("keyfile %s has to be a non-empty file", path)
+	}
+	return encryptor.HashKeyfile(f)
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// noPassword is a PasswordProvider failing the test when asked
+type noPassword struct {
+    t *testing.T
+}
+
+func (p noPassword) Password(purpose Purpose) (string, error) {
+    p.t.Fatalf("unexpected password request for %s", purpose)
+    return "", nil
+}
+
+func TestAPI_Keyfiles(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "docs")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    first, second := filepath.Join(tmp, "usb1.key"), filepath.Join(tmp, "usb2.key")
+    writeFile(t, first, []byte("first keyfile"))
+    writeFile(t, second, []byte("second keyfile"))
+    enc := filepath.Join(tmp, "enc")
+
+    // shredding verifies the stored data, which needs the keyfiles as well
+    if _, err := Hide(context.Background(), HideOptions{
+        Paths: []string{src}, Parts: 2, Output: enc, Password: StaticPassword("api-pass"),
+        Keyfiles: []string{first, second}, ShredSource: true, ShredPasses: 1,
+    }); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    unhide := func(out string, password string, keyfiles ...string) error {
+        _, err := Unhide(context.Background(), UnhideOptions{
+            Input: enc, Output: filepath.Join(tmp, out), Password: StaticPassword(password), Keyfiles: keyfiles,
+        })
+        return err
+    }
+    if err := unhide("out1", "api-pass"); !errors.Is(err, ErrKeyfiles) {
+        t.Fatalf("expected ErrKeyfiles without the keyfiles, got %v", err)
+    }
+    if err := unhide("out2", "api-pass", first, first); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword with a wrong keyfile, got %v", err)
+    }
+    if err := unhide("out3", "wrong", second, first); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword with the wrong password, got %v", err)
+    }
+    if err := unhide("out4", "api-pass", second, first); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if got := collectFiles(t, filepath.Join(tmp, "out4", "docs")); string(got["a.txt"]) != "alpha" {
+        t.Fatalf("unexpected restored files: %v", got)
+    }
+}
+
+func TestAPI_KeyfileOnly(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "a.txt")
+    writeFile(t, src, []byte("unattended"))
+    keyfile := filepath.Join(tmp, "job.key")
+    writeFile(t, keyfile, []byte("keyfile of the job"))
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+
+    if _, err := Hide(context.Background(), HideOptions{
+        Paths: []string{src}, Parts: 2, Output: enc, Password: noPassword{t}, Keyfiles: []string{keyfile}, KeyfileOnly: true,
+    }); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    // the masterlock records that no password is needed
+    if _, err := Unhide(context.Background(), UnhideOptions{
+        Input: enc, Output: out, Password: noPassword{t}, Keyfiles: []string{keyfile},
+    }); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if b, err := os.ReadFile(filepath.Join(out, "a.txt")); err != nil || string(b) != "unattended" {
+        t.Fatalf("unexpected restored file %q: %v", b, err)
+    }
+
+    empty := filepath.Join(tmp, "empty.key")
+    writeFile(t, empty, nil)
+    for _, keyfiles := range [][]string{nil, {empty}, {filepath.Join(tmp, "missing.key")}} {
+        if _, err := Hide(context.Background(), HideOptions{
+            Paths: []string{src}, Parts: 2, Output: filepath.Join(tmp, "enc2"), Keyfiles: keyfiles, KeyfileOnly: true,
+        }); err == nil {
+            t.Fatalf("expected hiding with keyfiles %v only to be refused", keyfiles)
+        }
+    }
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// test hook for running password commands; defaults to running them through the shell
+var commandOutputFn = func(command string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	// stdin may carry the data to hide, prompts of the command go to the terminal
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// PasswordFile is a PasswordProvider reading the password from the first line of a file
+type PasswordFile string
+
+// Password reads the file
+func (p PasswordFile) Password(Purpose) (string, error) {
+	data, err := os.ReadFile(string(p))
+	if err != nil {
+		return "", err
+	}
+	return firstLine(data), nil
+}
+
+// PasswordFD is a PasswordProvider reading the password from the first line of an inherited
+// file descriptor, e.g. 3 for `tachicrypt ... 3< secret`. The descriptor is closed afterwards,
+// so it can only be asked once: unhiding makes a single attempt with it whatever
+// PasswordAttempts says, and it can't supply both passwords of a rekey.
+type PasswordFD int
+
+// Password reads the descriptor until its end
+func (p PasswordFD) Password(Purpose) (string, error) {
+	if p < 0 {
+		return "", fmt.Errorf("invalid file descriptor %d", int(p))
+	}
+	f := os.NewFile(uintptr(p), "password-fd")
+	if f == nil {
+		return "", fmt.Errorf("invalid file descriptor %d", int(p))
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("error reading file descriptor %d: %w", int(p), err)
+	}
+	return firstLine(data), nil
+}
+
+// PasswordCommand is a PasswordProvider running a command through the shell, e.g.
+// "pass show backup", and using the first line of its output as the password
+type PasswordCommand string
+
+// Password runs the command, it has to succeed
+func (p PasswordCommand) Password(Purpose) (string, error) {
+	out, err := commandOutputFn(string(p))
+	if err != nil {
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+	return firstLine(out), nil
+}
+
+// firstLine returns data up to its first line break, passwords never span lines
+func firstLine(data []byte) string {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSuffix(string(data), "\r")
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+    "errors"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestPasswordFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "secret")
+    writeFile(t, path, []byte("s3cret\r\nignored second line\n"))
+    if got, err := PasswordFile(path).Password(PurposeUnhide); err != nil || got != "s3cret" {
+        t.Fatalf("expected the first line of the file, got %q (%v)", got, err)
+    }
+    if _, err := PasswordFile(path + ".missing").Password(PurposeUnhide); err == nil {
+        t.Fatalf("expected a missing file to fail")
+    }
+}
+
+func TestPasswordCommand(t *testing.T) {
+    if got, err := PasswordCommand("printf 'from-cmd\\nrest'").Password(PurposeHide); err != nil || got != "from-cmd" {
+        t.Fatalf("expected the first line printed by the command, got %q (%v)", got, err)
+    }
+
+    old := commandOutputFn
+    commandOutputFn = func(command string) ([]byte, error) { return nil, errors.New("exit status 1") }
+    t.Cleanup(func() { commandOutputFn = old })
+    if _, err := PasswordCommand("pass show missing").Password(PurposeHide); err == nil || !strings.Contains(err.Error(), "password command failed") {
+        t.Fatalf("expected a failing command to fail, got %v", err)
+    }
+}
+
+func TestCore_Hide_EmptyPasswordFromSource(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    secret := filepath.Join(t.TempDir(), "secret")
+    writeFile(t, secret, []byte("\n"))
+    c := New()
+    c.Password = PasswordFile(secret)
+    if err := c.Hide(src, 2, enc, ""); err == nil || !strings.Contains(err.Error(), "empty password") {
+        t.Fatalf("expected an empty password to be rejected, got %v", err)
+    }
+}
@@ -0,0 +1,85 @@
+//go:build unix
+
+package core
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "syscall"
+    "testing"
+)
+
+func TestPasswordFD(t *testing.T) {
+    r, w, err := os.Pipe()
+    if err != nil {
+        t.Fatalf("pipe: %v", err)
+    }
+    defer r.Close()
+    if _, err := w.WriteString("piped\n"); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    _ = w.Close()
+    // the provider closes the descriptor, so it gets a copy of the one owned by r
+    fd, err := syscall.Dup(int(r.Fd()))
+    if err != nil {
+        t.Fatalf("dup: %v", err)
+    }
+    if got, err := PasswordFD(fd).Password(PurposeHide); err != nil || got != "piped" {
+        t.Fatalf("expected the password from the descriptor, got %q (%v)", got, err)
+    }
+    if _, err := PasswordFD(-1).Password(PurposeHide); err == nil {
+        t.Fatalf("expected a negative descriptor to fail")
+    }
+}
+
+// pipedFD returns a descriptor reading content, owned by the PasswordFD it's given to
+func pipedFD(t *testing.T, content string) int {
+    t.Helper()
+    r, w, err := os.Pipe()
+    if err != nil {
+        t.Fatalf("pipe: %v", err)
+    }
+    t.Cleanup(func() { r.Close() })
+    if _, err := w.WriteString(content); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    _ = w.Close()
+    fd, err := syscall.Dup(int(r.Fd()))
+    if err != nil {
+        t.Fatalf("dup: %v", err)
+    }
+    return fd
+}
+
+func TestPasswordFD_ReadOnce(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "a.txt")
+    if err := os.WriteFile(src, []byte("alpha"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    enc := filepath.Join(tmp, "enc")
+    if _, err := Hide(context.Background(), HideOptions{Paths: []string{src}, Parts: 2, Output: enc, Password: StaticPassword("right")}); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    // the used up descriptor isn't asked again, the wrong password is reported as such
+    var warnings int
+    observer := ObserverFunc(func(e Event) {
+        if e.Kind == EventWarning {
+            warnings++
+        }
+    })
+    _, err := Verify(context.Background(), InspectOptions{Input: enc, Password: PasswordFD(pipedFD(t, "wrong\n")), PasswordAttempts: 3, Observer: observer})
+    if !errors.Is(err, ErrWrongPassword) || warnings != 0 {
+        t.Fatalf("expected a single attempt failing with a wrong password, got %v after %d warnings", err, warnings)
+    }
+
+    fd := PasswordFD(pipedFD(t, "right\n"))
+    err = Rekey(context.Background(), RekeyOptions{Input: enc, Password: fd, NewPassword: fd})
+    if err == nil || !strings.Contains(err.Error(), "file descriptor") {
+        t.Fatalf("expected both rekey passwords from one descriptor to be refused, got %v", err)
+    }
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// workers returns the number of parts processed concurrently, jobs <= 0 meaning one per CPU
+func workers(jobs int, parts int) int {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if jobs > parts {
+		jobs = parts
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	return jobs
+}
+
+// runPool calls fn for every index of order, in that order, using up to jobs concurrent workers.
+// progress is called with the number of finished indexes after each one, never concurrently.
+// Once fn failed no further indexes are started; of all failures the one of the lowest index
+// is returned, so the reported error doesn't depend on scheduling. Once ctx is done no further
+// indexes are started either and, unless fn failed, the error of ctx is returned.
+func runPool(ctx context.Context, order []int, jobs int, fn func(i int) error, progress func(done int)) error {
+	var (
+		mu       sync.Mutex
+		done     int
+		failed   bool
+		firstIdx int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	next := make(chan int)
+	for w := 0; w < workers(jobs, len(order)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				mu.Lock()
+				stop := failed
+				mu.Unlock()
+				if stop || ctx.Err() != nil {
+					continue
+				}
+				err := fn(i)
+				mu.Lock()
+				if err != nil {
+					if !failed || i < firstIdx {
+						firstIdx, firstErr = i, err
+					}
+					failed = true
+				} else {
+					done++
+					progress(done)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, i := range order {
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			break
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "path/filepath"
+    "sync"
+    "testing"
+)
+
+func TestRunPool_ProcessesAllWithProgress(t *testing.T) {
+    order := []int{4, 0, 3, 1, 2}
+    var mu sync.Mutex
+    seen := map[int]bool{}
+    var progress []int
+    err := runPool(context.Background(), order, 3, func(i int) error {
+        mu.Lock()
+        seen[i] = true
+        mu.Unlock()
+        return nil
+    }, func(done int) { progress = append(progress, done) })
+    if err != nil {
+        t.Fatalf("runPool: %v", err)
+    }
+    if len(seen) != 5 {
+        t.Fatalf("expected all indexes to be processed, got %v", seen)
+    }
+    for n, done := range progress {
+        if done != n+1 {
+            t.Fatalf("expected progress to count up, got %v", progress)
+        }
+    }
+}
+
+func TestRunPool_ReportsLowestFailedIndex(t *testing.T) {
+    order := make([]int, 50)
+    for i := range order {
+        order[i] = len(order) - 1 - i
+    }
+    for _, jobs := range []int{1, 4, 16} {
+        err := runPool(context.Background(), order, jobs, func(i int) error {
+            if i%10 == 7 {
+                return fmt.Errorf("part %d", i)
+            }
+            return nil
+        }, func(int) {})
+        if err == nil {
+            t.Fatalf("jobs %d: expected an error", jobs)
+        }
+    }
+
+    // with a single worker nothing is started after the failure
+    started := 0
+    err := runPool(context.Background(), []int{0, 1, 2, 3}, 1, func(i int) error {
+        started++
+        if i == 1 {
+            return errors.New("boom")
+        }
+        return nil
+    }, func(int) {})
+    if err == nil || err.Error() != "boom" || started != 2 {
+        t.Fatalf("expected to stop after the failure, got %v after %d", err, started)
+    }
+}
+
+func TestRunPool_StopsOnCancel(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    started := 0
+    err := runPool(ctx, []int{0, 1, 2, 3}, 1, func(i int) error {
+        started++
+        if i == 1 {
+            cancel()
+        }
+        return nil
+    }, func(int) {})
+    if !errors.Is(err, context.Canceled) || started != 2 {
+        t.Fatalf("expected to stop after the cancel, got %v after %d", err, started)
+    }
+}
+
+func TestWorkers(t *testing.T) {
+    if got := workers(8, 3); got != 3 {
+        t.Fatalf("expected workers to be capped by the parts, got %d", got)
+    }
+    if got := workers(0, 1000); got < 1 {
+        t.Fatalf("expected at least one worker, got %d", got)
+    }
+    if got := workers(4, 0); got != 1 {
+        t.Fatalf("expected one worker without parts, got %d", got)
+    }
+}
+
+func TestCore_RoundTrip_ParallelParts(t *testing.T) {
+    tmp := t.TempDir()
+    payload := bytes.Repeat([]byte("parallel parts "), 20000)
+    enc := filepath.Join(tmp, "enc")
+    c := New()
+    c.Jobs = 8
+    if err := c.HideReader(bytes.NewReader(payload), "data", 40, enc, "p"); err != nil {
+        t.Fatalf("HideReader: %v", err)
+    }
+    var out bytes.Buffer
+    u := New()
+    u.Jobs = 8
+    if err := u.UnhideTo(enc, &out, "p"); err != nil {
+        t.Fatalf("UnhideTo: %v", err)
+    }
+    if !bytes.Equal(out.Bytes(), payload) {
+        t.Fatalf("content mismatch with parallel parts: %d bytes", out.Len())
+    }
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
+)
+
+// RekeyOptions describe the masterlock Rekey re-encrypts and what it's encrypted with afterwards
+type RekeyOptions struct {
+	// Input is the directory holding the parts and masterlock
+	Input string
+	// Password and Keyfiles unlock the masterlock as it is
+	Password PasswordProvider
+	Keyfiles []string
+	// NewPassword is asked with PurposeRekey for the password the masterlock gets encrypted
+	// with, together with the NewKeyfiles or the NewKeyfiles alone with NewKeyfileOnly set
+	NewPassword    PasswordProvider
+	NewKeyfiles    []string
+	NewKeyfileOnly bool
+	// Observer receives the events of the run, nil discards them
+	Observer Observer
+
+	// the settings below are described at the fields of Core of the same name
+	PasswordAttempts int
+	MinEntropy       float64
+}
+
+// Rekey re-encrypts the masterlock in opts.Input with a new password, keyfiles or both. The parts
+// are left alone, their keys don't change: a copy of the old masterlock still opens them with the
+// old password.
+func Rekey(ctx context.Context, opts RekeyOptions) error {
+	if err := canceled(ctx, StageConfigure); err != nil {
+		return err
+	}
+	if fd, ok := opts.Password.(PasswordFD); ok && opts.NewPassword == PasswordProvider(fd) {
+		return fmt.Errorf("the current and the new password can't both be read from file descriptor %d", int(fd))
+	}
+	c := New()
+	c.Password = opts.Password
+	c.Keyfiles = opts.Keyfiles
+	c.PasswordAttempts = opts.PasswordAttempts
+	c.Observer = opts.Observer
+	next := New()
+	next.Password = opts.NewPassword
+	next.Keyfiles = opts.NewKeyfiles
+	next.KeyfileOnly = opts.NewKeyfileOnly
+	next.MinEntropy = opts.MinEntropy
+	next.Observer = opts.Observer
+	return c.rekey(ctx, opts.Input, next)
+}
+
+// rekey re-encrypts the masterlock in partsDir with the credentials of next, keeping its timestamps
+func (c *Core) rekey(ctx context.Context, partsDir string, next *Core) error {
+	c.startStage(StageConfigure)
+	c.info(StageConfigure, "Chosen mode: rekey (re-encrypting the masterlock)")
+	c.info(StageConfigure, "Input path: "+partsDir)
+	if len(next.Keyfiles) > 0 {
+		c.info(StageConfigure, "New keyfiles: "+strconv.Itoa(len(next.Keyfiles)))
+	}
+	c.finishStage(StageConfigure, "")
+
+	if err := canceled(ctx, StageMasterlock); err != nil {
+		return err
+	}
+	c.startStage(StageMasterlock)
+	c.info(StageMasterlock, "Reading masterlock")
+	path := filepath.Join(partsDir, "masterlock")
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading encrypted master lock file: %w", err)
+	}
+	stored, err := readFileFn(path)
+	if err != nil {
+		return fmt.Errorf("error reading encrypted master lock file: %w", err)
+	}
+	_, decryptedMasterLock, err := c.openMasterlock(ctx, stored, "")
+	if err != nil {
+		return err
+	}
+	creds, err := next.newCredentials("", PurposeRekey)
+	if err != nil {
+		return err
+	}
+	if err := canceled(ctx, StageMasterlock); err != nil {
+		return err
+	}
+
+	c.info(StageMasterlock, "Encrypting masterlock")
+	encryptedMasterLock, err := encryptWithPasswordFn(decryptedMasterLock, creds.password, creds.keyfiles...)
+	if err != nil {
+		return fmt.Errorf("error encrypting master lock file: %w", err)
+	}
+	encryptedMasterLock, err = masterlock.AddHeader(creds.factors(), encryptedMasterLock)
+	if err != nil {
+		return err
+	}
+	c.info(StageMasterlock, "Writing masterlock")
+	if err := writeToFileFn(path, encryptedMasterLock); err != nil {
+		return fmt.Errorf("error writing master lock file: %w", err)
+	}
+	// the obfuscated timestamps of the masterlock stay, it mustn't stand out from the parts
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("error restoring master lock file timestamps: %w", err)
+	}
+	c.finishStage(StageMasterlock, "Masterlock re-encrypted")
+	return nil
+}
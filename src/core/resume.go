@@ -0,0 +1,173 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/voodooEntity/go-tachicrypt/src/journal"
+	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
+)
+
+// Kinds of journal records. Every journal starts with a hide or unhide header describing the
+// data of the run, followed by a part or file record for everything finished.
+const (
+	recordHide   = "hide"
+	recordUnhide = "unhide"
+	recordPart   = "part"
+	recordFile   = "file"
+)
+
+// journalRecord is a single record of the journal a run keeps next to its staging directory
+type journalRecord struct {
+	Kind string `json:"kind"`
+	// Sum is the hex SHA-256 of the packed data for headers, of the stored part or the
+	// extracted file otherwise
+	Sum string `json:"sum"`
+	// FrontPadding and Parts let a resumed hide split the data exactly like before
+	FrontPadding []byte `json:"front_padding,omitempty"`
+	Parts        int    `json:"parts,omitempty"`
+	// Part is the masterlock entry of a stored part
+	Part *masterlock.PartInfo `json:"part,omitempty"`
+	// Name is the container name of an extracted file
+	Name string `json:"name,omitempty"`
+}
+
+// runJournal is the journal of a hide or unhide run, safe for concurrent use
+type runJournal struct {
+	mu      sync.Mutex
+	j       *journal.Journal
+	st      *staging
+	records []journalRecord
+}
+
+// openJournal opens the journal of the run staged in st. The journal is encrypted with a key
+// derived from the password and keyfiles. Staged content without journal records can't be trusted and is
+// removed.
+func openJournal(st *staging, creds credentials) (*runJournal, error) {
+	j, raw, err := journal.Open(st.journalPath(), creds.password, creds.keyfiles...)
+	if err != nil {
+		return nil, err
+	}
+	rj := &runJournal{j: j, st: st}
+	for _, data := range raw {
+		var record journalRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			j.Close()
+			return nil, fmt.Errorf("error reading journal: %w", err)
+		}
+		rj.records = append(rj.records, record)
+	}
+	if len(rj.records) == 0 {
+		if err := st.reset(); err != nil {
+			j.Close()
+			return nil, fmt.Errorf("error clearing staging directory: %w", err)
+		}
+	}
+	return rj, nil
+}
+
+// header returns the header record, checking it describes the same kind of run and data. ok is
+// false for a new journal.
+func (rj *runJournal) header(kind string, sum string) (journalRecord, bool, error) {
+	if len(rj.records) == 0 {
+		return journalRecord{}, false, nil
+	}
+	header := rj.records[0]
+	if header.Kind != kind || header.Sum != sum {
+		return journalRecord{}, false, fmt.Errorf("the interrupted run in %s worked on other data, remove it to start over", rj.st.dir)
+	}
+	return header, true, nil
+}
+
+// add appends a record. Once something beyond the header is recorded the staged content is
+// worth resuming and kept on failures.
+func (rj *runJournal) add(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	if err := rj.j.Append(data); err != nil {
+		return err
+	}
+	if record.Kind == recordPart || record.Kind == recordFile {
+		rj.st.retain()
+	}
+	return nil
+}
+
+// storedParts returns the recorded parts whose staged file is still complete, by index
+func (rj *runJournal) storedParts() map[int]masterlock.PartInfo {
+	parts := map[int]masterlock.PartInfo{}
+	for _, record := range rj.records {
+		if record.Kind != recordPart || record.Part == nil {
+			continue
+		}
+		if sum, err := fileSum(rj.st.path(record.Part.Filename)); err == nil && sum == record.Sum {
+			parts[record.Part.Index] = *record.Part
+		}
+	}
+	return parts
+}
+
+// close closes the journal file
+func (rj *runJournal) close() {
+	rj.j.Close()
+}
+
+// fileJournal lets the container skip files a resumed unhide already extracted
+type fileJournal struct {
+	rj   *runJournal
+	done map[string]string
+}
+
+func newFileJournal(rj *runJournal) *fileJournal {
+	fj := &fileJournal{rj: rj, done: map[string]string{}}
+	for _, record := range rj.records {
+		if record.Kind == recordFile {
+			fj.done[record.Name] = record.Sum
+		}
+	}
+	return fj
+}
+
+// Done reports whether name was extracted to path before and is still unchanged
+func (fj *fileJournal) Done(name string, path string) bool {
+	want, ok := fj.done[name]
+	if !ok {
+		return false
+	}
+	sum, err := fileSum(path)
+	return err == nil && sum == want
+}
+
+// Add records name as completely extracted
+func (fj *fileJournal) Add(name string, sum []byte) error {
+	return fj.rj.add(journalRecord{Kind: recordFile, Name: name, Sum: hex.EncodeToString(sum)})
+}
+
+// dataSum returns the hex SHA-256 of data
+func dataSum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileSum returns the hex SHA-256 of the content of the file at path
+func fileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
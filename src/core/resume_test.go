@@ -0,0 +1,178 @@
+package core
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/journal"
+)
+
+func TestCore_Hide_FailureWithoutResumeCleansUp(t *testing.T) {
+    tmp := t.TempDir()
+    enc := filepath.Join(tmp, "enc")
+    calls := 0
+    old := writeToFileFn
+    writeToFileFn = func(path string, data []byte) error {
+        calls++
+        if calls == 4 {
+            return errors.New("mount gone")
+        }
+        return old(path, data)
+    }
+    t.Cleanup(func() { writeToFileFn = old })
+    c := New()
+    c.Jobs = 1
+    if err := c.HideReader(bytes.NewReader(bytes.Repeat([]byte("x"), 20000)), "data", 6, enc, "p"); err == nil {
+        t.Fatalf("expected the run to fail")
+    }
+    if _, err := os.Stat(enc); !os.IsNotExist(err) {
+        t.Fatalf("expected a run without resume to leave nothing behind, got %v", err)
+    }
+
+    // the next run starts over without complaining about an interrupted one
+    writeToFileFn = old
+    if err := New().HideReader(bytes.NewReader([]byte("x")), "data", 2, enc, "p"); err != nil {
+        t.Fatalf("expected a fresh run to succeed, got %v", err)
+    }
+}
+
+func TestCore_Hide_Resume(t *testing.T) {
+    tmp := t.TempDir()
+    payload := bytes.Repeat([]byte("resumable "), 2000)
+    enc := filepath.Join(tmp, "enc")
+
+    // the first run dies while storing the fourth part
+    calls := 0
+    old := writeToFileFn
+    writeToFileFn = func(path string, data []byte) error {
+        calls++
+        if calls == 4 {
+            return errors.New("mount gone")
+        }
+        return old(path, data)
+    }
+    t.Cleanup(func() { writeToFileFn = old })
+    // a single worker keeps the counted writes deterministic
+    first := New()
+    first.Jobs = 1
+    first.Resume = true
+    if err := first.HideReader(bytes.NewReader(payload), "data", 6, enc, "p"); err == nil {
+        t.Fatalf("expected the first run to fail")
+    }
+    if names := dirNames(t, filepath.Join(enc, stagingName)); len(names) != 3 {
+        t.Fatalf("expected the three stored parts to be kept, got %v", names)
+    }
+
+    // a new run neither mixes with nor discards the interrupted one
+    writeToFileFn = old
+    err := New().HideReader(bytes.NewReader(payload), "data", 6, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "interrupted run") {
+        t.Fatalf("expected the interrupted run to be reported, got %v", err)
+    }
+
+    c := New()
+    c.Resume = true
+    c.Jobs = 1
+    if err := c.HideReader(bytes.NewReader(payload), "data", 6, enc, "wrong"); !errors.Is(err, journal.ErrWrongPassword) {
+        t.Fatalf("expected a wrong password to be refused, got %v", err)
+    }
+    err = c.HideReader(bytes.NewReader([]byte("other data")), "data", 6, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "other data") {
+        t.Fatalf("expected changed input to be refused, got %v", err)
+    }
+
+    calls = 0
+    writeToFileFn = func(path string, data []byte) error {
+        calls++
+        return old(path, data)
+    }
+    if err := c.HideReader(bytes.NewReader(payload), "data", 6, enc, "p"); err != nil {
+        t.Fatalf("resumed HideReader: %v", err)
+    }
+    if calls != 4 {
+        t.Fatalf("expected only the three missing parts and the masterlock to be written, got %d writes", calls)
+    }
+    if names := dirNames(t, enc); len(names) != 7 {
+        t.Fatalf("expected six parts and a masterlock without staging leftovers, got %v", names)
+    }
+
+    var out bytes.Buffer
+    if err := New().UnhideTo(enc, &out, "p"); err != nil {
+        t.Fatalf("UnhideTo: %v", err)
+    }
+    if !bytes.Equal(out.Bytes(), payload) {
+        t.Fatalf("content mismatch after resume: %d bytes", out.Len())
+    }
+}
+
+func TestCore_Unhide_Resume(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "tree")
+    writeFile(t, filepath.Join(srcDir, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(srcDir, "b.txt"), []byte("beta"))
+    writeFile(t, filepath.Join(srcDir, "sub", "c.txt"), []byte("gamma"))
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+    if err := New().Hide(srcDir, 3, enc, "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    // the first run dies while publishing, after everything got extracted
+    old := moveFn
+    moveFn = func(from, to string) error { return errors.New("mount gone") }
+    t.Cleanup(func() { moveFn = old })
+    first := New()
+    first.Resume = true
+    if err := first.Unhide(enc, out, "p"); err == nil {
+        t.Fatalf("expected the first run to fail")
+    }
+    moveFn = old
+    staged := filepath.Join(out, stagingName, "tree")
+    kept, err := os.Stat(filepath.Join(staged, "a.txt"))
+    if err != nil {
+        t.Fatalf("expected extracted files to be kept: %v", err)
+    }
+    // a file damaged since gets extracted again
+    if err := os.WriteFile(filepath.Join(staged, "b.txt"), []byte("torn"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+
+    if err := New().Unhide(enc, out, "p"); err == nil || !strings.Contains(err.Error(), "interrupted run") {
+        t.Fatalf("expected the interrupted run to be reported, got %v", err)
+    }
+    c := New()
+    c.Resume = true
+    if err := c.Unhide(enc, out, "p"); err != nil {
+        t.Fatalf("resumed Unhide: %v", err)
+    }
+    got := collectFiles(t, filepath.Join(out, "tree"))
+    if string(got["a.txt"]) != "alpha" || string(got["b.txt"]) != "beta" || string(got["sub/c.txt"]) != "gamma" {
+        t.Fatalf("unexpected content after resume: %v", got)
+    }
+    published, err := os.Stat(filepath.Join(out, "tree", "a.txt"))
+    if err != nil || !os.SameFile(kept, published) {
+        t.Fatalf("expected the verified file to be kept instead of extracted again")
+    }
+    if names := dirNames(t, out); len(names) != 1 {
+        t.Fatalf("expected no staging leftovers, got %v", names)
+    }
+}
+
+func TestCore_ResumeWithoutInterruptedRun(t *testing.T) {
+    src, enc, out := mkInputEnv(t)
+    c := New()
+    c.Resume = true
+    if err := c.Hide(src, 2, enc, "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+    if err := c.Unhide(enc, out, "p"); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if err := c.UnhideTo(enc, &bytes.Buffer{}, "p"); err == nil {
+        t.Fatalf("expected resuming a stream to be refused")
+    }
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/voodooEntity/go-tachicrypt/src/encryptor"
+	"github.com/voodooEntity/go-tachicrypt/src/fileutils"
+	"github.com/voodooEntity/go-tachicrypt/src/masterlock"
+	"github.com/voodooEntity/go-tachicrypt/src/utils"
+)
+
+// test hook for wiping source files; defaults to the real implementation
+var shredFn = fileutils.Shred
+
+// hiddenSource is a file system object read from disk while packing, with the info it got
+// packed with
+type hiddenSource struct {
+	path string
+	info os.FileInfo
+}
+
+// shredFailure is a source path which couldn't be wiped
+type shredFailure struct {
+	path string
+	err  error
+}
+
+// verify reads the stored parts and masterlock back from outputDir, decrypts them and compares
+// the result with the container data which was hidden
+func verify(outputDir string, creds credentials, expected []byte) error {
+	stored, err := readFileFn(filepath.Join(outputDir, "masterlock"))
+	if err != nil {
+		return fmt.Errorf("error reading masterlock: %w", err)
+	}
+	_, encryptedMasterLock, err := masterlock.SplitHeader(stored)
+	if err != nil {
+		return fmt.Errorf("error reading masterlock: %w", err)
+	}
+	decryptedMasterLock, err := decryptWithPasswordFn(encryptedMasterLock, creds.password, creds.keyfiles...)
+	if errors.Is(err, encryptor.ErrDecrypt) {
+		err = ErrWrongPassword
+	}
+	if err != nil {
+		return fmt.Errorf("error decrypting masterlock: %w", err)
+	}
+	var mlock masterlock.MasterLock
+	if err := jsonUnmarshalFn(decryptedMasterLock, &mlock); err != nil {
+		return fmt.Errorf("error unmarshaling masterlock: %w", err)
+	}
+
+	var allParts [][]byte
+	for i, partInfo := range mlock.Parts {
+		decryptedPart, err := decryptPart(outputDir, i, partInfo)
+		if err != nil {
+			return err
+		}
+		allParts = append(allParts, decryptedPart)
+	}
+	data := utils.ConcatByteSlices(allParts)
+	if mlock.FrontPadding+mlock.BackPadding > len(data) || !bytes.Equal(data[mlock.FrontPadding:len(data)-mlock.BackPadding], expected) {
+		return fmt.Errorf("stored data doesn't match the hidden data")
+	}
+	return nil
+}
+
+// checkOutsideInputs makes sure outputDir is neither one of dataPaths nor inside one of them,
+// wiping the source would wipe what just got stored there as well
+func checkOutsideInputs(outputDir string, dataPaths []string) error {
+	out, err := resolvePath(outputDir)
+	if err != nil {
+		return err
+	}
+	for _, dataPath := range dataPaths {
+		// the input itself is never followed, only the directories leading to it
+		abs, err := filepath.Abs(dataPath)
+		if err != nil {
+			return err
+		}
+		parent, err := resolvePath(filepath.Dir(abs))
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Join(parent, filepath.Base(abs)), out)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("shredding the source needs an output directory outside of the input, %s is inside %s", outputDir, dataPath)
+		}
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path with symlinks resolved as far as it exists
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// unchanged reports whether current is still the file system object hidden, same file,
+// type, size and modification time
+func (h hiddenSource) unchanged(current os.FileInfo) bool {
+	if !os.SameFile(h.info, current) || h.info.Mode().Type() != current.Mode().Type() {
+		return false
+	}
+	// wiping their content changes the modification time of directories, they're only
+	// removed once empty anyway
+	return current.IsDir() || current.Size() == h.info.Size() && current.ModTime().Equal(h.info.ModTime())
+}
+
+// linksLeft counts the hidden files which are hardlinks of info
+func linksLeft(hidden []hiddenSource, info os.FileInfo) uint64 {
+	var n uint64
+	for _, h := range hidden {
+		if h.info.Mode().IsRegular() && os.SameFile(h.info, info) {
+			n++
+		}
+	}
+	return n
+}
+
+// shredSources wipes the hidden files, parents before their children. Only those still
+// unchanged since they got packed are wiped, others might hold data which isn't part of the
+// archive and are left alone. Files with further hardlinks outside of the hidden ones are only
+// unlinked and reported, their content stays readable through those links. Directories are
+// removed once empty, those still holding excluded files are kept. Once ctx is done no further
+// files are wiped and a CanceledError is returned.
+func (c *Core) shredSources(ctx context.Context, hidden []hiddenSource) ([]shredFailure, error) {
+	var failures []shredFailure
+	var dirs []string
+	for i, source := range hidden {
+		if err := canceled(ctx, StageShred); err != nil {
+			return failures, fmt.Errorf("data hidden, source partly wiped: %w", err)
+		}
+		c.progress(StageShred, "Wiping source files", i+1, len(hidden))
+		info, err := os.Lstat(source.path)
+		if err != nil {
+			failures = append(failures, shredFailure{path: source.path, err: err})
+			continue
+		}
+		if !source.unchanged(info) {
+			failures = append(failures, shredFailure{path: source.path, err: fmt.Errorf("changed after it got hidden")})
+			continue
+		}
+		if info.IsDir() {
+			dirs = append(dirs, source.path)
+			continue
+		}
+		err = shredFn(source.path, c.ShredPasses)
+		if errors.Is(err, fileutils.ErrHardlinked) && linksLeft(hidden[i:], info) >= fileutils.LinkCount(info) {
+			// every link got hidden, the content is wiped once the last of them is reached
+			err = nil
+		}
+		if err != nil {
+			failures = append(failures, shredFailure{path: source.path, err: err})
+		}
+	}
+
+	// deepest first, so parents are empty by the time they are removed
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+	return failures, nil
+}
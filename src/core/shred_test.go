@@ -0,0 +1,200 @@
+package core
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/fileutils"
+)
+
+// mkShredSource creates a small tree to hide and wipe, with a log file that gets excluded
+func mkShredSource(t *testing.T) (string, string) {
+    t.Helper()
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "src")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(src, "sub", "b.txt"), []byte("beta"))
+    writeFile(t, filepath.Join(src, "logs", "debug.log"), []byte("excluded"))
+    return src, filepath.Join(tmp, "enc")
+}
+
+func TestCore_Hide_ShredSource(t *testing.T) {
+    src, enc := mkShredSource(t)
+    c := New()
+    c.ShredSource = true
+    c.ShredPasses = 1
+    c.Excludes = []string{"*.log"}
+    if err := c.Hide(src, 3, enc, "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    // everything hidden is gone, the excluded file and the directories leading to it are kept
+    for _, wiped := range []string{"a.txt", "sub"} {
+        if _, err := os.Lstat(filepath.Join(src, wiped)); !os.IsNotExist(err) {
+            t.Fatalf("expected %s to be wiped, got %v", wiped, err)
+        }
+    }
+    if names := dirNames(t, src); strings.Join(names, ",") != "logs" {
+        t.Fatalf("expected only the excluded logs to remain, got %v", names)
+    }
+
+    out := filepath.Join(t.TempDir(), "out")
+    if err := New().Unhide(enc, out, "p"); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    got := collectFiles(t, filepath.Join(out, "src"))
+    if string(got["a.txt"]) != "alpha" || string(got["sub/b.txt"]) != "beta" {
+        t.Fatalf("unexpected restored content: %v", got)
+    }
+}
+
+func TestCore_Hide_ShredSourceKeptWhenVerifyFails(t *testing.T) {
+    src, enc := mkShredSource(t)
+    old := decryptWithRandomKeyFn
+    decryptWithRandomKeyFn = func([]byte, string) ([]byte, error) { return []byte("garbage"), nil }
+    t.Cleanup(func() { decryptWithRandomKeyFn = old })
+
+    c := New()
+    c.ShredSource = true
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "verification failed") {
+        t.Fatalf("expected verification failure, got %v", err)
+    }
+    if got := collectFiles(t, src); len(got) != 3 {
+        t.Fatalf("expected the source to be kept, got %v", got)
+    }
+}
+
+func TestCore_Hide_ShredSourceReportsFailures(t *testing.T) {
+    src, enc := mkShredSource(t)
+    old := shredFn
+    shredFn = func(path string, passes int) error {
+        if filepath.Base(path) == "b.txt" {
+            return errors.New("device busy")
+        }
+        return old(path, passes)
+    }
+    t.Cleanup(func() { shredFn = old })
+
+    c := New()
+    c.ShredSource = true
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "1 source paths could not be wiped") || !strings.Contains(err.Error(), "b.txt") {
+        t.Fatalf("expected the failed file to be reported, got %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(src, "sub", "b.txt")); err != nil {
+        t.Fatalf("expected b.txt to be kept: %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(src, "a.txt")); !os.IsNotExist(err) {
+        t.Fatalf("expected a.txt to be wiped, got %v", err)
+    }
+}
+
+func TestCore_Hide_ShredSourceKeepsFilesChangedAfterPacking(t *testing.T) {
+    src, enc := mkShredSource(t)
+    // past timestamps on the stored files must not make any of them look like a source
+    c := New()
+    c.ShredSource = true
+    c.ShredPasses = 1
+    c.Timestamps = fileutils.Timestamps{Strategy: fileutils.TimestampsEpoch}
+    old := obfuscateFileTimestampsFn
+    obfuscateFileTimestampsFn = func(paths []string, opts fileutils.Timestamps) error {
+        // same size, the modification time still tells
+        if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("ALPHA"), 0o644); err != nil {
+            return err
+        }
+        return old(paths, opts)
+    }
+    t.Cleanup(func() { obfuscateFileTimestampsFn = old })
+
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "a.txt") {
+        t.Fatalf("expected the changed file to be reported, got %v", err)
+    }
+    if got, _ := os.ReadFile(filepath.Join(src, "a.txt")); string(got) != "ALPHA" {
+        t.Fatalf("expected the changed file to be kept, got %q", got)
+    }
+    if _, err := os.Stat(filepath.Join(src, "sub", "b.txt")); !os.IsNotExist(err) {
+        t.Fatalf("expected b.txt to be wiped, got %v", err)
+    }
+}
+
+func TestCore_Hide_ShredSourceHardlinks(t *testing.T) {
+    src, enc := mkShredSource(t)
+    keep := filepath.Join(filepath.Dir(src), "keep.txt")
+    writeFile(t, keep, []byte("precious"))
+    if err := os.Link(keep, filepath.Join(src, "link.txt")); err != nil {
+        t.Skipf("hardlinks not supported: %v", err)
+    }
+    if err := os.Link(filepath.Join(src, "a.txt"), filepath.Join(src, "sub", "a-again.txt")); err != nil {
+        t.Fatalf("link: %v", err)
+    }
+    if info, _ := os.Lstat(keep); fileutils.LinkCount(info) != 2 {
+        t.Skip("link count not available on this platform")
+    }
+
+    c := New()
+    c.ShredSource = true
+    c.ShredPasses = 1
+    c.Excludes = []string{"*.log"}
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "1 source paths could not be wiped") || !strings.Contains(err.Error(), "link.txt") {
+        t.Fatalf("expected only the link to a file outside to be reported, got %v", err)
+    }
+    // the link got removed, the file outside of the input is untouched
+    if _, err := os.Lstat(filepath.Join(src, "link.txt")); !os.IsNotExist(err) {
+        t.Fatalf("expected link.txt to be unlinked, got %v", err)
+    }
+    if got, _ := os.ReadFile(keep); string(got) != "precious" {
+        t.Fatalf("file outside of the input was modified: %q", got)
+    }
+    // both links of a.txt were hidden, so it got wiped completely
+    if names := dirNames(t, src); strings.Join(names, ",") != "logs" {
+        t.Fatalf("expected only the excluded logs to remain, got %v", names)
+    }
+}
+
+func TestCore_Hide_ShredSourceRejectsOutputInsideInput(t *testing.T) {
+    src, _ := mkShredSource(t)
+    c := New()
+    c.ShredSource = true
+    for _, out := range []string{src, filepath.Join(src, "out"), filepath.Join(src, "sub", "out")} {
+        err := c.HidePaths([]string{filepath.Join(src, "..", "src")}, 2, out, "p")
+        if err == nil || !strings.Contains(err.Error(), "outside of the input") {
+            t.Fatalf("expected %s to be rejected, got %v", out, err)
+        }
+    }
+    if got := collectFiles(t, src); len(got) != 3 {
+        t.Fatalf("expected the source to be untouched, got %v", got)
+    }
+
+    // a sibling sharing the name as prefix is fine
+    if err := c.Hide(src, 2, src+"-enc", "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+}
+
+func TestCore_Hide_ShredSourceRejectedCombinations(t *testing.T) {
+    src, enc := mkShredSource(t)
+    c := New()
+    c.ShredSource = true
+    c.FollowSymlinks = true
+    if err := c.Hide(src, 2, enc, "p"); err == nil || !strings.Contains(err.Error(), "symlinks") {
+        t.Fatalf("expected follow-symlinks to be rejected, got %v", err)
+    }
+    c.FollowSymlinks = false
+    c.ShredPasses = 0
+    if err := c.Hide(src, 2, enc, "p"); err == nil || !strings.Contains(err.Error(), "pass") {
+        t.Fatalf("expected zero passes to be rejected, got %v", err)
+    }
+    c.ShredPasses = 3
+    if err := c.HideReader(strings.NewReader("x"), "x", 2, enc, "p"); err == nil || !strings.Contains(err.Error(), "stream") {
+        t.Fatalf("expected streams to be rejected, got %v", err)
+    }
+    if names := dirNames(t, enc); len(names) != 0 {
+        t.Fatalf("expected nothing written, got %v", names)
+    }
+}
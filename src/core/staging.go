@@ -0,0 +1,211 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/voodooEntity/go-tachicrypt/src/fileutils"
+)
+
+// stagingName is the hidden directory runs write into before publishing their results. The
+// journal of a run is kept next to it, so an interrupted run can be resumed.
+const stagingName = ".tachicrypt-staging"
+
+// test hook for publishing; defaults to the real implementation
+var moveFn = fileutils.Move
+
+// staging is a hidden directory inside the target directory everything gets written to first.
+// Only publish moves its content into place, so failed or interrupted runs leave the target
+// as it was, apart from the staging directory of runs which retained their work for resuming.
+type staging struct {
+	dir    string
+	target string
+	// createdTarget is set when the target didn't exist before, it's removed again on rollback
+	createdTarget bool
+	// mu keeps a rollback from interfering with publishing
+	mu       sync.Mutex
+	finished bool
+	// keep makes rollbacks leave the staged content in place for resuming, only runs which
+	// are resumable get to keep it
+	keep      bool
+	resumable bool
+	// observer is told about kept work
+	observer Observer
+}
+
+// newStaging creates the staging directory inside target, creating target if needed. Runs
+// roll it back by closing it, also when their context got canceled. A staging directory left
+// by an interrupted run is only reused when resuming.
+func newStaging(target string, resume bool, observer Observer) (*staging, error) {
+	s := &staging{target: target, dir: filepath.Join(target, stagingName), observer: observer, resumable: resume}
+	_, err := os.Lstat(s.dir)
+	switch {
+	case err == nil && !resume:
+		return nil, interruptedRunError(s.dir)
+	case err == nil:
+		// the work of the interrupted run is never thrown away, e.g. by a mistyped password
+		s.keep = true
+		// the interrupted run may have applied read-only modes already
+		makeWritable(s.dir)
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("error creating staging directory: %w", err)
+	default:
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, fmt.Errorf("error creating output directory: %w", err)
+			}
+			s.createdTarget = true
+		}
+		if err := os.Mkdir(s.dir, 0700); err != nil {
+			s.removeTarget()
+			return nil, fmt.Errorf("error creating staging directory: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// path returns the staged location of name
+func (s *staging) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// journalPath returns where the journal of the run is kept
+func (s *staging) journalPath() string {
+	return s.dir + ".journal"
+}
+
+// retain makes rollbacks keep the staged content once it's worth resuming. Runs which weren't
+// started resumable clean up everything instead, like any failed run.
+func (s *staging) retain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resumable {
+		s.keep = true
+	}
+}
+
+// reset removes everything staged, e.g. leftovers of an interrupted run without a journal
+func (s *staging) reset() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(s.path(entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish moves the staged content into the target, the entry called last after all others.
+// Directories are merged into existing ones, other existing paths are replaced.
+func (s *staging) publish(last string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return fmt.Errorf("staging directory %s already cleaned up", s.dir)
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == last {
+			continue
+		}
+		if err := moveFn(s.path(entry.Name()), filepath.Join(s.target, entry.Name())); err != nil {
+			return fmt.Errorf("error publishing %s: %w", entry.Name(), err)
+		}
+	}
+	if last != "" {
+		if err := moveFn(s.path(last), filepath.Join(s.target, last)); err != nil {
+			return fmt.Errorf("error publishing %s: %w", last, err)
+		}
+	}
+	if err := fileutils.SyncDir(s.target); err != nil {
+		return fmt.Errorf("error syncing output directory: %w", err)
+	}
+	s.finished = true
+	if err := os.RemoveAll(s.dir); err != nil {
+		return err
+	}
+	if err := os.Remove(s.journalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// existing returns the staged top-level names which already exist in the target
+func (s *staging) existing() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, err := os.Lstat(filepath.Join(s.target, entry.Name())); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// rollback removes everything staged, and the target if this run created it. Retained
+// content is kept for resuming instead, rollback reports whether that happened.
+func (s *staging) rollback() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return false
+	}
+	s.finished = true
+	if s.keep {
+		return true
+	}
+	// staged directories may be read-only, make them removable first
+	makeWritable(s.dir)
+	_ = os.RemoveAll(s.dir)
+	_ = os.Remove(s.journalPath())
+	s.removeTarget()
+	return false
+}
+
+// close rolls back unless published
+func (s *staging) close() {
+	if s.rollback() {
+		s.warn("Finished work kept in " + s.dir + ", continue with resume")
+	}
+}
+
+// warn reports message to the observer, if there is one
+func (s *staging) warn(message string) {
+	if s.observer != nil {
+		s.observer.Observe(Event{Kind: EventWarning, Message: message})
+	}
+}
+
+// removeTarget removes the target directory if this run created it and nothing else got into it
+func (s *staging) removeTarget() {
+	if s.createdTarget {
+		_ = os.Remove(s.target)
+	}
+}
+
+// makeWritable makes dir and the directories below it writable for the owner
+func makeWritable(dir string) {
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			_ = os.Chmod(path, 0700)
+		}
+		return nil
+	})
+}
+
+// interruptedRunError tells how to deal with the staging directory of an interrupted run
+func interruptedRunError(dir string) error {
+	return fmt.Errorf("%s holds the work of an interrupted run, continue it with resume or remove it to start over", dir)
+}
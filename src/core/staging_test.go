@@ -0,0 +1,246 @@
+package core
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// dirNames returns the names inside dir, or nil if it doesn't exist
+func dirNames(t *testing.T, dir string) []string {
+    t.Helper()
+    entries, err := os.ReadDir(dir)
+    if os.IsNotExist(err) {
+        return nil
+    }
+    if err != nil {
+        t.Fatalf("read dir: %v", err)
+    }
+    var names []string
+    for _, e := range entries {
+        names = append(names, e.Name())
+    }
+    return names
+}
+
+// publishedNames returns the names inside dir without the staging directory and journal of
+// an interrupted run
+func publishedNames(t *testing.T, dir string) []string {
+    t.Helper()
+    var names []string
+    for _, name := range dirNames(t, dir) {
+        if !strings.HasPrefix(name, stagingName) {
+            names = append(names, name)
+        }
+    }
+    return names
+}
+
+func TestCore_Hide_FailureLeavesNoParts(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    calls := 0
+    old := writeToFileFn
+    writeToFileFn = func(path string, data []byte) error {
+        calls++
+        if calls == 3 {
+            return errors.New("disk full")
+        }
+        return old(path, data)
+    }
+    t.Cleanup(func() { writeToFileFn = old })
+
+    c := New()
+    c.Jobs = 1
+    if err := c.Hide(src, 5, enc, "p"); err == nil {
+        t.Fatalf("expected hide to fail at the third part")
+    }
+    if names := publishedNames(t, enc); len(names) != 0 {
+        t.Fatalf("expected no published parts, got %v", names)
+    }
+}
+
+func TestCore_Hide_PasswordFailureLeavesNoParts(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    old := encryptWithPasswordFn
+    encryptWithPasswordFn = func(data []byte, password string, keyfiles ...[]byte) ([]byte, error) { return nil, errors.New("kdf fail") }
+    t.Cleanup(func() { encryptWithPasswordFn = old })
+
+    if err := New().Hide(src, 3, enc, "p"); err == nil {
+        t.Fatalf("expected hide to fail")
+    }
+    if names := publishedNames(t, enc); len(names) != 0 {
+        t.Fatalf("expected no published parts, got %v", names)
+    }
+}
+
+func TestCore_Hide_CreatesAndRemovesMissingOutputDir(t *testing.T) {
+    src, _, _ := mkInputEnv(t)
+    missing := filepath.Join(t.TempDir(), "new", "enc")
+    // failing before a part got stored leaves nothing worth resuming
+    old := encryptWithRandomKeyFn
+    encryptWithRandomKeyFn = func([]byte) ([]byte, string, error) { return nil, "", errors.New("enc fail") }
+    t.Cleanup(func() { encryptWithRandomKeyFn = old })
+
+    if err := New().Hide(src, 2, missing, "p"); err == nil {
+        t.Fatalf("expected hide to fail")
+    }
+    if _, err := os.Stat(missing); !os.IsNotExist(err) {
+        t.Fatalf("expected the created output directory to be removed, got %v", err)
+    }
+
+    encryptWithRandomKeyFn = old
+    if err := New().Hide(src, 2, missing, "p"); err != nil {
+        t.Fatalf("Hide into missing dir: %v", err)
+    }
+    if names := dirNames(t, missing); len(names) != 3 {
+        t.Fatalf("expected two parts and a masterlock, got %v", names)
+    }
+}
+
+func TestCore_Hide_RefusesExistingMasterlock(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    lock := filepath.Join(enc, "masterlock")
+    if err := os.WriteFile(lock, []byte("older run"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    // even when forced, an older masterlock is never replaced
+    c := New()
+    c.Force = true
+    err := c.Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "masterlock") {
+        t.Fatalf("expected existing masterlock to be refused, got %v", err)
+    }
+    if got, _ := os.ReadFile(lock); string(got) != "older run" {
+        t.Fatalf("existing masterlock replaced: %q", got)
+    }
+    if names := publishedNames(t, enc); len(names) != 1 {
+        t.Fatalf("expected no parts to be published, got %v", names)
+    }
+}
+
+func TestCore_Hide_RefusesNonEmptyOutputDir(t *testing.T) {
+    src, enc, _ := mkInputEnv(t)
+    foreign := filepath.Join(enc, "notes.txt")
+    if err := os.WriteFile(foreign, []byte("mine"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    stamp := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+    if err := os.Chtimes(foreign, stamp, stamp); err != nil {
+        t.Fatalf("chtimes: %v", err)
+    }
+
+    err := New().Hide(src, 2, enc, "p")
+    if err == nil || !strings.Contains(err.Error(), "not empty") {
+        t.Fatalf("expected non-empty output directory to be refused, got %v", err)
+    }
+    if names := dirNames(t, enc); len(names) != 1 {
+        t.Fatalf("expected nothing to be written, got %v", names)
+    }
+
+    c := New()
+    c.Force = true
+    if err := c.Hide(src, 2, enc, "p"); err != nil {
+        t.Fatalf("forced Hide: %v", err)
+    }
+    if names := dirNames(t, enc); len(names) != 4 {
+        t.Fatalf("expected two parts and a masterlock next to the existing file, got %v", names)
+    }
+    info, err := os.Stat(foreign)
+    if err != nil {
+        t.Fatalf("stat: %v", err)
+    }
+    if !info.ModTime().Equal(stamp) {
+        t.Fatalf("timestamp of existing file changed: %v", info.ModTime())
+    }
+}
+
+func TestCore_Unhide_StagesExtraction(t *testing.T) {
+    tmp := t.TempDir()
+    srcDir := filepath.Join(tmp, "tree")
+    writeFile(t, filepath.Join(srcDir, "a.txt"), []byte("a"))
+    enc := filepath.Join(tmp, "enc")
+    out := filepath.Join(tmp, "out")
+    if err := New().Hide(srcDir, 2, enc, "p"); err != nil {
+        t.Fatalf("Hide: %v", err)
+    }
+
+    // a failing unhide leaves no trace, not even the output directory it had to create
+    if err := New().Unhide(enc, out, "wrong"); err == nil {
+        t.Fatalf("expected wrong password to fail")
+    }
+    if _, err := os.Stat(out); !os.IsNotExist(err) {
+        t.Fatalf("expected no output directory, got %v", err)
+    }
+
+    if err := New().Unhide(enc, out, "p"); err != nil {
+        t.Fatalf("Unhide: %v", err)
+    }
+    if names := dirNames(t, out); len(names) != 1 || names[0] != "tree" {
+        t.Fatalf("expected only the published tree, got %v", names)
+    }
+}
+
+func TestStaging_CloseRollsBack(t *testing.T) {
+    target := filepath.Join(t.TempDir(), "out")
+    st, err := newStaging(target, false, nil)
+    if err != nil {
+        t.Fatalf("newStaging: %v", err)
+    }
+    if err := os.WriteFile(st.path("part"), []byte("x"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    st.close()
+    if _, err := os.Stat(target); !os.IsNotExist(err) {
+        t.Fatalf("expected staged files and created target to be removed, got %v", err)
+    }
+    if err := st.publish(""); err == nil {
+        t.Fatalf("expected publishing after a rollback to fail")
+    }
+}
+
+func TestStaging_RetainNeedsResume(t *testing.T) {
+    target := filepath.Join(t.TempDir(), "out")
+    st, err := newStaging(target, false, nil)
+    if err != nil {
+        t.Fatalf("newStaging: %v", err)
+    }
+    if err := os.WriteFile(st.path("part"), []byte("x"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    st.retain()
+    st.close()
+    if _, err := os.Stat(target); !os.IsNotExist(err) {
+        t.Fatalf("expected a run without resume to clean up, got %v", err)
+    }
+}
+
+func TestStaging_CloseKeepsRetainedWork(t *testing.T) {
+    target := filepath.Join(t.TempDir(), "out")
+    st, err := newStaging(target, true, nil)
+    if err != nil {
+        t.Fatalf("newStaging: %v", err)
+    }
+    if err := os.WriteFile(st.path("part"), []byte("x"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    st.retain()
+    st.close()
+    if _, err := os.Stat(st.path("part")); err != nil {
+        t.Fatalf("expected retained work to be kept: %v", err)
+    }
+
+    if _, err := newStaging(target, false, nil); err == nil || !strings.Contains(err.Error(), "interrupted run") {
+        t.Fatalf("expected the kept staging directory to block a fresh run, got %v", err)
+    }
+    resumed, err := newStaging(target, true, nil)
+    if err != nil {
+        t.Fatalf("resume staging: %v", err)
+    }
+    defer resumed.close()
+    if names := dirNames(t, resumed.dir); len(names) != 1 || names[0] != "part" {
+        t.Fatalf("expected the kept work to be reused, got %v", names)
+    }
+}
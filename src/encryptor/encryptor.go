@@ -1,14 +1,18 @@
 package encryptor
 
 import (
+    "bytes"
     "crypto/aes"
     "crypto/cipher"
     "crypto/rand"
     "crypto/sha256"
     "encoding/base64"
+    "encoding/binary"
     "errors"
     "fmt"
+    "hash"
     "io"
+    "sort"
 )
 
 // test hooks / indirection for easier unit testing of error paths
@@ -18,10 +22,47 @@ var (
     cipherNewGCM   = cipher.NewGCM
 )
 
-// deriveKey converts a password string into a 32-byte key using SHA-256
-func deriveKey(password string) []byte {
-    hash := sha256.Sum256([]byte(password))
-    return hash[:]
+// ErrDecrypt is returned when ciphertext can't be decrypted with the given password or key,
+// because either is wrong or the ciphertext got damaged
+var ErrDecrypt = errors.New("error decrypting ciphertext")
+
+// keyfileContext separates keys mixing in keyfiles from keys derived from a password alone
+const keyfileContext = "tachicrypt-keyfiles-v1"
+
+// deriveKey converts a password string into a 32-byte key using SHA-256. The hashes of keyfiles
+// (see HashKeyfile) are mixed in when given, in any order, so the key needs all of them together
+// with the password. Without keyfiles the key is the plain hash of the password, as it always was.
+func deriveKey(password string, keyfiles ...[]byte) []byte {
+    if len(keyfiles) == 0 {
+        hash := sha256.Sum256([]byte(password))
+        return hash[:]
+    }
+    sorted := append([][]byte(nil), keyfiles...)
+    sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+    h := sha256.New()
+    writeField(h, []byte(keyfileContext))
+    writeField(h, []byte(password))
+    for _, keyfile := range sorted {
+        writeField(h, keyfile)
+    }
+    return h.Sum(nil)
+}
+
+// writeField writes data prefixed by its length, so neighbouring fields can't be shifted
+// into each other
+func writeField(h hash.Hash, data []byte) {
+    binary.Write(h, binary.BigEndian, uint64(len(data)))
+    h.Write(data)
+}
+
+// HashKeyfile returns the SHA-256 hash of the content of a keyfile, which is what gets mixed
+// into keys. Any file works as a keyfile, e.g. 64 random bytes on a USB stick.
+func HashKeyfile(r io.Reader) ([]byte, error) {
+    h := sha256.New()
+    if _, err := io.Copy(h, r); err != nil {
+        return nil, fmt.Errorf("error reading keyfile: %w", err)
+    }
+    return h.Sum(nil), nil
 }
 
 func EncryptWithRandomKey(data []byte) ([]byte, string, error) {
@@ -55,9 +96,11 @@ func DecryptWithRandomKey(ciphertextBytes []byte, encodedKey string) ([]byte, er
 	return DecryptWithPassword(ciphertextBytes, string(keyBytes))
 }
 
-func DecryptWithPassword(ciphertextBytes []byte, password string) ([]byte, error) {
+// DecryptWithPassword decrypts ciphertext encrypted by EncryptWithPassword with the same
+// password and keyfile hashes
+func DecryptWithPassword(ciphertextBytes []byte, password string, keyfiles ...[]byte) ([]byte, error) {
 	// Derive a 32-byte key from the password
-	key := deriveKey(password)
+	key := deriveKey(password, keyfiles...)
  aesCipher, err := aesNewCipher(key)
  if err != nil {
      return []byte{}, fmt.Errorf("error creating AES cipher: %w", err)
@@ -70,6 +113,9 @@ func DecryptWithPassword(ciphertextBytes []byte, password string) ([]byte, error
  }
 
 	// Split the ciphertext into IV and actual ciphertext
+	if len(ciphertextBytes) < gcm.NonceSize() {
+		return []byte{}, ErrDecrypt
+	}
 	iv := ciphertextBytes[:gcm.NonceSize()]
 	ciphertextBytes = ciphertextBytes[gcm.NonceSize():]
 
@@ -77,15 +123,17 @@ func DecryptWithPassword(ciphertextBytes []byte, password string) ([]byte, error
 	dst := []byte{}
 	plaintext, err := gcm.Open(dst, iv, ciphertextBytes, nil)
 	if err != nil {
-		return []byte{}, errors.New("error decrypting ciphertext")
+		return []byte{}, ErrDecrypt
 	}
 
 	return plaintext, nil
 }
 
-func EncryptWithPassword(data []byte, password string) ([]byte, error) {
+// EncryptWithPassword encrypts data with a key derived from the password and the hashes of
+// keyfiles, if any
+func EncryptWithPassword(data []byte, password string, keyfiles ...[]byte) ([]byte, error) {
 	// Derive a 32-byte key from the password
-	key := deriveKey(password)
+	key := deriveKey(password, keyfiles...)
  aesCipher, err := aesNewCipher(key)
  if err != nil {
      return []byte{}, fmt.Errorf("error creating AES cipher: %+w", err)
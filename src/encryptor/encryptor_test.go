@@ -3,6 +3,7 @@ package encryptor
 import (
     "bytes"
     "encoding/base64"
+    "errors"
     "testing"
     "io"
 )
@@ -78,8 +79,8 @@ func TestDecryptWithPassword_AuthFailure(t *testing.T) {
     ctBad := make([]byte, len(ct))
     copy(ctBad, ct)
     ctBad[len(ctBad)-1] ^= 0xFF
-    if _, err := DecryptWithPassword(ctBad, pwd); err == nil {
-        t.Fatalf("expected auth error on tampered ciphertext")
+    if _, err := DecryptWithPassword(ctBad, pwd); !errors.Is(err, ErrDecrypt) {
+        t.Fatalf("expected auth error on tampered ciphertext, got %v", err)
     }
 }
 
@@ -121,13 +122,10 @@ func TestEncryptWithPassword_IVReadPanic(t *testing.T) {
     _, _ = EncryptWithPassword([]byte("data"), "pwd")
 }
 
-func TestDecryptWithPassword_TooShortCiphertextPanics(t *testing.T) {
-    defer func() {
-        if r := recover(); r == nil {
-            t.Fatalf("expected panic on too-short ciphertext slice for nonce")
-        }
-    }()
-    _, _ = DecryptWithPassword([]byte{1, 2, 3}, "pwd")
+func TestDecryptWithPassword_TooShortCiphertext(t *testing.T) {
+    if _, err := DecryptWithPassword([]byte{1, 2, 3}, "pwd"); !errors.Is(err, ErrDecrypt) {
+        t.Fatalf("expected ErrDecrypt for ciphertext shorter than the nonce, got %v", err)
+    }
 }
 
 func TestEncryptWithRandomKey_KeyGenError(t *testing.T) {
@@ -140,3 +138,35 @@ func TestEncryptWithRandomKey_KeyGenError(t *testing.T) {
         t.Fatalf("expected error when random key generation fails")
     }
 }
+
+func TestEncryptDecryptWithPassword_Keyfiles(t *testing.T) {
+    data := []byte("needs both factors")
+    first, _ := HashKeyfile(bytes.NewReader([]byte("usb stick")))
+    second, _ := HashKeyfile(bytes.NewReader([]byte("second stick")))
+
+    ct, err := EncryptWithPassword(data, "pwd", first, second)
+    if err != nil {
+        t.Fatalf("encrypt error: %v", err)
+    }
+    // the order of the keyfiles doesn't matter
+    if pt, err := DecryptWithPassword(ct, "pwd", second, first); err != nil || !bytes.Equal(pt, data) {
+        t.Fatalf("expected decryption with both keyfiles, got %q (%v)", pt, err)
+    }
+    for _, missing := range [][][]byte{nil, {first}, {first, first}} {
+        if _, err := DecryptWithPassword(ct, "pwd", missing...); !errors.Is(err, ErrDecrypt) {
+            t.Fatalf("expected ErrDecrypt with keyfiles %d, got %v", len(missing), err)
+        }
+    }
+    if _, err := DecryptWithPassword(ct, "other", first, second); !errors.Is(err, ErrDecrypt) {
+        t.Fatalf("expected ErrDecrypt with the wrong password, got %v", err)
+    }
+
+    // keyfile-only and the unchanged key of a password alone
+    ct, _ = EncryptWithPassword(data, "", first)
+    if pt, err := DecryptWithPassword(ct, "", first); err != nil || !bytes.Equal(pt, data) {
+        t.Fatalf("expected decryption with the keyfile alone, got %q (%v)", pt, err)
+    }
+    if !bytes.Equal(deriveKey("pwd"), deriveKey("pwd", [][]byte{}...)) || bytes.Equal(deriveKey(""), deriveKey("", first)) {
+        t.Fatalf("expected keyfiles to change the key and only them")
+    }
+}
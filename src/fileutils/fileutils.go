@@ -2,44 +2,121 @@ package fileutils
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
-	"time"
+	"path/filepath"
+	"runtime"
 )
 
+// test hooks for dependency injection in unit tests; default to real implementations
+var (
+	createTempFn = os.CreateTemp
+	syncFileFn   = func(f *os.File) error { return f.Sync() }
+	renameFn     = os.Rename
+	openDirFn    = os.Open
+)
+
+// WriteToFile atomically replaces filename with content. The content is written to a temp
+// file in the same directory, synced to disk and renamed over filename, after which the
+// directory itself is synced. A crash at any point leaves either the complete new file or
+// none at all, never a torn one.
 func WriteToFile(filename string, content []byte) error {
-	// Open the file for writing with 0644 permissions
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0644)
+	// renaming over a device or directory would replace it instead of writing into it
+	if info, err := os.Lstat(filename); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("error opening file: %s is not a regular file", filename)
+	}
+
+	dir := filepath.Dir(filename)
+	tmp, err := createTempFn(dir, "."+filepath.Base(filename)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("error opening file: %w", err)
 	}
-	defer file.Close() // Ensure the file is closed even if an error occurs
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
-	// Write the content to the file
-	_, err = file.Write(content)
-	if err != nil {
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
 		return fmt.Errorf("error writing to file: %w", err)
 	}
+	if err := syncFileFn(tmp); err != nil {
+		return fmt.Errorf("error syncing file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	if err := renameFn(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("error renaming file into place: %w", err)
+	}
+	committed = true
 
+	// the rename itself is only durable once the directory entry is synced
+	if err := SyncDir(dir); err != nil {
+		return fmt.Errorf("error syncing directory: %w", err)
+	}
 	return nil
 }
 
-func ObfuscateFileTimestamps(dirPath string) error {
-	files, err := os.ReadDir(dirPath)
+// SyncDir flushes the directory entries of dir to disk. Directories can't be opened for
+// syncing on Windows, so it's a no-op there.
+func SyncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := openDirFn(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return syncFileFn(d)
+}
+
+// MoveTree moves everything inside src into dst, merging into directories which already exist
+// there and replacing other existing paths. src and dst have to be on the same file system.
+func MoveTree(src, dst string) error {
+	entries, err := os.ReadDir(src)
 	if err != nil {
 		return err
 	}
-	for _, file := range files {
-		filePath := dirPath + "/" + file.Name()
-		if err := touchFile(filePath); err != nil {
+	for _, entry := range entries {
+		if err := Move(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func touchFile(filePath string) error {
-	rand.Seed(time.Now().UnixNano())
-	randomTime := time.Now().Add(time.Duration(rand.Intn(1000000)-500000) * time.Second)
-	return os.Chtimes(filePath, randomTime, randomTime)
+// Move renames from to to. Directories are merged into an existing directory at to, anything
+// else at to is replaced; directories only when empty.
+func Move(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	existing, err := os.Lstat(to)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case info.IsDir() && existing.IsDir():
+		// the moved directory is dropped afterwards, it only has to allow moving its content out
+		if err := os.Chmod(from, 0700); err != nil {
+			return err
+		}
+		if err := MoveTree(from, to); err != nil {
+			return err
+		}
+		return os.Remove(from)
+	case info.IsDir() || existing.IsDir():
+		// rename can't replace a file with a directory or the other way round
+		if err := os.Remove(to); err != nil {
+			return fmt.Errorf("can't replace %s: %w", to, err)
+		}
+	}
+	return renameFn(from, to)
 }
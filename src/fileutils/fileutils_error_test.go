@@ -1,6 +1,7 @@
 package fileutils
 
 import (
+    "errors"
     "os"
     "path/filepath"
     "runtime"
@@ -22,8 +23,8 @@ func TestWriteToFile_WriteError(t *testing.T) {
     }
 }
 
-// TestObfuscateFileTimestamps_TouchError creates a dangling symlink in the
-// directory so that os.Chtimes fails when ObfuscateFileTimestamps iterates it.
+// TestObfuscateFileTimestamps_TouchError lists a dangling symlink so that
+// os.Chtimes fails when ObfuscateFileTimestamps iterates it.
 // If symlink creation is not supported, the test is skipped.
 func TestObfuscateFileTimestamps_TouchError(t *testing.T) {
     // Symlinks generally unsupported or require privilege on Windows
@@ -43,7 +44,54 @@ func TestObfuscateFileTimestamps_TouchError(t *testing.T) {
         t.Skipf("symlink not supported: %v", err)
     }
 
-    if err := ObfuscateFileTimestamps(dir); err == nil {
+    if err := ObfuscateFileTimestamps([]string{filepath.Join(dir, "ok.txt"), brokenLink}, Timestamps{}); err == nil {
         t.Fatalf("expected error due to dangling symlink causing Chtimes failure")
     }
 }
+
+// TestWriteToFile_FailuresKeepOldContent makes every step of the atomic write fail in turn and
+// checks the previous content survives and no temp file is left behind
+func TestWriteToFile_FailuresKeepOldContent(t *testing.T) {
+    oldSync, oldRename, oldOpenDir := syncFileFn, renameFn, openDirFn
+    t.Cleanup(func() { syncFileFn, renameFn, openDirFn = oldSync, oldRename, oldOpenDir })
+    boom := errors.New("boom")
+
+    cases := map[string]func(){
+        "sync":   func() { syncFileFn = func(f *os.File) error { return boom } },
+        "rename": func() { renameFn = func(from, to string) error { return boom } },
+    }
+    for name, stub := range cases {
+        dir := t.TempDir()
+        path := filepath.Join(dir, "part")
+        if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+            t.Fatalf("write: %v", err)
+        }
+        syncFileFn, renameFn = oldSync, oldRename
+        stub()
+
+        if err := WriteToFile(path, []byte("new content")); !errors.Is(err, boom) {
+            t.Fatalf("%s: expected failure, got %v", name, err)
+        }
+        if got, _ := os.ReadFile(path); string(got) != "old" {
+            t.Fatalf("%s: old content lost: %q", name, got)
+        }
+        if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+            t.Fatalf("%s: expected temp file to be removed, got %d entries", name, len(entries))
+        }
+    }
+
+    // a failing directory sync is reported, the file itself is already in place
+    syncFileFn, renameFn = oldSync, oldRename
+    openDirFn = func(name string) (*os.File, error) { return nil, boom }
+    path := filepath.Join(t.TempDir(), "masterlock")
+    if err := WriteToFile(path, []byte("x")); !errors.Is(err, boom) {
+        t.Fatalf("expected directory sync failure, got %v", err)
+    }
+}
+
+// TestWriteToFile_MissingDirectory checks the temp file creation error is reported
+func TestWriteToFile_MissingDirectory(t *testing.T) {
+    if err := WriteToFile(filepath.Join(t.TempDir(), "missing", "part"), []byte("x")); err == nil {
+        t.Fatalf("expected error for missing directory")
+    }
+}
@@ -55,13 +55,13 @@ func TestObfuscateFileTimestamps_WithinBounds(t *testing.T) {
     stat1Before, _ := os.Stat(f1)
     stat2Before, _ := os.Stat(f2)
 
-    // Only files directly under tmp are processed by ObfuscateFileTimestamps
-    if err := ObfuscateFileTimestamps(tmp); err != nil {
+    // Only the listed files are processed by ObfuscateFileTimestamps
+    if err := ObfuscateFileTimestamps([]string{f1}, Timestamps{}); err != nil {
         t.Fatalf("ObfuscateFileTimestamps error: %v", err)
     }
 
     stat1After, _ := os.Stat(f1)
-    // f2 is in a subdir and won't be touched by current implementation
+    // f2 isn't listed and must not be touched
     stat2After, _ := os.Stat(f2)
 
     if stat1After.ModTime().Equal(stat1Before.ModTime()) {
@@ -76,14 +76,98 @@ func TestObfuscateFileTimestamps_WithinBounds(t *testing.T) {
         t.Fatalf("f1 ModTime out of expected bounds: %v not in [%v, %v]", mt, lower, upper)
     }
 
-    // Ensure untouched nested file stays the same (documents current behavior)
+    // Ensure the unlisted file stays the same
     if !stat2After.ModTime().Equal(stat2Before.ModTime()) {
-        t.Fatalf("expected f2 mtime to remain unchanged for nested file in current implementation")
+        t.Fatalf("expected f2 mtime to remain unchanged as it wasn't listed")
     }
 }
 
-func TestObfuscateFileTimestamps_NonexistentDir(t *testing.T) {
-    if err := ObfuscateFileTimestamps(filepath.Join(t.TempDir(), "does-not-exist")); err == nil {
-        t.Fatalf("expected error for nonexistent directory")
+func TestObfuscateFileTimestamps_NonexistentFile(t *testing.T) {
+    if err := ObfuscateFileTimestamps([]string{filepath.Join(t.TempDir(), "does-not-exist")}, Timestamps{}); err == nil {
+        t.Fatalf("expected error for nonexistent file")
+    }
+}
+
+func TestWriteToFile_ReplacesLargerFileCompletely(t *testing.T) {
+    tmp := t.TempDir()
+    path := filepath.Join(tmp, "masterlock")
+    if err := WriteToFile(path, bytes.Repeat([]byte("x"), 1024)); err != nil {
+        t.Fatalf("first write: %v", err)
+    }
+    if err := WriteToFile(path, []byte("short")); err != nil {
+        t.Fatalf("second write: %v", err)
+    }
+    got, err := os.ReadFile(path)
+    if err != nil || string(got) != "short" {
+        t.Fatalf("expected no trailing data, got %q (%v)", got, err)
+    }
+    entries, _ := os.ReadDir(tmp)
+    if len(entries) != 1 {
+        t.Fatalf("expected no temp files to be left, got %d entries", len(entries))
+    }
+    info, _ := os.Stat(path)
+    if info.Mode().Perm() != 0o644 {
+        t.Fatalf("unexpected mode %v", info.Mode())
+    }
+}
+
+func TestMoveTree_MergesAndReplaces(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "staging")
+    dst := filepath.Join(tmp, "out")
+    write := func(path, content string) {
+        t.Helper()
+        if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+            t.Fatalf("mkdir: %v", err)
+        }
+        if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+            t.Fatalf("write: %v", err)
+        }
+    }
+    write(filepath.Join(src, "tree", "new.txt"), "new")
+    write(filepath.Join(src, "tree", "same.txt"), "staged")
+    write(filepath.Join(src, "tree", "was-file", "inner.txt"), "inner")
+    write(filepath.Join(src, "fresh", "f.txt"), "fresh")
+    // a read-only staged directory merged into an existing one
+    if err := os.Chmod(filepath.Join(src, "tree"), 0o555); err != nil {
+        t.Fatalf("chmod: %v", err)
+    }
+    write(filepath.Join(dst, "tree", "keep.txt"), "keep")
+    write(filepath.Join(dst, "tree", "same.txt"), "old")
+    write(filepath.Join(dst, "tree", "was-file"), "old file")
+
+    if err := MoveTree(src, dst); err != nil {
+        t.Fatalf("MoveTree: %v", err)
+    }
+    expect := map[string]string{
+        filepath.Join("tree", "new.txt"):               "new",
+        filepath.Join("tree", "same.txt"):              "staged",
+        filepath.Join("tree", "keep.txt"):              "keep",
+        filepath.Join("tree", "was-file", "inner.txt"): "inner",
+        filepath.Join("fresh", "f.txt"):                "fresh",
+    }
+    for name, content := range expect {
+        got, err := os.ReadFile(filepath.Join(dst, name))
+        if err != nil || string(got) != content {
+            t.Fatalf("%s = %q (%v), want %q", name, got, err, content)
+        }
+    }
+    if entries, _ := os.ReadDir(src); len(entries) != 0 {
+        t.Fatalf("expected staging to be empty, got %d entries", len(entries))
+    }
+}
+
+func TestMove_RefusesNonEmptyDirectory(t *testing.T) {
+    tmp := t.TempDir()
+    from := filepath.Join(tmp, "file")
+    to := filepath.Join(tmp, "dir")
+    if err := os.WriteFile(from, []byte("x"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if err := os.MkdirAll(filepath.Join(to, "child"), 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := Move(from, to); err == nil {
+        t.Fatalf("expected a non-empty directory to not be replaced")
     }
 }
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fileutils
+
+import "os"
+
+// LinkCount reports a single link on platforms whose file info carries no link count
+func LinkCount(info os.FileInfo) uint64 {
+	return 1
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+// LinkCount returns the number of hardlinks of the given file, 1 if the platform doesn't tell
+func LinkCount(info os.FileInfo) uint64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 1
+	}
+	return uint64(st.Nlink)
+}
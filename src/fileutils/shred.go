@@ -0,0 +1,120 @@
+package fileutils
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// shredBlockSize is the size of the random blocks files are overwritten with
+const shredBlockSize = 64 * 1024
+
+// test hooks for shredding; default to real implementations
+var (
+	randReadFn = crand.Read
+	removeFn   = os.Remove
+)
+
+// ErrHardlinked is returned by Shred for a file with further hardlinks, which is only unlinked:
+// overwriting it would destroy the content reachable through the other links as well
+var ErrHardlinked = errors.New("file has further hardlinks, only unlinked, content not wiped")
+
+// Shred overwrites the regular file at filePath with random data passes times, truncates it,
+// renames it to a random name and unlinks it, so neither content, size nor name are left
+// behind. Other file types, symlinks included, are only unlinked and never followed. A regular
+// file with further hardlinks is unlinked as well and ErrHardlinked returned, its content stays
+// readable through the other links.
+//
+// Overwriting in place is best effort: copy-on-write (btrfs, ZFS, APFS) and journaling file
+// systems, snapshots and the wear leveling of flash storage may keep older copies of the data.
+func Shred(filePath string, passes int) error {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return err
+	}
+	linked := info.Mode().IsRegular() && LinkCount(info) > 1
+	if info.Mode().IsRegular() && !linked {
+		if err := overwrite(filePath, info, passes); err != nil {
+			return err
+		}
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(filePath)
+	renamed := filepath.Join(dir, name)
+	if err := renameFn(filePath, renamed); err != nil {
+		return fmt.Errorf("error renaming: %w", err)
+	}
+	if err := removeFn(renamed); err != nil {
+		return fmt.Errorf("error unlinking: %w", err)
+	}
+	if err := SyncDir(dir); err != nil {
+		return err
+	}
+	if linked {
+		return ErrHardlinked
+	}
+	return nil
+}
+
+// overwrite fills the file with random data passes times and truncates it to zero length,
+// syncing after every pass so the writes actually reach the disk
+func overwrite(filePath string, info os.FileInfo, passes int) error {
+	f, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if os.IsPermission(err) {
+		// read-only files get wiped as well, the directory allowing to delete them is enough
+		if err := os.Chmod(filePath, 0600); err != nil {
+			return err
+		}
+		f, err = os.OpenFile(filePath, os.O_WRONLY, 0)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, shredBlockSize)
+	for pass := 0; pass < passes; pass++ {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		for left := info.Size(); left > 0; {
+			n := int64(len(buf))
+			if left < n {
+				n = left
+			}
+			if _, err := randReadFn(buf[:n]); err != nil {
+				return err
+			}
+			if _, err := f.Write(buf[:n]); err != nil {
+				return fmt.Errorf("error overwriting: %w", err)
+			}
+			left -= n
+		}
+		if err := syncFileFn(f); err != nil {
+			return fmt.Errorf("error syncing: %w", err)
+		}
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating: %w", err)
+	}
+	if err := syncFileFn(f); err != nil {
+		return fmt.Errorf("error syncing: %w", err)
+	}
+	return f.Close()
+}
+
+// randomName returns a random hex file name
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := randReadFn(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
@@ -0,0 +1,129 @@
+package fileutils
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestShred_OverwritesTruncatesRenamesAndUnlinks(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "secret.txt")
+    if err := os.WriteFile(path, bytes.Repeat([]byte("s"), 3*shredBlockSize+10), 0o400); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+
+    // keep the renamed file around to check what is left of it
+    var renamed string
+    oldRemove, oldRead := removeFn, randReadFn
+    removeFn = func(name string) error { renamed = name; return nil }
+    reads := 0
+    randReadFn = func(b []byte) (int, error) { reads++; return oldRead(b) }
+    t.Cleanup(func() { removeFn, randReadFn = oldRemove, oldRead })
+
+    if err := Shred(path, 2); err != nil {
+        t.Fatalf("shred: %v", err)
+    }
+    if _, err := os.Lstat(path); !os.IsNotExist(err) {
+        t.Fatalf("expected original name to be gone, got %v", err)
+    }
+    if filepath.Dir(renamed) != dir || filepath.Base(renamed) == "secret.txt" {
+        t.Fatalf("unexpected rename target %q", renamed)
+    }
+    info, err := os.Stat(renamed)
+    if err != nil || info.Size() != 0 {
+        t.Fatalf("expected a truncated file, got %v %v", info, err)
+    }
+    // two passes of four blocks each, plus the random name
+    if reads != 9 {
+        t.Fatalf("expected 9 random reads, got %d", reads)
+    }
+
+    removeFn = oldRemove
+    other := filepath.Join(dir, "other.txt")
+    if err := os.WriteFile(other, []byte("x"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if err := Shred(other, 1); err != nil {
+        t.Fatalf("shred: %v", err)
+    }
+    entries, _ := os.ReadDir(dir)
+    if len(entries) != 1 {
+        t.Fatalf("expected only the kept file from the first shred, got %d entries", len(entries))
+    }
+}
+
+func TestShred_DoesNotFollowSymlinks(t *testing.T) {
+    dir := t.TempDir()
+    target := filepath.Join(dir, "target.txt")
+    link := filepath.Join(dir, "link")
+    if err := os.WriteFile(target, []byte("keep"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if err := os.Symlink(target, link); err != nil {
+        t.Skipf("symlinks not supported: %v", err)
+    }
+    if err := Shred(link, 3); err != nil {
+        t.Fatalf("shred: %v", err)
+    }
+    if _, err := os.Lstat(link); !os.IsNotExist(err) {
+        t.Fatalf("expected link to be removed, got %v", err)
+    }
+    if got, _ := os.ReadFile(target); string(got) != "keep" {
+        t.Fatalf("link target was modified: %q", got)
+    }
+}
+
+func TestShred_OnlyUnlinksHardlinkedFiles(t *testing.T) {
+    dir := t.TempDir()
+    keep := filepath.Join(dir, "keep.txt")
+    link := filepath.Join(dir, "link.txt")
+    if err := os.WriteFile(keep, []byte("keep"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if err := os.Link(keep, link); err != nil {
+        t.Skipf("hardlinks not supported: %v", err)
+    }
+    if info, _ := os.Lstat(link); LinkCount(info) != 2 {
+        t.Skip("link count not available on this platform")
+    }
+    if err := Shred(link, 3); !errors.Is(err, ErrHardlinked) {
+        t.Fatalf("expected ErrHardlinked, got %v", err)
+    }
+    if _, err := os.Lstat(link); !os.IsNotExist(err) {
+        t.Fatalf("expected link to be removed, got %v", err)
+    }
+    if got, _ := os.ReadFile(keep); string(got) != "keep" {
+        t.Fatalf("other link was modified: %q", got)
+    }
+
+    // with the last link gone the file is wiped as usual
+    if err := Shred(keep, 1); err != nil {
+        t.Fatalf("shred: %v", err)
+    }
+    if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+        t.Fatalf("expected the directory to be empty, got %d entries", len(entries))
+    }
+}
+
+func TestShred_Errors(t *testing.T) {
+    if err := Shred(filepath.Join(t.TempDir(), "missing"), 1); err == nil {
+        t.Fatalf("expected error for missing file")
+    }
+
+    path := filepath.Join(t.TempDir(), "f")
+    if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    oldRead := randReadFn
+    randReadFn = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+    t.Cleanup(func() { randReadFn = oldRead })
+    if err := Shred(path, 1); err == nil {
+        t.Fatalf("expected random source error")
+    }
+    if _, err := os.Stat(path); err != nil {
+        t.Fatalf("expected file to be kept on failure: %v", err)
+    }
+}
@@ -0,0 +1,213 @@
+package fileutils
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Timestamp strategies ObfuscateFileTimestamps supports
+const (
+	// TimestampsRandom spreads the files randomly across a window of about eleven days around now
+	TimestampsRandom = "random"
+	// TimestampsEpoch sets all files to one fixed time
+	TimestampsEpoch = "epoch"
+	// TimestampsRange spreads the files uniformly across a given date range
+	TimestampsRange = "range"
+	// TimestampsReference copies the timestamps of the files in a reference directory
+	TimestampsReference = "reference"
+)
+
+// randomWindow is how far the random strategy spreads timestamps in both directions of now
+const randomWindow = 500000 * time.Second
+
+// test hooks for the timestamp handling; default to real implementations
+var (
+	randIntFn = crand.Int
+	chtimesFn = os.Chtimes
+)
+
+// Timestamps configures how ObfuscateFileTimestamps picks the new timestamps
+type Timestamps struct {
+	// Strategy is one of the Timestamps* constants, empty means random
+	Strategy string
+	// Epoch is the time the epoch strategy sets, the zero value means the unix epoch
+	Epoch time.Time
+	// From and To bound the range strategy
+	From time.Time
+	To   time.Time
+	// Reference is the directory whose file timestamps the reference strategy copies
+	Reference string
+}
+
+// ParseTimestamps reads a timestamp strategy as given on the command line: random, epoch,
+// epoch=DATE, range=FROM,TO or reference=DIR. Dates are RFC 3339 or plain YYYY-MM-DD (UTC).
+func ParseTimestamps(spec string) (Timestamps, error) {
+	strategy, arg, hasArg := strings.Cut(spec, "=")
+	t := Timestamps{Strategy: strategy}
+	switch strategy {
+	case "", TimestampsRandom:
+		if hasArg {
+			return Timestamps{}, fmt.Errorf("timestamp strategy %s takes no value", TimestampsRandom)
+		}
+	case TimestampsEpoch:
+		if hasArg {
+			epoch, err := parseDate(arg)
+			if err != nil {
+				return Timestamps{}, err
+			}
+			t.Epoch = epoch
+		}
+	case TimestampsRange:
+		from, to, ok := strings.Cut(arg, ",")
+		if !ok {
+			return Timestamps{}, fmt.Errorf("timestamp range needs two dates, range=FROM,TO")
+		}
+		var err error
+		if t.From, err = parseDate(from); err != nil {
+			return Timestamps{}, err
+		}
+		if t.To, err = parseDate(to); err != nil {
+			return Timestamps{}, err
+		}
+	case TimestampsReference:
+		if arg == "" {
+			return Timestamps{}, fmt.Errorf("timestamp reference needs a directory, reference=DIR")
+		}
+		t.Reference = arg
+	default:
+		return Timestamps{}, fmt.Errorf("unknown timestamp strategy: %s", strategy)
+	}
+	return t, t.Validate()
+}
+
+// parseDate accepts RFC 3339 times and plain dates
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// Validate checks the strategy can be applied, so a bad one fails before anything gets written
+func (t Timestamps) Validate() error {
+	_, err := t.picker()
+	return err
+}
+
+// ObfuscateFileTimestamps sets the given files to new access and modification times picked by
+// the strategy, so their order can't be told from them. Only the listed files are touched,
+// never anything else next to them. The change time can't be set and keeps the real time.
+func ObfuscateFileTimestamps(paths []string, opts Timestamps) error {
+	pick, err := opts.picker()
+	if err != nil {
+		return err
+	}
+	for _, filePath := range paths {
+		atime, mtime, err := pick()
+		if err != nil {
+			return err
+		}
+		if err := chtimesFn(filePath, atime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// picker returns the function picking the times of one file. The modification time is drawn
+// from the strategy's range and the access time lies between it and the end of that range,
+// as a file can't be read before it was written.
+func (t Timestamps) picker() (func() (time.Time, time.Time, error), error) {
+	var from, to time.Time
+	var pool []time.Time
+	switch t.Strategy {
+	case "", TimestampsRandom:
+		now := time.Now()
+		from, to = now.Add(-randomWindow), now.Add(randomWindow)
+	case TimestampsEpoch:
+		from = t.Epoch
+		if from.IsZero() {
+			from = time.Unix(0, 0)
+		}
+		to = from
+	case TimestampsRange:
+		if t.To.Before(t.From) {
+			return nil, fmt.Errorf("timestamp range ends before it starts")
+		}
+		from, to = t.From, t.To
+	case TimestampsReference:
+		var err error
+		if pool, err = referenceTimes(t.Reference); err != nil {
+			return nil, err
+		}
+		for _, mtime := range pool {
+			if mtime.After(to) {
+				to = mtime
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown timestamp strategy: %s", t.Strategy)
+	}
+
+	return func() (time.Time, time.Time, error) {
+		var mtime time.Time
+		if pool != nil {
+			i, err := randIntFn(crand.Reader, big.NewInt(int64(len(pool))))
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			mtime = pool[i.Int64()]
+		} else {
+			var err error
+			if mtime, err = randomBetween(from, to); err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+		}
+		atime, err := randomBetween(mtime, to)
+		return atime, mtime, err
+	}, nil
+}
+
+// referenceTimes collects the modification times of the regular files below dir
+func referenceTimes(dir string) ([]time.Time, error) {
+	var times []time.Time
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			times = append(times, info.ModTime())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading timestamp reference: %w", err)
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("timestamp reference %s holds no files", dir)
+	}
+	return times, nil
+}
+
+// randomBetween returns a uniformly random time in [from, to], drawn from crypto/rand
+func randomBetween(from, to time.Time) (time.Time, error) {
+	span := to.Sub(from)
+	if span <= 0 {
+		return from, nil
+	}
+	n, err := randIntFn(crand.Reader, new(big.Int).Add(big.NewInt(int64(span)), big.NewInt(1)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return from.Add(time.Duration(n.Int64())), nil
+}
@@ -0,0 +1,139 @@
+package fileutils
+
+import (
+    "errors"
+    "io"
+    "math/big"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// recordChtimes captures the times set per file, reading access times back isn't portable
+func recordChtimes(t *testing.T) map[string][2]time.Time {
+    t.Helper()
+    got := map[string][2]time.Time{}
+    old := chtimesFn
+    chtimesFn = func(name string, atime, mtime time.Time) error {
+        got[name] = [2]time.Time{atime, mtime}
+        return old(name, atime, mtime)
+    }
+    t.Cleanup(func() { chtimesFn = old })
+    return got
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) []string {
+    t.Helper()
+    var paths []string
+    for _, name := range names {
+        p := filepath.Join(dir, name)
+        if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+            t.Fatalf("write: %v", err)
+        }
+        paths = append(paths, p)
+    }
+    return paths
+}
+
+func TestObfuscateFileTimestamps_Epoch(t *testing.T) {
+    paths := writeFiles(t, t.TempDir(), "a", "b")
+    got := recordChtimes(t)
+    if err := ObfuscateFileTimestamps(paths, Timestamps{Strategy: TimestampsEpoch}); err != nil {
+        t.Fatalf("obfuscate: %v", err)
+    }
+    for _, p := range paths {
+        info, _ := os.Stat(p)
+        if !info.ModTime().Equal(time.Unix(0, 0)) || !got[p][0].Equal(time.Unix(0, 0)) {
+            t.Fatalf("expected %s at the unix epoch, got %v / %v", p, info.ModTime(), got[p][0])
+        }
+    }
+}
+
+func TestObfuscateFileTimestamps_Range(t *testing.T) {
+    paths := writeFiles(t, t.TempDir(), "a", "b", "c", "d")
+    got := recordChtimes(t)
+    from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+    to := time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)
+    if err := ObfuscateFileTimestamps(paths, Timestamps{Strategy: TimestampsRange, From: from, To: to}); err != nil {
+        t.Fatalf("obfuscate: %v", err)
+    }
+    for _, p := range paths {
+        atime, mtime := got[p][0], got[p][1]
+        if mtime.Before(from) || mtime.After(to) {
+            t.Fatalf("mtime %v outside of range", mtime)
+        }
+        if atime.Before(mtime) || atime.After(to) {
+            t.Fatalf("atime %v not between mtime %v and range end", atime, mtime)
+        }
+    }
+}
+
+func TestObfuscateFileTimestamps_Reference(t *testing.T) {
+    ref := t.TempDir()
+    stamps := map[time.Time]bool{}
+    for i, p := range writeFiles(t, ref, "x", "y") {
+        stamp := time.Date(2015, 3, i+1, 12, 0, 0, 0, time.UTC)
+        if err := os.Chtimes(p, stamp, stamp); err != nil {
+            t.Fatalf("chtimes: %v", err)
+        }
+        stamps[stamp] = true
+    }
+    paths := writeFiles(t, t.TempDir(), "a", "b", "c")
+    got := recordChtimes(t)
+    if err := ObfuscateFileTimestamps(paths, Timestamps{Strategy: TimestampsReference, Reference: ref}); err != nil {
+        t.Fatalf("obfuscate: %v", err)
+    }
+    for _, p := range paths {
+        if !stamps[got[p][1].UTC()] {
+            t.Fatalf("mtime %v not taken from the reference", got[p][1])
+        }
+    }
+}
+
+func TestObfuscateFileTimestamps_RandomSourceError(t *testing.T) {
+    paths := writeFiles(t, t.TempDir(), "a")
+    old := randIntFn
+    randIntFn = func(io.Reader, *big.Int) (*big.Int, error) { return nil, errors.New("no entropy") }
+    t.Cleanup(func() { randIntFn = old })
+    if err := ObfuscateFileTimestamps(paths, Timestamps{}); err == nil {
+        t.Fatalf("expected random source error")
+    }
+}
+
+func TestParseTimestamps(t *testing.T) {
+    tests := []struct {
+        spec string
+        want Timestamps
+        err  string
+    }{
+        {spec: "random", want: Timestamps{Strategy: TimestampsRandom}},
+        {spec: "epoch", want: Timestamps{Strategy: TimestampsEpoch}},
+        {spec: "epoch=2020-02-03", want: Timestamps{Strategy: TimestampsEpoch, Epoch: time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC)}},
+        {spec: "range=2019-01-01,2019-06-30T12:00:00Z", want: Timestamps{Strategy: TimestampsRange, From: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2019, 6, 30, 12, 0, 0, 0, time.UTC)}},
+        {spec: "random=1", err: "takes no value"},
+        {spec: "range=2019-01-01", err: "two dates"},
+        {spec: "range=2020-01-01,2019-01-01", err: "ends before"},
+        {spec: "epoch=yesterday", err: "invalid date"},
+        {spec: "reference=", err: "needs a directory"},
+        {spec: "reference=" + filepath.Join(t.TempDir(), "missing"), err: "timestamp reference"},
+        {spec: "reference=" + t.TempDir(), err: "holds no files"},
+        {spec: "shuffle", err: "unknown timestamp strategy"},
+    }
+    for _, tt := range tests {
+        got, err := ParseTimestamps(tt.spec)
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Fatalf("%s: expected error containing %q, got %v", tt.spec, tt.err, err)
+            }
+            continue
+        }
+        if err != nil {
+            t.Fatalf("%s: %v", tt.spec, err)
+        }
+        if got.Strategy != tt.want.Strategy || !got.Epoch.Equal(tt.want.Epoch) || !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+            t.Fatalf("%s: got %+v", tt.spec, got)
+        }
+    }
+}
@@ -0,0 +1,174 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name of the ignore files read while walking directories
+const FileName = ".tachiignore"
+
+// rule is a single compiled ignore pattern
+type rule struct {
+	// base is the slash separated directory the pattern is relative to, "" for the root
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher decides whether paths are excluded. Patterns follow gitignore semantics: the last
+// matching pattern wins, "!" re-includes, a trailing "/" only matches directories, patterns
+// containing a "/" are anchored to the directory they were defined in and "**" matches across
+// directories.
+type Matcher struct {
+	rules []rule
+}
+
+// New returns a matcher for the given patterns, relative to the root of the walk
+func New(patterns []string) (Matcher, error) {
+	return Matcher{}.Add("", patterns)
+}
+
+// Add returns a new matcher extended by patterns relative to the base directory. The receiver
+// is left untouched so matchers can be scoped while descending into directories.
+func (m Matcher) Add(base string, patterns []string) (Matcher, error) {
+	rules := make([]rule, len(m.rules), len(m.rules)+len(patterns))
+	copy(rules, m.rules)
+	for _, p := range patterns {
+		r, ok, err := compile(base, p)
+		if err != nil {
+			return m, err
+		}
+		if ok {
+			rules = append(rules, r)
+		}
+	}
+	return Matcher{rules: rules}, nil
+}
+
+// AddFile reads patterns from an ignore file located in the base directory
+func (m Matcher) AddFile(base string, r io.Reader) (Matcher, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return m, err
+	}
+	return m.Add(base, patterns)
+}
+
+// Match reports whether the slash separated path, relative to the root of the walk, is excluded
+func (m Matcher) Match(name string, isDir bool) bool {
+	excluded := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel := name
+		if r.base != "" {
+			if !strings.HasPrefix(name, r.base+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, r.base+"/")
+		}
+		if r.re.MatchString(rel) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+// compile turns a single gitignore style line into a rule. Blank lines and comments report false.
+func compile(base string, line string) (rule, bool, error) {
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false, nil
+	}
+
+	r := rule{base: strings.Trim(path.Clean("/"+base), "/")}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false, nil
+	}
+
+	// a slash anywhere but at the end anchors the pattern to its base directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := "^"
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	expr += translate(line) + "$"
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule{}, false, err
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// translate converts a glob into a regular expression body
+func translate(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// trimTrailingSpaces removes trailing spaces unless they are escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
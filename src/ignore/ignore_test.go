@@ -0,0 +1,90 @@
+package ignore
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestMatch_GitignoreSemantics(t *testing.T) {
+    m, err := New([]string{
+        "# comment",
+        "",
+        "node_modules/",
+        "*.log",
+        "!keep.log",
+        "/build",
+        "docs/**/*.tmp",
+        "cache?",
+        "[ab].bin",
+    })
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+
+    cases := []struct {
+        name  string
+        isDir bool
+        want  bool
+    }{
+        {"node_modules", true, true},
+        {"src/node_modules", true, true},
+        {"node_modules", false, false}, // dir-only pattern
+        {"debug.log", false, true},
+        {"logs/deep/debug.log", false, true},
+        {"keep.log", false, false},
+        {"build", true, true},
+        {"src/build", true, false}, // anchored to the root
+        {"docs/a/b/x.tmp", false, true},
+        {"docs/x.tmp", false, true},
+        {"other/x.tmp", false, false},
+        {"cache1", true, true},
+        {"cache12", true, false},
+        {"a.bin", false, true},
+        {"c.bin", false, false},
+        {"main.go", false, false},
+    }
+    for _, tc := range cases {
+        if got := m.Match(tc.name, tc.isDir); got != tc.want {
+            t.Errorf("Match(%q, dir=%v) = %v, want %v", tc.name, tc.isDir, got, tc.want)
+        }
+    }
+}
+
+func TestAddFile_ScopedToBase(t *testing.T) {
+    root, err := New(nil)
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+    m, err := root.AddFile("sub", strings.NewReader("/local.txt\n*.tmp\n"))
+    if err != nil {
+        t.Fatalf("AddFile: %v", err)
+    }
+
+    if !m.Match("sub/local.txt", false) {
+        t.Fatalf("expected anchored pattern to match in its directory")
+    }
+    if m.Match("sub/deeper/local.txt", false) {
+        t.Fatalf("expected anchored pattern to not match deeper")
+    }
+    if !m.Match("sub/deeper/x.tmp", false) {
+        t.Fatalf("expected unanchored pattern to match below its directory")
+    }
+    if m.Match("x.tmp", false) || m.Match("other/x.tmp", false) {
+        t.Fatalf("expected patterns to not apply outside their directory")
+    }
+    if root.Match("sub/x.tmp", false) {
+        t.Fatalf("expected the original matcher to be left untouched")
+    }
+}
+
+func TestCompile_EscapesAndTrailingSpaces(t *testing.T) {
+    m, err := New([]string{`\#hash`, `\!bang`, "spaced   ", `keep\ `})
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+    for _, name := range []string{"#hash", "!bang", "spaced", "keep "} {
+        if !m.Match(name, false) {
+            t.Errorf("expected %q to match", name)
+        }
+    }
+}
@@ -0,0 +1,121 @@
+// Package journal implements an append-only log of encrypted records. It lets interrupted runs
+// remember their progress without leaking part names or keys to whoever can read the disk.
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/voodooEntity/go-tachicrypt/src/encryptor"
+)
+
+// keyContext separates the journal key from the masterlock key derived from the same password
+const keyContext = "\x00tachicrypt-journal"
+
+// Sizes bounding the length prefix, other sizes can only come from a damaged journal. A record
+// holds at least the GCM nonce and tag.
+const (
+	minRecordSize = 12 + 16
+	maxRecordSize = 16 << 20
+)
+
+// ErrWrongPassword is returned by Open when the existing records don't decrypt with the password
+var ErrWrongPassword = errors.New("journal doesn't decrypt with this password")
+
+// test hooks for dependency injection in unit tests; default to real implementations
+var (
+	encryptFn = encryptor.EncryptWithPassword
+	decryptFn = encryptor.DecryptWithPassword
+	syncFn    = func(f *os.File) error { return f.Sync() }
+)
+
+// Journal appends records to a journal file
+type Journal struct {
+	f   *os.File
+	key string
+	// keyfiles are the hashes of the keyfiles mixed into the key
+	keyfiles [][]byte
+}
+
+// Open opens the journal at path, creating it if needed, and returns the records it already
+// holds. It's encrypted with the password and keyfile hashes unlocking the masterlock. A torn
+// record at the end, left by a crash while appending, is dropped.
+func Open(path string, password string, keyfiles ...[]byte) (*Journal, [][]byte, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	j := &Journal{f: f, key: password + keyContext, keyfiles: keyfiles}
+
+	records, end, err := j.read()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	// appending continues right after the last complete record
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	return j, records, nil
+}
+
+// read decrypts all complete records and returns them with the offset the last one ends at
+func (j *Journal) read() ([][]byte, int64, error) {
+	r := bufio.NewReader(j.f)
+	var records [][]byte
+	var end int64
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return records, end, nil
+		}
+		if size < minRecordSize || size > maxRecordSize {
+			return records, end, nil
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return records, end, nil
+		}
+		record, err := decryptFn(data, j.key, j.keyfiles...)
+		if err != nil {
+			// the first record failing means another password, a later one a damaged tail
+			if len(records) == 0 {
+				return nil, 0, ErrWrongPassword
+			}
+			return records, end, nil
+		}
+		records = append(records, record)
+		end += 4 + int64(size)
+	}
+}
+
+// Append encrypts record and appends it durably to the journal
+func (j *Journal) Append(record []byte) error {
+	data, err := encryptFn(record, j.key, j.keyfiles...)
+	if err != nil {
+		return fmt.Errorf("error encrypting journal record: %w", err)
+	}
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	if _, err := j.f.Write(append(buf, data...)); err != nil {
+		return fmt.Errorf("error writing journal: %w", err)
+	}
+	if err := syncFn(j.f); err != nil {
+		return fmt.Errorf("error syncing journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
@@ -0,0 +1,117 @@
+package journal
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestJournal_AppendAndReopen(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "journal")
+    j, records, err := Open(path, "pwd")
+    if err != nil {
+        t.Fatalf("open: %v", err)
+    }
+    if len(records) != 0 {
+        t.Fatalf("expected an empty journal, got %d records", len(records))
+    }
+    for _, r := range []string{"first", "second"} {
+        if err := j.Append([]byte(r)); err != nil {
+            t.Fatalf("append: %v", err)
+        }
+    }
+    j.Close()
+
+    raw, _ := os.ReadFile(path)
+    if len(raw) == 0 || string(raw) == "firstsecond" {
+        t.Fatalf("expected encrypted records on disk")
+    }
+
+    j, records, err = Open(path, "pwd")
+    if err != nil {
+        t.Fatalf("reopen: %v", err)
+    }
+    if len(records) != 2 || string(records[0]) != "first" || string(records[1]) != "second" {
+        t.Fatalf("unexpected records: %q", records)
+    }
+    if err := j.Append([]byte("third")); err != nil {
+        t.Fatalf("append: %v", err)
+    }
+    j.Close()
+    if _, records, _ = Open(path, "pwd"); len(records) != 3 {
+        t.Fatalf("expected three records, got %q", records)
+    }
+}
+
+func TestJournal_DropsTornTail(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "journal")
+    j, _, _ := Open(path, "pwd")
+    _ = j.Append([]byte("complete"))
+    _ = j.Append([]byte("torn"))
+    j.Close()
+
+    info, _ := os.Stat(path)
+    if err := os.Truncate(path, info.Size()-5); err != nil {
+        t.Fatalf("truncate: %v", err)
+    }
+    j, records, err := Open(path, "pwd")
+    if err != nil {
+        t.Fatalf("open: %v", err)
+    }
+    if len(records) != 1 || string(records[0]) != "complete" {
+        t.Fatalf("unexpected records: %q", records)
+    }
+    // the torn record is cut off so new records follow the complete ones
+    _ = j.Append([]byte("after"))
+    j.Close()
+    if _, records, _ = Open(path, "pwd"); len(records) != 2 || string(records[1]) != "after" {
+        t.Fatalf("unexpected records after append: %q", records)
+    }
+}
+
+func TestJournal_WrongPassword(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "journal")
+    j, _, _ := Open(path, "right")
+    _ = j.Append([]byte("secret"))
+    j.Close()
+    if _, _, err := Open(path, "wrong"); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword, got %v", err)
+    }
+}
+
+func TestJournal_Keyfiles(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "journal")
+    j, _, _ := Open(path, "right", []byte("keyfile hash"))
+    _ = j.Append([]byte("secret"))
+    j.Close()
+    if _, _, err := Open(path, "right"); !errors.Is(err, ErrWrongPassword) {
+        t.Fatalf("expected ErrWrongPassword without the keyfile, got %v", err)
+    }
+    if _, records, err := Open(path, "right", []byte("keyfile hash")); err != nil || len(records) != 1 {
+        t.Fatalf("expected the record with the keyfile, got %q (%v)", records, err)
+    }
+}
+
+func TestJournal_Errors(t *testing.T) {
+    if _, _, err := Open(filepath.Join(t.TempDir(), "missing", "journal"), "pwd"); err == nil {
+        t.Fatalf("expected error for missing directory")
+    }
+
+    j, _, err := Open(filepath.Join(t.TempDir(), "journal"), "pwd")
+    if err != nil {
+        t.Fatalf("open: %v", err)
+    }
+    defer j.Close()
+    oldEnc, oldSync := encryptFn, syncFn
+    t.Cleanup(func() { encryptFn, syncFn = oldEnc, oldSync })
+    encryptFn = func([]byte, string, ...[]byte) ([]byte, error) { return nil, errors.New("enc") }
+    if err := j.Append([]byte("x")); err == nil {
+        t.Fatalf("expected encryption error")
+    }
+    encryptFn = oldEnc
+    syncFn = func(*os.File) error { return errors.New("sync") }
+    if err := j.Append([]byte("x")); err == nil {
+        t.Fatalf("expected sync error")
+    }
+}
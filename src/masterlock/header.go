@@ -0,0 +1,68 @@
+package masterlock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// headerMagic starts the masterlocks which record the factors unlocking them. Masterlocks
+// protected by a password alone are stored as the bare ciphertext, like all versions wrote them,
+// which starts with a random nonce and can't be mistaken for it.
+var headerMagic = []byte("TCMLOCK\x00")
+
+// headerVersion is the layout of the header following the magic: the version, a flags byte and
+// the number of keyfiles
+const headerVersion = 1
+
+// flagPassword is set in the flags byte when a password is required
+const flagPassword = 1
+
+// ErrUnknownHeader is returned for masterlock headers written by a later version
+var ErrUnknownHeader = errors.New("unknown masterlock header")
+
+// Factors are what unlocking a masterlock requires
+type Factors struct {
+	// Password is set when a password is required
+	Password bool
+	// Keyfiles is the number of keyfiles required
+	Keyfiles int
+}
+
+// AddHeader prefixes the encrypted masterlock with a header recording the factors it's
+// encrypted with. Without keyfiles it's returned unchanged.
+func AddHeader(factors Factors, encrypted []byte) ([]byte, error) {
+	if factors.Keyfiles == 0 {
+		if !factors.Password {
+			return nil, fmt.Errorf("a masterlock needs a password or keyfiles")
+		}
+		return encrypted, nil
+	}
+	if factors.Keyfiles > 255 {
+		return nil, fmt.Errorf("at most 255 keyfiles are supported, got %d", factors.Keyfiles)
+	}
+	var flags byte
+	if factors.Password {
+		flags |= flagPassword
+	}
+	data := append([]byte{}, headerMagic...)
+	data = append(data, headerVersion, flags, byte(factors.Keyfiles))
+	return append(data, encrypted...), nil
+}
+
+// SplitHeader returns the factors recorded in the header of a masterlock read from disk and the
+// encrypted masterlock following it. Masterlocks without a header require a password only.
+func SplitHeader(data []byte) (Factors, []byte, error) {
+	if !bytes.HasPrefix(data, headerMagic) {
+		return Factors{Password: true}, data, nil
+	}
+	header := data[len(headerMagic):]
+	if len(header) < 3 {
+		return Factors{}, nil, fmt.Errorf("masterlock header is truncated")
+	}
+	if header[0] != headerVersion {
+		return Factors{}, nil, fmt.Errorf("%w version %d", ErrUnknownHeader, header[0])
+	}
+	factors := Factors{Password: header[1]&flagPassword != 0, Keyfiles: int(header[2])}
+	return factors, header[3:], nil
+}
@@ -0,0 +1,49 @@
+package masterlock
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+)
+
+func TestHeader_RoundTrip(t *testing.T) {
+    encrypted := []byte("ciphertext")
+    for _, factors := range []Factors{{Password: true, Keyfiles: 2}, {Keyfiles: 1}} {
+        data, err := AddHeader(factors, encrypted)
+        if err != nil {
+            t.Fatalf("AddHeader: %v", err)
+        }
+        got, rest, err := SplitHeader(data)
+        if err != nil || got != factors || !bytes.Equal(rest, encrypted) {
+            t.Fatalf("expected %+v and the ciphertext back, got %+v %q (%v)", factors, got, rest, err)
+        }
+    }
+}
+
+func TestHeader_PasswordOnlyStaysBare(t *testing.T) {
+    encrypted := []byte("ciphertext")
+    data, err := AddHeader(Factors{Password: true}, encrypted)
+    if err != nil || !bytes.Equal(data, encrypted) {
+        t.Fatalf("expected a password-only masterlock without header, got %q (%v)", data, err)
+    }
+    // masterlocks of earlier versions need the password only
+    got, rest, err := SplitHeader(encrypted)
+    if err != nil || got != (Factors{Password: true}) || !bytes.Equal(rest, encrypted) {
+        t.Fatalf("expected a bare masterlock to need a password, got %+v %q (%v)", got, rest, err)
+    }
+}
+
+func TestHeader_Invalid(t *testing.T) {
+    if _, err := AddHeader(Factors{}, nil); err == nil {
+        t.Fatalf("expected a masterlock without any factor to be refused")
+    }
+    if _, err := AddHeader(Factors{Keyfiles: 256}, nil); err == nil {
+        t.Fatalf("expected too many keyfiles to be refused")
+    }
+    if _, _, err := SplitHeader(append(append([]byte{}, headerMagic...), 2, 0, 1)); !errors.Is(err, ErrUnknownHeader) {
+        t.Fatalf("expected ErrUnknownHeader for a later header version, got %v", err)
+    }
+    if _, _, err := SplitHeader(append(append([]byte{}, headerMagic...), 1)); err == nil {
+        t.Fatalf("expected a truncated header to be refused")
+    }
+}
@@ -11,20 +11,29 @@ type PartInfo struct {
 	Key      string `json:"key"`
 }
 
+// ArchiveInfo records how the hidden data was packed so unhide can unpack it again.
+// Empty fields mean the defaults of masterlocks written before they existed.
+type ArchiveInfo struct {
+    Format      string `json:"format,omitempty"`
+    Compression string `json:"compression,omitempty"`
+}
+
 type MasterLock struct {
-    Parts        []PartInfo `json:"parts"`
-    FrontPadding int        `json:"front_padding"`
-    BackPadding  int        `json:"padding"`
+    Parts        []PartInfo  `json:"parts"`
+    FrontPadding int         `json:"front_padding"`
+    BackPadding  int         `json:"padding"`
+    Archive      ArchiveInfo `json:"archive"`
 }
 
 // test hook for unit testing error paths; defaults to json.Marshal
 var jsonMarshalFn = json.Marshal
 
-func CreateMasterLock(parts []PartInfo, frontPadding int, backPadding int) ([]byte, error) {
+func CreateMasterLock(parts []PartInfo, frontPadding int, backPadding int, archive ArchiveInfo) ([]byte, error) {
     masterLock := MasterLock{
         Parts:        parts,
         FrontPadding: frontPadding,
         BackPadding:  backPadding,
+        Archive:      archive,
     }
 
     data, err := jsonMarshalFn(masterLock)
@@ -12,7 +12,7 @@ func TestCreateMasterLock_MarshalError(t *testing.T) {
     t.Cleanup(func() { jsonMarshalFn = old })
 
     parts := []PartInfo{{Index: 0, Filename: "a", Key: "k"}}
-    if _, err := CreateMasterLock(parts, 1, 2); err == nil {
+    if _, err := CreateMasterLock(parts, 1, 2, ArchiveInfo{}); err == nil {
         t.Fatalf("expected error from json marshal hook")
     }
 }
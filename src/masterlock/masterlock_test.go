@@ -14,7 +14,7 @@ func TestCreateMasterLock_JSONRoundTrip(t *testing.T) {
     front := 123
     back := 7
 
-    data, err := CreateMasterLock(parts, front, back)
+    data, err := CreateMasterLock(parts, front, back, ArchiveInfo{Compression: "zstd"})
     if err != nil {
         t.Fatalf("CreateMasterLock error: %v", err)
     }
@@ -39,10 +39,13 @@ func TestCreateMasterLock_JSONRoundTrip(t *testing.T) {
     if ml.BackPadding != back { // JSON tag is "padding"
         t.Fatalf("BackPadding mismatch: got %d want %d", ml.BackPadding, back)
     }
+    if ml.Archive.Compression != "zstd" {
+        t.Fatalf("Archive.Compression mismatch: got %q", ml.Archive.Compression)
+    }
 }
 
 func TestCreateMasterLock_EmptyParts(t *testing.T) {
-    data, err := CreateMasterLock(nil, 0, 0)
+    data, err := CreateMasterLock(nil, 0, 0, ArchiveInfo{})
     if err != nil {
         t.Fatalf("CreateMasterLock error: %v", err)
     }
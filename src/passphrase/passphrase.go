@@ -0,0 +1,66 @@
+package passphrase
+
+import (
+	crand "crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DefaultWords is the number of words of generated passphrases unless asked otherwise, about
+// 77 bits of entropy
+const DefaultWords = 6
+
+// eff_large_wordlist.txt is the EFF large wordlist for diceware, 7776 words each indexed by
+// five dice rolls. https://www.eff.org/dice, licensed CC BY 3.0 US.
+//
+//go:embed eff_large_wordlist.txt
+var wordlist string
+
+// words are the words of the wordlist without their dice rolls
+var words = func() []string {
+	var words []string
+	for _, line := range strings.Split(strings.TrimSpace(wordlist), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			words = append(words, fields[1])
+		}
+	}
+	return words
+}()
+
+// test hook for drawing random numbers; defaults to crypto/rand
+var randIntFn = crand.Int
+
+// Generate returns a passphrase of n words drawn uniformly and independently from the wordlist
+// with crypto/rand, separated by spaces
+func Generate(n int) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("a passphrase needs at least one word, got %d", n)
+	}
+	picked := make([]string, n)
+	for i := range picked {
+		index, err := randIntFn(crand.Reader, big.NewInt(int64(len(words))))
+		if err != nil {
+			return "", fmt.Errorf("error drawing a random word: %w", err)
+		}
+		picked[i] = words[index.Int64()]
+	}
+	return strings.Join(picked, " "), nil
+}
+
+// Entropy returns the entropy in bits of a generated passphrase of n words
+func Entropy(n int) float64 {
+	return float64(n) * math.Log2(float64(len(words)))
+}
+
+// Words returns a copy of the words of the wordlist, in the order of their dice rolls
+func Words() []string {
+	return append([]string(nil), words...)
+}
+
+// Size returns the number of words in the wordlist
+func Size() int {
+	return len(words)
+}
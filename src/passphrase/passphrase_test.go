@@ -0,0 +1,68 @@
+package passphrase
+
+import (
+    "errors"
+    "io"
+    "math/big"
+    "strings"
+    "testing"
+)
+
+func TestWordlist(t *testing.T) {
+    if Size() != 7776 {
+        t.Fatalf("expected the 7776 words of the EFF large wordlist, got %d", Size())
+    }
+    seen := map[string]bool{}
+    for _, word := range words {
+        if seen[word] || strings.ContainsAny(word, " \t") {
+            t.Fatalf("duplicate or invalid word %q", word)
+        }
+        seen[word] = true
+    }
+}
+
+func TestGenerate(t *testing.T) {
+    phrase, err := Generate(DefaultWords)
+    if err != nil {
+        t.Fatalf("Generate: %v", err)
+    }
+    picked := strings.Split(phrase, " ")
+    if len(picked) != DefaultWords {
+        t.Fatalf("expected %d words, got %q", DefaultWords, phrase)
+    }
+    other, _ := Generate(DefaultWords)
+    if other == phrase {
+        t.Fatalf("expected different passphrases, got %q twice", phrase)
+    }
+    if _, err := Generate(0); err == nil {
+        t.Fatalf("expected a passphrase without words to be refused")
+    }
+}
+
+func TestGenerate_UsesRandomIndexes(t *testing.T) {
+    old := randIntFn
+    next := int64(0)
+    randIntFn = func(r io.Reader, max *big.Int) (*big.Int, error) {
+        if max.Int64() != 7776 {
+            t.Fatalf("expected to draw from all words, got max %v", max)
+        }
+        next++
+        return big.NewInt(next * 1000), nil
+    }
+    t.Cleanup(func() { randIntFn = old })
+    phrase, err := Generate(2)
+    if err != nil || phrase != words[1000]+" "+words[2000] {
+        t.Fatalf("unexpected passphrase %q (%v)", phrase, err)
+    }
+
+    randIntFn = func(io.Reader, *big.Int) (*big.Int, error) { return nil, errors.New("no entropy") }
+    if _, err := Generate(2); err == nil {
+        t.Fatalf("expected the random source failure to be returned")
+    }
+}
+
+func TestEntropy(t *testing.T) {
+    if got := Entropy(6); got < 77.5 || got > 77.6 {
+        t.Fatalf("expected about 77.5 bits for six words, got %.2f", got)
+    }
+}
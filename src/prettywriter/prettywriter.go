@@ -2,9 +2,111 @@ package prettywriter
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+
+	"golang.org/x/term"
+)
+
+// DefaultWidth is the width of boxes when not writing to a terminal
+const DefaultWidth = 40
+
+// minWidth keeps boxes readable in very narrow terminals
+const minWidth = 20
+
+// test hooks for unit testing; default to real implementations
+var (
+	isTerminalFn = term.IsTerminal
+	getSizeFn    = term.GetSize
+	getenvFn     = os.Getenv
 )
 
+// output is where everything gets written to, nil meaning stdout
+var output io.Writer
+
+// quiet drops all output while set, see SetQuiet
+var quiet bool
+
+// SetOutput redirects all output, e.g. to stderr when stdout carries data. nil restores stdout.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Output returns the writer all output currently goes to
+func Output() io.Writer {
+	if output == nil {
+		return os.Stdout
+	}
+	return output
+}
+
+// SetQuiet drops everything written while set. Errors and prompts the user has to see lift it
+// again, see utils.ExitErrorCode.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
+// Quiet reports whether output is dropped
+func Quiet() bool {
+	return quiet
+}
+
+// writer returns where the next write goes, nowhere while quiet
+func writer() io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return Output()
+}
+
+// Terminal reports whether the output is written to a terminal rather than e.g. a pipe or a log file
+func Terminal() bool {
+	f, ok := Output().(*os.File)
+	return ok && isTerminalFn(int(f.Fd()))
+}
+
+// Colored reports whether colors are written. They are on a terminal unless the NO_COLOR
+// environment variable is set to anything but an empty string, see https://no-color.org.
+func Colored() bool {
+	return getenvFn("NO_COLOR") == "" && Terminal()
+}
+
+// Width returns the width boxes fill, that's the width of the terminal written to without the
+// corners of the box, or DefaultWidth when the output is no terminal
+func Width() int {
+	f, ok := Output().(*os.File)
+	if !ok || !isTerminalFn(int(f.Fd())) {
+		return DefaultWidth
+	}
+	width, _, err := getSizeFn(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return DefaultWidth
+	}
+	if width-2 < minWidth {
+		return minWidth
+	}
+	return width - 2
+}
+
+// Print writes the operands without any formatting, like fmt.Print
+func Print(a ...interface{}) {
+	fmt.Fprint(writer(), a...)
+}
+
+// Println writes the operands without any formatting followed by a newline, like fmt.Println
+func Println(a ...interface{}) {
+	fmt.Fprintln(writer(), a...)
+}
+
+// colorize wraps message in the escape codes of the colors, if colors are written at all
+func colorize(message string, foregroundColor, backgroundColor Color) string {
+	if !Colored() {
+		return message
+	}
+	return fmt.Sprint("\033[", int(foregroundColor), ";", int(backgroundColor), "m", message, "\033[0m")
+}
+
 // Color represents the available colors for text formatting.
 type Color int
 
@@ -33,24 +135,24 @@ const (
 
 // Write writes the given message to the console with the specified foreground and background colors.
 func Write(message string, foregroundColor, backgroundColor Color) {
-	fmt.Print("\033[", int(foregroundColor), ";", int(backgroundColor), "m", message, "\033[0m")
+	fmt.Fprint(writer(), colorize(message, foregroundColor, backgroundColor))
 }
 
 // Writeln writes the given message to the console with the specified foreground and background colors, followed by a newline.
 func Writeln(message string, foregroundColor, backgroundColor Color) {
 	Write(message, foregroundColor, backgroundColor)
-	fmt.Println()
+	Println()
 }
 
 // Writef writes the formatted message to the console with the specified foreground and background colors.
 func Writef(format string, foregroundColor, backgroundColor Color, a ...interface{}) {
-	fmt.Printf(fmt.Sprint("\033[", int(foregroundColor), ";", int(backgroundColor), "m", format, "\033[0m"), a...)
+	fmt.Fprintf(writer(), colorize(format, foregroundColor, backgroundColor), a...)
 }
 
 // Writefln writes the formatted message to the console with the specified foreground and background colors, followed by a newline.
 func Writefln(format string, foregroundColor, backgroundColor Color, a ...interface{}) {
 	Writef(format, foregroundColor, backgroundColor, a...)
-	fmt.Println()
+	Println()
 }
 
 // Style represents the border style of the box.
@@ -61,7 +163,7 @@ const (
 	DoubleLine
 )
 
-// WriteInBox prints the message in a box format within the provided shell width.
+// WriteInBox prints the message in a box format within the provided shell width, usually Width().
 func WriteInBox(shellWidth int, message string, foregroundColor, backgroundColor Color, style Style) {
 	lines := wrapMessage(message, shellWidth-4) // Account for box corners and padding
 
@@ -70,19 +172,23 @@ func WriteInBox(shellWidth int, message string, foregroundColor, backgroundColor
 	bottomBorder := "└" + getBorder(shellWidth, style) + "┘"
 
 	// Print top border
-	fmt.Println(topBorder)
+	Println(topBorder)
 
 	// Iterate through wrapped lines
 	for _, line := range lines {
 		// Calculate padding based on line length
-		padding := strings.Repeat(" ", shellWidth-len(line)-3) // Account for corners, spaces
+		// Words longer than the box overflow it instead of being cut
+		padding := ""
+		if n := shellWidth - len(line) - 3; n > 0 { // Account for corners, spaces
+			padding = strings.Repeat(" ", n)
+		}
 
 		// Print line with color and padding
-		fmt.Printf("|\033[%d;%dm %s %s \033[0m|\n", int(foregroundColor), int(backgroundColor), line, padding)
+		fmt.Fprint(writer(), "|", colorize(" "+line+" "+padding+" ", foregroundColor, backgroundColor), "|\n")
 	}
 
 	// Print bottom border
-	fmt.Println(bottomBorder)
+	Println(bottomBorder)
 }
 
 // wrapMessage splits the message into lines based on the provided width, breaking at spaces.
@@ -80,7 +80,18 @@ func TestGetBorder_SingleVsDouble(t *testing.T) {
     }
 }
 
+// fakeTerminal makes every output a terminal of the given width and clears NO_COLOR
+func fakeTerminal(t *testing.T, width int) {
+    t.Helper()
+    oldTerm, oldSize, oldEnv := isTerminalFn, getSizeFn, getenvFn
+    isTerminalFn = func(int) bool { return true }
+    getSizeFn = func(int) (int, int, error) { return width, 24, nil }
+    getenvFn = func(string) string { return "" }
+    t.Cleanup(func() { isTerminalFn, getSizeFn, getenvFn = oldTerm, oldSize, oldEnv })
+}
+
 func TestWriteAndWriteln_Output(t *testing.T) {
+    fakeTerminal(t, 80)
     out := captureOutput(t, func() {
         Write("hello", Green, BlackBG)
     })
@@ -150,3 +161,116 @@ func TestWritefAndWritefln(t *testing.T) {
         t.Fatalf("Writefln should end with newline")
     }
 }
+
+func TestSetOutput_Redirects(t *testing.T) {
+    var buf bytes.Buffer
+    SetOutput(&buf)
+    t.Cleanup(func() { SetOutput(nil) })
+
+    stdout := captureOutput(t, func() {
+        Writeln("to the side", Green, BlackBG)
+        WriteInBox(20, "boxed", Green, BlackBG, SingleLine)
+        Print("\r")
+        Println("plain")
+    })
+    if stdout != "" {
+        t.Fatalf("expected nothing on stdout, got %q", stdout)
+    }
+    for _, want := range []string{"to the side", "boxed", "\rplain\n"} {
+        if !strings.Contains(buf.String(), want) {
+            t.Fatalf("expected %q in redirected output %q", want, buf.String())
+        }
+    }
+
+    SetOutput(nil)
+    if Output() != os.Stdout {
+        t.Fatalf("expected nil to restore stdout")
+    }
+}
+
+func TestWrite_PlainWithoutTerminal(t *testing.T) {
+    out := captureOutput(t, func() {
+        Writeln("piped", Green, BlackBG)
+        Writef("n=%d", Red, BlackBG, 1)
+        WriteInBox(20, "boxed", Green, BlackBG, SingleLine)
+    })
+    if strings.Contains(out, "\x1b") {
+        t.Fatalf("expected no escape codes writing to a pipe: %q", out)
+    }
+    for _, want := range []string{"piped\n", "n=1", "| boxed "} {
+        if !strings.Contains(out, want) {
+            t.Fatalf("expected %q in %q", want, out)
+        }
+    }
+}
+
+func TestWrite_NoColor(t *testing.T) {
+    fakeTerminal(t, 80)
+    getenvFn = func(key string) string {
+        if key == "NO_COLOR" {
+            return "1"
+        }
+        return ""
+    }
+    out := captureOutput(t, func() {
+        Write("hello", Green, BlackBG)
+    })
+    if out != "hello" {
+        t.Fatalf("expected plain output with NO_COLOR, got %q", out)
+    }
+}
+
+func TestSetQuiet_DropsOutput(t *testing.T) {
+    SetQuiet(true)
+    t.Cleanup(func() { SetQuiet(false) })
+    out := captureOutput(t, func() {
+        Writeln("hidden", Green, BlackBG)
+        WriteInBox(20, "hidden", Green, BlackBG, SingleLine)
+        Println("hidden")
+    })
+    if out != "" {
+        t.Fatalf("expected nothing while quiet, got %q", out)
+    }
+    SetQuiet(false)
+    out = captureOutput(t, func() {
+        Println("shown")
+    })
+    if out != "shown\n" {
+        t.Fatalf("expected output once quiet is lifted, got %q", out)
+    }
+}
+
+func TestWidth(t *testing.T) {
+    if w := captureWidth(t); w != DefaultWidth {
+        t.Fatalf("expected %d without a terminal, got %d", DefaultWidth, w)
+    }
+    fakeTerminal(t, 100)
+    if w := captureWidth(t); w != 98 {
+        t.Fatalf("expected the terminal width without the corners, got %d", w)
+    }
+    fakeTerminal(t, 10)
+    if w := captureWidth(t); w != minWidth {
+        t.Fatalf("expected at least %d in a narrow terminal, got %d", minWidth, w)
+    }
+
+    // a box of the width fills the terminal, long words overflow it
+    fakeTerminal(t, 30)
+    out := captureOutput(t, func() {
+        WriteInBox(Width(), "short "+strings.Repeat("x", 40), Green, BlackBG, SingleLine)
+    })
+    lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+    if n := len([]rune(lines[0])); n != 30 {
+        t.Fatalf("expected the border to span 30 columns, got %d: %q", n, lines[0])
+    }
+    if !strings.Contains(out, strings.Repeat("x", 40)) {
+        t.Fatalf("expected the long word in the box: %q", out)
+    }
+}
+
+// captureWidth returns Width() while stdout is captured
+func captureWidth(t *testing.T) int {
+    t.Helper()
+    var w int
+    captureOutput(t, func() { w = Width() })
+    return w
+}
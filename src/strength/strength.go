@@ -0,0 +1,333 @@
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/voodooEntity/go-tachicrypt/src/passphrase"
+)
+
+// Recommended is the entropy in bits a masterlock password should at least have. The masterlock
+// key is derived with a single hash, so guessing is cheap and only entropy protects it.
+const Recommended = 60
+
+// maxAnalyzed is the number of characters searched for patterns, the rest counts as bruteforce
+const maxAnalyzed = 100
+
+// common.txt lists common passwords and words, most common first
+//
+//go:embed common.txt
+var commonList string
+
+// ranks maps the entries of common.txt to their rank, 1 being the most common
+var ranks = func() map[string]int {
+	ranks := map[string]int{}
+	for i, word := range strings.Fields(commonList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// dictionary are the English words of the EFF diceware list. They aren't ranked, a diceware
+// passphrase draws each of them with the same chance, so every word costs wordBits.
+var dictionary = func() map[string]bool {
+	dictionary := map[string]bool{}
+	for _, word := range passphrase.Words() {
+		dictionary[word] = true
+	}
+	return dictionary
+}()
+
+var wordBits = math.Log2(float64(passphrase.Size()))
+
+// separators are the characters passphrases commonly put between their words
+const separators = " -_.,+"
+
+// keyboardRows are walked by keyboard patterns like qwerty or asdf, for qwerty, qwertz and azerty
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "qwertzuiop", "yxcvbnm", "azertyuiop", "qsdfghjklm", "wxcvbn"}
+
+// leet maps the substitutions of l33t speak back to the letters they replace
+var leet = map[rune]rune{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'}
+
+// Entropy estimates the entropy of password in bits, the log2 of the guesses an attacker trying
+// likely passwords first would need. Like zxcvbn it looks for the cheapest way to make up the
+// password from common passwords and English words (also reversed, capitalized or in l33t
+// speak), keyboard walks, sequences, repeats and years, counting everything else as
+// bruteforce. Words joined by a separator are scored as a passphrase.
+func Entropy(password string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+	perChar := math.Log2(float64(cardinality(runes)))
+	rest := 0.0
+	if len(runes) > maxAnalyzed {
+		rest = float64(len(runes)-maxAnalyzed) * perChar
+		runes = runes[:maxAnalyzed]
+	}
+	bits := cheapest(runes, perChar)
+	if phrase, ok := passphraseBits(runes, perChar); ok && phrase < bits {
+		bits = phrase
+	}
+	return bits + rest
+}
+
+// passphraseBits matches words split by the same separator throughout, like diceware
+// passphrases: every word costs what it costs alone, plus picking the separator once
+func passphraseBits(runes []rune, perChar float64) (float64, bool) {
+	best, found := math.Inf(1), false
+	for _, sep := range separators {
+		parts := strings.Split(string(runes), string(sep))
+		if len(parts) < 2 {
+			continue
+		}
+		bits := math.Log2(float64(len(separators)))
+		for _, part := range parts {
+			if part == "" {
+				bits = math.Inf(1)
+				break
+			}
+			bits += cheapest([]rune(part), perChar)
+		}
+		if bits < best {
+			best, found = bits, true
+		}
+	}
+	return best, found
+}
+
+// Describe names how strong a password with the given entropy is
+func Describe(bits float64) string {
+	switch {
+	case bits < 28:
+		return "very weak"
+	case bits < 36:
+		return "weak"
+	case bits < Recommended:
+		return "fair"
+	case bits < 80:
+		return "strong"
+	}
+	return "very strong"
+}
+
+// cheapest returns the bits of the cheapest way to make up runes from patterns and bruteforce
+func cheapest(runes []rune, perChar float64) float64 {
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = math.Inf(1)
+		for j := 0; j < i; j++ {
+			if bits := best[j] + segmentBits(runes[j:i], perChar); bits < best[i] {
+				best[i] = bits
+			}
+		}
+	}
+	return best[len(runes)]
+}
+
+// segmentBits returns the bits of the cheapest pattern matching all of seg
+func segmentBits(seg []rune, perChar float64) float64 {
+	bits := float64(len(seg)) * perChar
+	if len(seg) < 2 {
+		return bits
+	}
+	for _, match := range []func([]rune, float64) (float64, bool){dictionaryBits, sequenceBits, keyboardBits, repeatBits, yearBits} {
+		if b, ok := match(seg, perChar); ok && b < bits {
+			bits = b
+		}
+	}
+	return bits
+}
+
+// dictionaryBits matches common passwords and English words
+func dictionaryBits(seg []rune, _ float64) (float64, bool) {
+	if len(seg) < 3 {
+		return 0, false
+	}
+	lower := strings.ToLower(string(seg))
+	variations := caseBits(seg)
+	best, found := math.Inf(1), false
+	try := func(word string, extra float64) {
+		if rank, ok := ranks[word]; ok {
+			found = true
+			best = math.Min(best, math.Log2(float64(rank))+variations+extra)
+		}
+		if dictionary[word] {
+			found = true
+			best = math.Min(best, wordBits+variations+extra)
+		}
+	}
+	try(lower, 0)
+	try(reverse(lower), 1)
+	if plain := unleet(lower); plain != lower {
+		try(plain, 1)
+		try(reverse(plain), 2)
+	}
+	return best, found
+}
+
+// sequenceBits matches runs like abc, 13579 or zyx
+func sequenceBits(seg []rune, _ float64) (float64, bool) {
+	if len(seg) < 3 {
+		return 0, false
+	}
+	delta := seg[1] - seg[0]
+	if delta == 0 || delta < -2 || delta > 2 {
+		return 0, false
+	}
+	for i := 2; i < len(seg); i++ {
+		if seg[i]-seg[i-1] != delta {
+			return 0, false
+		}
+	}
+	starts := 26.0
+	switch {
+	case strings.ContainsRune("aAzZ019", seg[0]):
+		starts = 4
+	case unicode.IsDigit(seg[0]):
+		starts = 10
+	}
+	bits := math.Log2(starts) + math.Log2(float64(len(seg)))
+	if delta < 0 {
+		bits++
+	}
+	return bits, true
+}
+
+// keyboardBits matches walks along a keyboard row like qwerty or lkjh
+func keyboardBits(seg []rune, _ float64) (float64, bool) {
+	if len(seg) < 3 {
+		return 0, false
+	}
+	lower := strings.ToLower(string(seg))
+	for _, row := range keyboardRows {
+		for _, walk := range []struct {
+			row   string
+			extra float64
+		}{{row, 0}, {reverse(row), 1}} {
+			if strings.Contains(walk.row, lower) {
+				return math.Log2(float64(len(keyboardRows)*10)) + math.Log2(float64(len(seg))) + caseBits(seg) + walk.extra, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// repeatBits matches a shorter unit repeated, like aaaa or abcabc
+func repeatBits(seg []rune, perChar float64) (float64, bool) {
+	for unit := 1; unit <= len(seg)/2; unit++ {
+		if len(seg)%unit != 0 {
+			continue
+		}
+		repeated := true
+		for i := unit; i < len(seg) && repeated; i++ {
+			repeated = seg[i] == seg[i-unit]
+		}
+		if repeated {
+			return cheapest(seg[:unit], perChar) + math.Log2(float64(len(seg)/unit)), true
+		}
+	}
+	return 0, false
+}
+
+// yearBits matches years from 1900 to 2039
+func yearBits(seg []rune, _ float64) (float64, bool) {
+	if len(seg) != 4 {
+		return 0, false
+	}
+	year := 0
+	for _, r := range seg {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+		year = year*10 + int(r-'0')
+	}
+	if year < 1900 || year > 2039 {
+		return 0, false
+	}
+	return math.Log2(140), true
+}
+
+// cardinality returns the size of the alphabet a bruteforce attack on runes has to try
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < 128:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			n += class.size
+		}
+	}
+	return n
+}
+
+// caseBits returns the bits capitalization adds to a word: none when lowercase, one when only
+// the first letter or all letters are uppercase, else the ways to pick its uppercase letters
+func caseBits(seg []rune) float64 {
+	var upper, lower int
+	for _, r := range seg {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0 || (upper == 1 && unicode.IsUpper(seg[0])):
+		return 1
+	}
+	ways := 0.0
+	for k := 1; k <= upper && k <= lower; k++ {
+		ways += binomial(upper+lower, k)
+	}
+	return math.Log2(ways)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// unleet replaces the l33t substitutions in s with the letters they stand for
+func unleet(s string) string {
+	return strings.Map(func(r rune) rune {
+		if letter, ok := leet[r]; ok {
+			return letter
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,113 @@
+package strength
+
+import (
+    "math"
+    "strings"
+    "testing"
+
+    "github.com/voodooEntity/go-tachicrypt/src/passphrase"
+)
+
+func TestEntropy_FindsPatterns(t *testing.T) {
+    tests := []struct {
+        password string
+        max      float64
+    }{
+        {"password", 2},
+        {"P@ssw0rd", 4},
+        {"drowssap", 3},
+        {"123456", 1},
+        {"qwertyuiop", 5},
+        {"LKJHGFDSA", 8},
+        {"abcdefgh", 5},
+        {"97531", 6},
+        {"aaaaaaaaaaaa", 9},
+        {"abcabcabcabc", 7},
+        {"summer2019", 15},
+    }
+    for _, tt := range tests {
+        if got := Entropy(tt.password); got > tt.max {
+            t.Fatalf("expected %q to be found weak (at most %.0f bits), got %.1f", tt.password, tt.max, got)
+        }
+    }
+}
+
+func TestEntropy_RandomLooksStrong(t *testing.T) {
+    tests := []struct {
+        password string
+        min      float64
+    }{
+        {"kT9#vQ2!mZ", 60},
+        {"x7Rq-w2Lp-9Zt4-Hm3c", 100},
+    }
+    for _, tt := range tests {
+        if got := Entropy(tt.password); got < tt.min {
+            t.Fatalf("expected %q to be strong (at least %.0f bits), got %.1f", tt.password, tt.min, got)
+        }
+    }
+}
+
+func TestEntropy_EdgeCases(t *testing.T) {
+    if got := Entropy(""); got != 0 {
+        t.Fatalf("expected no entropy for an empty password, got %.1f", got)
+    }
+    // a capitalized word costs a bit more than the plain one
+    if Entropy("Dragon") <= Entropy("dragon") {
+        t.Fatalf("expected capitalization to add entropy")
+    }
+    // long passwords are only analyzed in part, the rest counts fully
+    long := strings.Repeat("zQ8!", 50)
+    if got := Entropy(long); got < Entropy(long[:maxAnalyzed]) {
+        t.Fatalf("expected the unanalyzed rest to add entropy, got %.1f", got)
+    }
+}
+
+func TestDescribe(t *testing.T) {
+    if Describe(10) != "very weak" || Describe(Recommended-1) != "fair" || Describe(Recommended) != "strong" || Describe(120) != "very strong" {
+        t.Fatalf("unexpected descriptions")
+    }
+}
+
+func TestEntropy_WordBased(t *testing.T) {
+    word := math.Log2(float64(passphrase.Size()))
+    sep := math.Log2(float64(len(separators)))
+    tests := []struct {
+        password string
+        want     float64
+    }{
+        // every diceware word costs the same, the separator is picked once
+        {"gumdrop unplug stuffed sushi anthem", 5*word + sep},
+        {"Gumdrop-Unplug-Stuffed-Sushi", 4*word + 4 + sep},
+        {"gumdropunplugstuffed", 3 * word},
+        // horse isn't a diceware word and counts as bruteforce
+        {"correct horse battery staple", 3*word + 5*math.Log2(26+33) + sep},
+    }
+    for _, tt := range tests {
+        if got := Entropy(tt.password); math.Abs(got-tt.want) > 0.01 {
+            t.Fatalf("expected %q to be estimated at %.1f bits, got %.1f", tt.password, tt.want, got)
+        }
+    }
+
+    // diceware passphrases are estimated at their real entropy, unless a word happens to be a
+    // common password as well
+    words := passphrase.Words()
+    for i := 0; i+6 < len(words); i += 997 {
+        phrase := strings.Join(words[i:i+6], " ")
+        common := false
+        for _, w := range words[i : i+6] {
+            _, ok := ranks[w]
+            common = common || ok
+        }
+        if common {
+            continue
+        }
+        want := passphrase.Entropy(6) + sep
+        if got := Entropy(phrase); math.Abs(got-want) > 0.01 {
+            t.Fatalf("expected %q to be estimated at %.1f bits, got %.1f", phrase, want, got)
+        }
+    }
+
+    if got := Entropy("Summer2024!"); got >= 28 {
+        t.Fatalf("expected a capitalized common word and a year to be very weak, got %.1f", got)
+    }
+}
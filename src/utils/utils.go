@@ -15,6 +15,8 @@ var (
     randReadFn      = crand.Read
     randIntFn       = crand.Int
     readPasswordFn  = term.ReadPassword
+    isTerminalFn    = term.IsTerminal
+    openTTYFn       = func() (*os.File, error) { return os.OpenFile("/dev/tty", os.O_RDWR, 0) }
 )
 
 var TachiHeading = `
@@ -36,40 +38,77 @@ func GenerateRandomFilename() (string, error) {
     return fmt.Sprintf("%x", filename), nil
 }
 
-func PromptForPassword(prompt string) string {
-    prettywriter.WriteInBox(40, prompt, prettywriter.BlackBG, prettywriter.Green, prettywriter.DoubleLine)
-    password, err := readPasswordFn(int(syscall.Stdin))
-    if err != nil {
-        prettywriter.Writeln(fmt.Sprintf("\nError reading password: %+v", err), prettywriter.Red, prettywriter.BlackBG)
-        fmt.Sprintf("\nError reading password: %+v", err)
-        return PromptForPassword(prompt)
-    }
-	if string(password) == "" {
-		prettywriter.Writeln("Error: Invalid empty password.", prettywriter.Red, prettywriter.BlackBG)
-		return PromptForPassword(prompt)
+// PasswordAttempts is how often a password is asked for before giving up
+const PasswordAttempts = 3
+
+// PromptForPassword reads a password from the terminal. Unreadable or empty input is asked
+// for again, up to PasswordAttempts times.
+func PromptForPassword(prompt string) (string, error) {
+	for attempt := 1; ; attempt++ {
+		prettywriter.WriteInBox(prettywriter.Width(), prompt, prettywriter.BlackBG, prettywriter.Green, prettywriter.DoubleLine)
+		password, err := readPasswordFn(passwordFd())
+		switch {
+		case err != nil:
+			prettywriter.Writeln(fmt.Sprintf("\nError reading password: %+v", err), prettywriter.Red, prettywriter.BlackBG)
+		case string(password) == "":
+			prettywriter.Writeln("Error: Invalid empty password.", prettywriter.Red, prettywriter.BlackBG)
+		default:
+			prettywriter.Write("[**] Password entered successfully", prettywriter.BlackBG, prettywriter.Green)
+			prettywriter.Println("")
+			return string(password), nil
+		}
+		if attempt == PasswordAttempts {
+			return "", fmt.Errorf("no password entered after %d attempts", PasswordAttempts)
+		}
+	}
+}
+
+// tty is the controlling terminal once it had to be opened for reading a password
+var tty *os.File
+
+// passwordFd returns the terminal to read passwords from. That's stdin unless it got
+// redirected, e.g. to pipe the data to hide in, in which case the controlling terminal is used.
+func passwordFd() int {
+	if isTerminalFn(int(syscall.Stdin)) {
+		return int(syscall.Stdin)
+	}
+	if tty == nil {
+		f, err := openTTYFn()
+		if err != nil {
+			return int(syscall.Stdin)
+		}
+		// kept open for retries and later prompts, it's closed on exit
+		tty = f
 	}
-	prettywriter.Write("[**] Password entered successfully", prettywriter.BlackBG, prettywriter.Green)
-	fmt.Println("")
-	pwd := string(password)
-	return pwd
+	return int(tty.Fd())
 }
 
+// PrintApplicationHeader prints the version and links, headed by the big TachiHeading on a terminal
 func PrintApplicationHeader(version string) {
-	prettywriter.Write(TachiHeading, prettywriter.BlackBG, prettywriter.Green)
+	if prettywriter.Terminal() {
+		prettywriter.Write(TachiHeading, prettywriter.BlackBG, prettywriter.Green)
+	}
 	prettywriter.Writeln("> Version: "+version, prettywriter.BlackBG, prettywriter.Green)
 	prettywriter.Writeln("> Github: https://github.com/voodooEntity/go-tachicrypt", prettywriter.BlackBG, prettywriter.Green)
 	prettywriter.Writeln("> Author: voodooEntity", prettywriter.BlackBG, prettywriter.Green)
 	prettywriter.Writeln("> Contributors: f0o", prettywriter.BlackBG, prettywriter.Green)
-	fmt.Println("")
+	prettywriter.Println("")
 }
 
 func ExitError(message string) {
-	fmt.Println("")
-	prettywriter.WriteInBox(40, "!Error!", prettywriter.Black, prettywriter.Red, prettywriter.DoubleLine)
-	fmt.Println("")
+	ExitErrorCode(message, 1)
+}
+
+// ExitErrorCode prints message like ExitError, but exits with the given code
+func ExitErrorCode(message string, code int) {
+	// errors are shown with --quiet as well
+	prettywriter.SetQuiet(false)
+	prettywriter.Println("")
+	prettywriter.WriteInBox(prettywriter.Width(), "!Error!", prettywriter.Black, prettywriter.Red, prettywriter.DoubleLine)
+	prettywriter.Println("")
 	prettywriter.Writeln(message, prettywriter.Black, prettywriter.Red)
-	fmt.Println("")
-	os.Exit(1)
+	prettywriter.Println("")
+	os.Exit(code)
 }
 
 func ConcatByteSlices(byteSlices [][]byte) []byte {
@@ -110,3 +149,19 @@ func GenerateRandomBytes(min, max int) ([]byte, error) {
 
 	return randomBytes, nil
 }
+
+// RandomPermutation returns the numbers 0 to n-1 in an order drawn from crypto/rand
+func RandomPermutation(n int) ([]int, error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j, err := randIntFn(crand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j.Int64()] = perm[j.Int64()], perm[i]
+	}
+	return perm, nil
+}
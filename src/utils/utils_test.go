@@ -9,6 +9,8 @@ import (
     "io"
     "os/exec"
     "math/big"
+
+    "github.com/voodooEntity/go-tachicrypt/src/prettywriter"
 )
 
 // captureStdout captures stdout while fn executes and returns the printed string.
@@ -115,6 +117,10 @@ func TestPrintApplicationHeader_OutputsCoreInfo(t *testing.T) {
     if !strings.Contains(out, "> Author: voodooEntity") {
         t.Fatalf("header should contain author")
     }
+    // stdout is a pipe here, so the big heading is left out
+    if strings.Contains(out, "|_   _|") {
+        t.Fatalf("header should skip the heading outside a terminal, got: %q", out)
+    }
 }
 
 func TestGenerateRandomFilename_RandReadError(t *testing.T) {
@@ -166,9 +172,9 @@ func TestPromptForPassword_ErrorThenEmptyThenSuccess(t *testing.T) {
 
     // Capture stdout to avoid polluting test output; result should be the final valid password
     out := captureStdout(t, func() {
-        pwd := PromptForPassword("enter")
-        if pwd != "ok-secret" {
-            t.Fatalf("unexpected password result: %q", pwd)
+        pwd, err := PromptForPassword("enter")
+        if err != nil || pwd != "ok-secret" {
+            t.Fatalf("unexpected password result: %q (%v)", pwd, err)
         }
     })
     if !strings.Contains(out, "Password entered successfully") {
@@ -179,6 +185,54 @@ func TestPromptForPassword_ErrorThenEmptyThenSuccess(t *testing.T) {
     }
 }
 
+func TestPromptForPassword_GivesUp(t *testing.T) {
+    calls := 0
+    old := readPasswordFn
+    readPasswordFn = func(fd int) ([]byte, error) {
+        calls++
+        return []byte(""), nil
+    }
+    t.Cleanup(func() { readPasswordFn = old })
+
+    captureStdout(t, func() {
+        if _, err := PromptForPassword("enter"); err == nil {
+            t.Fatalf("expected to give up on empty input")
+        }
+    })
+    if calls != PasswordAttempts {
+        t.Fatalf("expected %d attempts, got %d", PasswordAttempts, calls)
+    }
+}
+
+func TestPromptForPassword_ReadsFromTTYWhenStdinRedirected(t *testing.T) {
+    fake, err := os.CreateTemp(t.TempDir(), "tty")
+    if err != nil {
+        t.Fatalf("create: %v", err)
+    }
+    oldRead, oldIsTerm, oldOpen, oldTTY := readPasswordFn, isTerminalFn, openTTYFn, tty
+    tty = nil
+    isTerminalFn = func(fd int) bool { return false }
+    openTTYFn = func() (*os.File, error) { return fake, nil }
+    var gotFd int
+    readPasswordFn = func(fd int) ([]byte, error) {
+        gotFd = fd
+        return []byte("tty-secret"), nil
+    }
+    t.Cleanup(func() {
+        readPasswordFn, isTerminalFn, openTTYFn, tty = oldRead, oldIsTerm, oldOpen, oldTTY
+        fake.Close()
+    })
+
+    captureStdout(t, func() {
+        if pwd, _ := PromptForPassword("enter"); pwd != "tty-secret" {
+            t.Fatalf("unexpected password result: %q", pwd)
+        }
+    })
+    if gotFd != int(fake.Fd()) {
+        t.Fatalf("expected the password to be read from the terminal, got fd %d", gotFd)
+    }
+}
+
 // TestExitError_Exits verifies that ExitError prints an error box and exits with code 1.
 func TestExitError_Exits(t *testing.T) {
     if os.Getenv("UTILS_EXIT_HELPER") == "1" {
@@ -201,3 +255,45 @@ func TestExitError_Exits(t *testing.T) {
         t.Fatalf("unexpected output: %q", s)
     }
 }
+
+// TestExitErrorCode_ExitsWithCode verifies that ExitErrorCode exits with the given code.
+func TestExitErrorCode_ExitsWithCode(t *testing.T) {
+    if os.Getenv("UTILS_EXIT_HELPER") == "1" {
+        // errors get through quiet mode
+        prettywriter.SetQuiet(true)
+        ExitErrorCode("wrong password", 3)
+        return
+    }
+
+    c := exec.Command(os.Args[0], "-test.run", "TestExitErrorCode_ExitsWithCode")
+    c.Env = append(os.Environ(), "UTILS_EXIT_HELPER=1")
+    out, err := c.CombinedOutput()
+    exitErr, ok := err.(*exec.ExitError)
+    if !ok || exitErr.ExitCode() != 3 {
+        t.Fatalf("expected the subprocess to exit with code 3, got %v", err)
+    }
+    if !strings.Contains(string(out), "wrong password") {
+        t.Fatalf("unexpected output: %q", out)
+    }
+}
+
+func TestRandomPermutation(t *testing.T) {
+    perm, err := RandomPermutation(50)
+    if err != nil {
+        t.Fatalf("RandomPermutation: %v", err)
+    }
+    seen := make([]bool, 50)
+    for _, v := range perm {
+        if v < 0 || v >= 50 || seen[v] {
+            t.Fatalf("not a permutation: %v", perm)
+        }
+        seen[v] = true
+    }
+
+    oldInt := randIntFn
+    randIntFn = func(r io.Reader, max *big.Int) (*big.Int, error) { return nil, io.ErrUnexpectedEOF }
+    t.Cleanup(func() { randIntFn = oldInt })
+    if _, err := RandomPermutation(3); err == nil {
+        t.Fatalf("expected random source error")
+    }
+}
@@ -0,0 +1,118 @@
+package zipper
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression methods selectable for archive entries
+const (
+	CompressionStore   = "store"
+	CompressionDeflate = "deflate"
+	CompressionZstd    = "zstd"
+)
+
+// sampleSize is the amount of data inspected to decide whether an entry is worth compressing
+const sampleSize = 64 * 1024
+
+// minSampleSize is the smallest sample the entropy check is trusted on; smaller files are
+// compressed with the configured method regardless
+const minSampleSize = 512
+
+// incompressibleEntropy is the entropy in bits per byte above which data is considered
+// already compressed or encrypted and gets stored instead
+const incompressibleEntropy = 7.5
+
+func init() {
+	// zstd entries have to be readable by every zip reader, the compressor is registered per
+	// writer since it depends on the configured level
+	zip.RegisterDecompressor(zstd.ZipMethodWinZip, zstd.ZipDecompressor())
+}
+
+// ValidateCompression checks the given compression method and level. A level of 0 selects the
+// default of the method, deflate accepts 1-9 and zstd 1-22.
+func ValidateCompression(method string, level int) error {
+	switch method {
+	case CompressionStore:
+		if level != 0 {
+			return fmt.Errorf("compression level can't be used with %s", CompressionStore)
+		}
+	case CompressionDeflate:
+		if level < 0 || level > flate.BestCompression {
+			return fmt.Errorf("invalid %s compression level %d, expected 1-9", method, level)
+		}
+	case CompressionZstd:
+		if level < 0 || level > 22 {
+			return fmt.Errorf("invalid %s compression level %d, expected 1-22", method, level)
+		}
+	default:
+		return fmt.Errorf("unknown compression method %q", method)
+	}
+	return nil
+}
+
+// compression returns the configured compression method, defaulting to deflate
+func (z *Zipper) compression() string {
+	if z.Compression == "" {
+		return CompressionDeflate
+	}
+	return z.Compression
+}
+
+// zipMethod maps the configured compression to the zip method id
+func (z *Zipper) zipMethod() uint16 {
+	switch z.compression() {
+	case CompressionStore:
+		return zip.Store
+	case CompressionZstd:
+		return zstd.ZipMethodWinZip
+	default:
+		return zip.Deflate
+	}
+}
+
+// registerCompressors registers the compressors matching the configured level on the writer
+func (z *Zipper) registerCompressors(w *zip.Writer) {
+	switch z.compression() {
+	case CompressionDeflate:
+		if z.Level != 0 {
+			level := z.Level
+			w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(out, level)
+			})
+		}
+	case CompressionZstd:
+		level := zstd.SpeedDefault
+		if z.Level != 0 {
+			level = zstd.EncoderLevelFromZstd(z.Level)
+		}
+		w.RegisterCompressor(zstd.ZipMethodWinZip, zstd.ZipCompressor(zstd.WithEncoderLevel(level)))
+	}
+}
+
+// looksIncompressible reports whether the sample has so much entropy that compressing it would
+// only waste CPU, as is the case for media, archives and encrypted data
+func looksIncompressible(sample []byte) bool {
+	if len(sample) < minSampleSize {
+		return false
+	}
+	var counts [256]int
+	for _, b := range sample {
+		counts[b]++
+	}
+	entropy := 0.0
+	total := float64(len(sample))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy > incompressibleEntropy
+}
@@ -0,0 +1,129 @@
+package zipper
+
+import (
+    "archive/zip"
+    "bytes"
+    "crypto/rand"
+    "path/filepath"
+    "testing"
+
+    "github.com/klauspost/compress/zstd"
+)
+
+func entryMethods(t *testing.T, data []byte) map[string]uint16 {
+    t.Helper()
+    r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        t.Fatalf("reader: %v", err)
+    }
+    methods := map[string]uint16{}
+    for _, f := range r.File {
+        methods[f.Name] = f.Method
+    }
+    return methods
+}
+
+func TestZip_CompressionMethodsRoundTrip(t *testing.T) {
+    text := bytes.Repeat([]byte("a fairly compressible line of text\n"), 500)
+    cases := []struct {
+        method string
+        level  int
+        want   uint16
+    }{
+        {CompressionStore, 0, zip.Store},
+        {CompressionDeflate, 0, zip.Deflate},
+        {CompressionDeflate, 9, zip.Deflate},
+        {CompressionZstd, 0, zstd.ZipMethodWinZip},
+        {CompressionZstd, 19, zstd.ZipMethodWinZip},
+    }
+    for _, tc := range cases {
+        tmp := t.TempDir()
+        src := filepath.Join(tmp, "dump.sql")
+        writeFile(t, src, text)
+
+        z := New()
+        z.Compression = tc.method
+        z.Level = tc.level
+        data, err := z.Zip(src)
+        if err != nil {
+            t.Fatalf("%s/%d zip: %v", tc.method, tc.level, err)
+        }
+        if got := entryMethods(t, data)["dump.sql"]; got != tc.want {
+            t.Fatalf("%s/%d: method mismatch: got %d want %d", tc.method, tc.level, got, tc.want)
+        }
+        if tc.method != CompressionStore && len(data) >= len(text) {
+            t.Fatalf("%s/%d: expected compressed archive, got %d bytes for %d input", tc.method, tc.level, len(data), len(text))
+        }
+
+        dest := filepath.Join(tmp, "out")
+        if err := New().Extract(data, dest); err != nil {
+            t.Fatalf("%s/%d extract: %v", tc.method, tc.level, err)
+        }
+        if got := readFile(t, filepath.Join(dest, "dump.sql")); !bytes.Equal(got, text) {
+            t.Fatalf("%s/%d: content mismatch", tc.method, tc.level)
+        }
+    }
+}
+
+func TestZip_IncompressibleEntriesAreStored(t *testing.T) {
+    tmp := t.TempDir()
+    noise := make([]byte, 128*1024)
+    if _, err := rand.Read(noise); err != nil {
+        t.Fatalf("rand: %v", err)
+    }
+    writeFile(t, filepath.Join(tmp, "in", "video.bin"), noise)
+    writeFile(t, filepath.Join(tmp, "in", "notes.txt"), bytes.Repeat([]byte("note "), 1000))
+
+    z := New()
+    z.Compression = CompressionZstd
+    data, err := z.Zip(filepath.Join(tmp, "in"))
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+    methods := entryMethods(t, data)
+    if methods["in/video.bin"] != zip.Store {
+        t.Fatalf("expected random data to be stored, got method %d", methods["in/video.bin"])
+    }
+    if methods["in/notes.txt"] != zstd.ZipMethodWinZip {
+        t.Fatalf("expected text to be zstd compressed, got method %d", methods["in/notes.txt"])
+    }
+
+    dest := filepath.Join(tmp, "out")
+    if err := z.Extract(data, dest); err != nil {
+        t.Fatalf("extract: %v", err)
+    }
+    if got := readFile(t, filepath.Join(dest, "in", "video.bin")); !bytes.Equal(got, noise) {
+        t.Fatalf("stored entry content mismatch")
+    }
+}
+
+func TestValidateCompression(t *testing.T) {
+    valid := []struct {
+        method string
+        level  int
+    }{
+        {CompressionStore, 0}, {CompressionDeflate, 0}, {CompressionDeflate, 1}, {CompressionZstd, 22},
+    }
+    for _, v := range valid {
+        if err := ValidateCompression(v.method, v.level); err != nil {
+            t.Fatalf("expected %s/%d to be valid: %v", v.method, v.level, err)
+        }
+    }
+    invalid := []struct {
+        method string
+        level  int
+    }{
+        {"lzma", 0}, {CompressionStore, 3}, {CompressionDeflate, 10}, {CompressionZstd, 23}, {CompressionZstd, -1},
+    }
+    for _, v := range invalid {
+        if err := ValidateCompression(v.method, v.level); err == nil {
+            t.Fatalf("expected %s/%d to be rejected", v.method, v.level)
+        }
+    }
+
+    z := New()
+    z.Compression = "lzma"
+    if _, err := z.Zip(t.TempDir()); err == nil {
+        t.Fatalf("expected Zip to reject unknown compression")
+    }
+}
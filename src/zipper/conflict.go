@@ -0,0 +1,215 @@
+package zipper
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Conflict policies deciding what happens to entries whose path already exists in the
+// destination directory
+const (
+	ConflictFail      = "fail"
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+// Conflict records how an entry clashing with an existing path was handled
+type Conflict struct {
+	// Name is the entry name inside the container
+	Name string
+	// Action is the policy that was applied: skip, overwrite or rename
+	Action string
+	// Target is the name the entry was written as, differing from Name when renamed
+	Target string
+}
+
+// ValidateConflictPolicy makes sure the conflict policy is known, an empty one meaning fail
+func ValidateConflictPolicy(policy string) error {
+	switch policy {
+	case "", ConflictFail, ConflictSkip, ConflictOverwrite, ConflictRename:
+		return nil
+	}
+	return fmt.Errorf("unknown conflict policy %q, use fail, skip, overwrite or rename", policy)
+}
+
+// decision is how a single entry gets extracted
+type decision struct {
+	// target is the name the entry is written as
+	target string
+	skip   bool
+	// overwrite removes what is at the target first
+	overwrite bool
+}
+
+// planEntry is an entry as seen while planning, before anything gets written
+type planEntry struct {
+	name  string
+	isDir bool
+}
+
+// plan decides how every entry is extracted before anything is written, so the fail policy
+// leaves the destination untouched and renamed entries can't clash with other entries.
+// Archives holding a name twice are rejected, a later entry could otherwise be written
+// through a symlink an earlier one of the same name created.
+func (x *extractor) plan(entries []planEntry) error {
+	x.decisions = map[string]decision{}
+	x.taken = map[string]bool{}
+	for _, e := range entries {
+		name := cleanName(e.name)
+		if x.taken[name] {
+			return fmt.Errorf("%w: %s appears more than once", ErrUnsafePath, name)
+		}
+		x.taken[name] = true
+	}
+
+	var existing []string
+	for _, e := range entries {
+		d, err := x.decide(e.name, e.isDir)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			existing = append(existing, cleanName(e.name))
+		}
+	}
+	if len(existing) > 0 {
+		return existingError(x.destDir, existing)
+	}
+	return nil
+}
+
+// resolve returns the target path of an entry, or skip if it must not be written. Entries
+// which weren't planned, e.g. when reading a stream, are decided on the spot.
+func (x *extractor) resolve(name string, isDir bool) (string, bool, error) {
+	d, ok := x.decisions[cleanName(name)]
+	if !ok {
+		dp, err := x.decide(name, isDir)
+		if err != nil {
+			return "", false, err
+		}
+		if dp == nil {
+			return "", false, existingError(x.destDir, []string{cleanName(name)})
+		}
+		d = *dp
+	}
+	if d.skip {
+		return "", true, nil
+	}
+	target, err := safeJoin(x.writeDir, d.target)
+	if err != nil {
+		return "", false, err
+	}
+	if d.overwrite && x.writeDir == x.destDir {
+		// whatever is in the way is removed, writing through an existing symlink or into an
+		// existing file's inode could hit data outside of the extracted tree. When staging,
+		// moving the staged entry into place replaces it instead.
+		if info, err := osLstatFn(target); err == nil && (!info.IsDir() || !isDir) {
+			if err := osRemoveFn(target); err != nil {
+				return "", false, fmt.Errorf("can't overwrite %s: %w", target, err)
+			}
+		}
+	}
+	return target, false, nil
+}
+
+// decide applies the conflict policy to a single entry and records the decision. nil means
+// the entry conflicts and the policy is fail.
+func (x *extractor) decide(name string, isDir bool) (*decision, error) {
+	name = cleanName(name)
+	if x.decisions == nil {
+		x.decisions = map[string]decision{}
+	}
+	target, err := safeJoin(x.destDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// entries below skipped or renamed directories follow their parent, renamed ones end up
+	// in a directory which didn't exist before so they can't conflict
+	for parent := path.Dir(name); parent != "."; parent = path.Dir(parent) {
+		if d, ok := x.decisions[parent]; ok && (d.skip || d.target != parent) {
+			child := decision{skip: d.skip, target: d.target + strings.TrimPrefix(name, parent)}
+			x.decisions[name] = child
+			return &child, nil
+		}
+	}
+
+	d := decision{target: name}
+	info, err := osLstatFn(target)
+	if err != nil || (isDir && info.IsDir()) {
+		// nothing there yet, or a directory being merged into an existing one
+		x.decisions[name] = d
+		return &d, nil
+	}
+
+	switch x.policy {
+	case ConflictSkip:
+		d.skip = true
+	case ConflictOverwrite:
+		// only empty directories can be replaced, anything else would lose their content
+		if info.IsDir() && !isDir {
+			if entries, err := osReadDirFn(target); err != nil || len(entries) > 0 {
+				return nil, fmt.Errorf("can't overwrite non-empty directory %s with a file", target)
+			}
+		}
+		d.overwrite = true
+	case ConflictRename:
+		d.target = x.freeName(name)
+	default:
+		return nil, nil
+	}
+	x.decisions[name] = d
+	conflict := Conflict{Name: name, Action: x.policy, Target: d.target}
+	if d.skip {
+		conflict.Target = ""
+	}
+	x.conflicts = append(x.conflicts, conflict)
+	return &d, nil
+}
+
+// freeName returns the first name_N.ext next to name which neither exists in the destination
+// nor is used by another entry
+func (x *extractor) freeName(name string) string {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s%s_%d%s", dir, stem, i, ext)
+		if x.taken[candidate] {
+			continue
+		}
+		if _, err := osLstatFn(filepath.Join(x.destDir, filepath.FromSlash(candidate))); os.IsNotExist(err) {
+			if x.taken == nil {
+				x.taken = map[string]bool{}
+			}
+			x.taken[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// skipped reports whether the entry was skipped because of a conflict
+func (x *extractor) skipped(name string) bool {
+	return x.decisions[cleanName(name)].skip
+}
+
+// cleanName normalizes an entry name, dropping the trailing slash of directories
+func cleanName(name string) string {
+	return path.Clean(strings.TrimSuffix(name, "/"))
+}
+
+func existingError(destDir string, names []string) error {
+	const shown = 5
+	list := strings.Join(names, ", ")
+	if len(names) > shown {
+		list = strings.Join(names[:shown], ", ") + fmt.Sprintf(" and %d more", len(names)-shown)
+	}
+	return fmt.Errorf("%d entries already exist in %s, nothing was written: %s", len(names), destDir, list)
+}
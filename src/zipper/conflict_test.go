@@ -0,0 +1,214 @@
+package zipper
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// conflictFixture packs a small tree and prepares a destination already holding some of its paths
+func conflictFixture(t *testing.T, format string) (Container, []byte, string) {
+    t.Helper()
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    writeFile(t, filepath.Join(root, "a.txt"), []byte("new a"))
+    writeFile(t, filepath.Join(root, "a_2.txt"), []byte("new a2"))
+    writeFile(t, filepath.Join(root, "b.txt"), []byte("new b"))
+    writeFile(t, filepath.Join(root, "sub", "c.txt"), []byte("new c"))
+    if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "hard")); err != nil {
+        t.Fatalf("link: %v", err)
+    }
+
+    c, err := NewContainer(format, Options{})
+    if err != nil {
+        t.Fatalf("container: %v", err)
+    }
+    data, err := c.Pack(root)
+    if err != nil {
+        t.Fatalf("pack: %v", err)
+    }
+
+    dest := filepath.Join(tmp, "out")
+    writeFile(t, filepath.Join(dest, "tree", "a.txt"), []byte("old a"))
+    // a file where the archive has a directory
+    writeFile(t, filepath.Join(dest, "tree", "sub"), []byte("old sub"))
+    return c, data, dest
+}
+
+func unpackWith(t *testing.T, format string, policy string, data []byte, dest string) (Container, error) {
+    t.Helper()
+    c, err := NewContainer(format, Options{OnConflict: policy})
+    if err != nil {
+        t.Fatalf("container: %v", err)
+    }
+    return c, c.Unpack(data, dest)
+}
+
+func TestUnpack_ConflictFailWritesNothing(t *testing.T) {
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        _, data, dest := conflictFixture(t, format)
+        _, err := unpackWith(t, format, "", data, dest)
+        if err == nil || !strings.Contains(err.Error(), "2 entries already exist") {
+            t.Fatalf("%s: expected conflict error, got %v", format, err)
+        }
+        if _, err := os.Stat(filepath.Join(dest, "tree", "b.txt")); !os.IsNotExist(err) {
+            t.Fatalf("%s: expected nothing to be written, got %v", format, err)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "a.txt")); string(got) != "old a" {
+            t.Fatalf("%s: existing file touched: %q", format, got)
+        }
+    }
+}
+
+func TestUnpack_ConflictSkip(t *testing.T) {
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        _, data, dest := conflictFixture(t, format)
+        c, err := unpackWith(t, format, ConflictSkip, data, dest)
+        if err != nil {
+            t.Fatalf("%s: unpack: %v", format, err)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "a.txt")); string(got) != "old a" {
+            t.Fatalf("%s: skipped file overwritten: %q", format, got)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "b.txt")); string(got) != "new b" {
+            t.Fatalf("%s: other file not written: %q", format, got)
+        }
+        // the hardlink to the skipped entry is skipped as well
+        if _, err := os.Lstat(filepath.Join(dest, "tree", "hard")); !os.IsNotExist(err) {
+            t.Fatalf("%s: expected hardlink to skipped entry to be skipped, got %v", format, err)
+        }
+        want := []Conflict{
+            {Name: "tree/a.txt", Action: ConflictSkip},
+            {Name: "tree/sub", Action: ConflictSkip},
+            {Name: "tree/hard", Action: ConflictSkip},
+        }
+        if !sameConflicts(c.Conflicts(), want) {
+            t.Fatalf("%s: unexpected conflicts %+v", format, c.Conflicts())
+        }
+    }
+}
+
+func TestUnpack_ConflictOverwrite(t *testing.T) {
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        _, data, dest := conflictFixture(t, format)
+        // a symlink in the way must be replaced, not written through
+        outside := filepath.Join(filepath.Dir(dest), "outside.txt")
+        writeFile(t, outside, []byte("outside"))
+        if err := os.Symlink(outside, filepath.Join(dest, "tree", "b.txt")); err != nil {
+            t.Fatalf("symlink: %v", err)
+        }
+
+        if _, err := unpackWith(t, format, ConflictOverwrite, data, dest); err != nil {
+            t.Fatalf("%s: unpack: %v", format, err)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "a.txt")); string(got) != "new a" {
+            t.Fatalf("%s: file not overwritten: %q", format, got)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "sub", "c.txt")); string(got) != "new c" {
+            t.Fatalf("%s: file replaced by directory not written: %q", format, got)
+        }
+        if got := readFile(t, outside); string(got) != "outside" {
+            t.Fatalf("%s: wrote through symlink: %q", format, got)
+        }
+        if got := readFile(t, filepath.Join(dest, "tree", "b.txt")); string(got) != "new b" {
+            t.Fatalf("%s: symlink not replaced: %q", format, got)
+        }
+    }
+}
+
+func TestUnpack_ConflictRename(t *testing.T) {
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        _, data, dest := conflictFixture(t, format)
+        c, err := unpackWith(t, format, ConflictRename, data, dest)
+        if err != nil {
+            t.Fatalf("%s: unpack: %v", format, err)
+        }
+        tree := filepath.Join(dest, "tree")
+        expect := map[string]string{
+            "a.txt":                         "old a",
+            "a_2.txt":                       "new a2", // taken by an archive entry, so a.txt becomes a_3.txt
+            "a_3.txt":                       "new a",
+            "sub":                           "old sub",
+            filepath.Join("sub_2", "c.txt"): "new c",
+        }
+        for name, content := range expect {
+            if got := readFile(t, filepath.Join(tree, name)); string(got) != content {
+                t.Fatalf("%s: %s = %q, want %q", format, name, got, content)
+            }
+        }
+        // the hardlink follows its renamed first entry
+        renamed, _ := os.Stat(filepath.Join(tree, "a_3.txt"))
+        hard, err := os.Stat(filepath.Join(tree, "hard"))
+        if err != nil || !os.SameFile(renamed, hard) {
+            t.Fatalf("%s: hardlink not pointing to renamed entry: %v", format, err)
+        }
+        want := []Conflict{
+            {Name: "tree/a.txt", Action: ConflictRename, Target: "tree/a_3.txt"},
+            {Name: "tree/sub", Action: ConflictRename, Target: "tree/sub_2"},
+        }
+        if !sameConflicts(c.Conflicts(), want) {
+            t.Fatalf("%s: unexpected conflicts %+v", format, c.Conflicts())
+        }
+        // extracted entries are listed under the names they were written as
+        extracted := map[string]bool{}
+        for _, name := range c.Extracted() {
+            extracted[name] = true
+        }
+        if !extracted["tree/a_3.txt"] || !extracted["tree/sub_2/c.txt"] || !extracted["tree/hard"] || extracted["tree/a.txt"] || extracted["tree/sub_2"] {
+            t.Fatalf("%s: unexpected extracted entries %v", format, c.Extracted())
+        }
+    }
+}
+
+func TestUnpack_ExistingDirectoriesAreMerged(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    writeFile(t, filepath.Join(root, "new.txt"), []byte("new"))
+    data, err := New().Pack(root)
+    if err != nil {
+        t.Fatalf("pack: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    writeFile(t, filepath.Join(dest, "tree", "existing.txt"), []byte("existing"))
+
+    z := New()
+    if err := z.Unpack(data, dest); err != nil {
+        t.Fatalf("unpack: %v", err)
+    }
+    if len(z.Conflicts()) != 0 {
+        t.Fatalf("expected no conflicts, got %+v", z.Conflicts())
+    }
+    if got := readFile(t, filepath.Join(dest, "tree", "existing.txt")); string(got) != "existing" {
+        t.Fatalf("existing file touched: %q", got)
+    }
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+    for _, policy := range []string{"", ConflictFail, ConflictSkip, ConflictOverwrite, ConflictRename} {
+        if err := ValidateConflictPolicy(policy); err != nil {
+            t.Fatalf("policy %q: %v", policy, err)
+        }
+    }
+    if err := ValidateConflictPolicy("merge"); err == nil {
+        t.Fatalf("expected unknown policy to be rejected")
+    }
+    z := New()
+    z.OnConflict = "merge"
+    if err := z.Unpack([]byte{}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "unknown conflict policy") {
+        t.Fatalf("expected unpack to reject an unknown policy, got %v", err)
+    }
+}
+
+// sameConflicts compares conflicts ignoring their order
+func sameConflicts(got, want []Conflict) bool {
+    index := func(list []Conflict) map[string]Conflict {
+        m := map[string]Conflict{}
+        for _, c := range list {
+            m[c.Name] = c
+        }
+        return m
+    }
+    return len(got) == len(want) && reflect.DeepEqual(index(got), index(want))
+}
@@ -0,0 +1,102 @@
+package zipper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Container formats the hidden data can be packed into
+const (
+	FormatZip     = "zip"
+	FormatTar     = "tar"
+	FormatTarZstd = "tar.zst"
+)
+
+// Container packs files and directories into a single blob and unpacks such a blob again.
+// Every packed path becomes a top-level entry. Zipper and Tarrer are the available implementations.
+type Container interface {
+	Pack(paths ...string) ([]byte, error)
+	Unpack(data []byte, destDir string) error
+	// PackStream packs everything read from r as a single file entry called name
+	PackStream(name string, r io.Reader) ([]byte, error)
+	// UnpackStream writes the content of a single file archive to w and returns the entry name
+	UnpackStream(data []byte, w io.Writer) (string, error)
+	// Conflicts reports how entries clashing with existing paths were handled by the last Unpack
+	Conflicts() []Conflict
+	// Extracted lists the files, links and special files written by the last Unpack, by the
+	// slash separated name they got below the destination
+	Extracted() []string
+	// Entries lists the entries of packed data in their order without extracting anything.
+	// Their content is read completely, so damaged data fails like it would unpacking.
+	Entries(ctx context.Context, data []byte) ([]Entry, error)
+
+	// the Context variants stop with the error of ctx once it's done, checking between entries
+	// and while copying their content
+	PackContext(ctx context.Context, paths ...string) ([]byte, error)
+	UnpackContext(ctx context.Context, data []byte, destDir string) error
+	PackStreamContext(ctx context.Context, name string, r io.Reader) ([]byte, error)
+	UnpackStreamContext(ctx context.Context, data []byte, w io.Writer) (string, error)
+}
+
+// both formats have to implement Container
+var (
+	_ Container = (*Zipper)(nil)
+	_ Container = (*Tarrer)(nil)
+)
+
+// Options are the settings shared by all container formats
+type Options struct {
+	// StripMetadata drops permissions, ownership and timestamps from the archive so
+	// nothing but names and contents is stored
+	StripMetadata bool
+	// FollowSymlinks archives the files and directories symlinks point to instead of the links themselves
+	FollowSymlinks bool
+	// Compression is the method used for file entries (store, deflate or zstd). Only zip supports
+	// all of them; tar is always stored and tar.zst always zstd compressed.
+	Compression string
+	// Level is the compression level of the chosen method, 0 selects the method's default
+	Level int
+	// Excludes are gitignore style patterns, relative to the packed path, of files and
+	// directories to leave out. Patterns from .tachiignore files are applied as well.
+	Excludes []string
+	// OnConflict decides what happens when unpacking an entry whose path already exists:
+	// fail (the default), skip, overwrite or rename
+	OnConflict string
+	// StagingDir makes Unpack write into this directory instead of the destination, while
+	// conflicts are still decided against the destination. Moving the result into place
+	// (fileutils.MoveTree) then applies overwrites.
+	StagingDir string
+	// Journal makes Unpack resumable: files it reports as done are kept instead of written
+	// again, and every file written completely gets added to it
+	Journal Journal
+	// Packed is called for every file, link and directory Pack read from disk into the archive,
+	// with the file info it got stored with
+	Packed func(path string, info os.FileInfo)
+}
+
+// Journal remembers the files an interrupted Unpack already wrote
+type Journal interface {
+	// Done reports whether the entry name was already written completely to path
+	Done(name string, path string) bool
+	// Add records that the entry name was written completely, sum being the SHA-256 of its content
+	Add(name string, sum []byte) error
+}
+
+// NewContainer returns the container implementation for the given format, an empty format
+// selecting zip
+func NewContainer(format string, opts Options) (Container, error) {
+	switch format {
+	case "", FormatZip:
+		return &Zipper{Options: opts}, nil
+	case FormatTar, FormatTarZstd:
+		t := NewTar(format == FormatTarZstd)
+		t.Options = opts
+		if err := t.validate(); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown container format %q", format)
+}
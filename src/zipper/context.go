@@ -0,0 +1,20 @@
+package zipper
+
+import (
+	"context"
+	"io"
+)
+
+// ctxReader fails with the error of ctx once it's done, so copying the content of an entry
+// stops between chunks instead of running to the end of a large file
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,82 @@
+package zipper
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// cancelingReader cancels its context once the first chunk got read
+type cancelingReader struct {
+    r      io.Reader
+    cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+    if len(p) > 1024 {
+        p = p[:1024]
+    }
+    n, err := c.r.Read(p)
+    c.cancel()
+    return n, err
+}
+
+func TestContext_StopsPackingAndUnpacking(t *testing.T) {
+    src := filepath.Join(t.TempDir(), "tree")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("a"))
+    canceled, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    for _, format := range []string{FormatZip, FormatTar, FormatTarZstd} {
+        c, err := NewContainer(format, Options{})
+        if err != nil {
+            t.Fatalf("container %s: %v", format, err)
+        }
+        if _, err := c.PackContext(canceled, src); !errors.Is(err, context.Canceled) {
+            t.Fatalf("%s: expected packing to be canceled, got %v", format, err)
+        }
+
+        data, err := c.Pack(src)
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        dest := filepath.Join(t.TempDir(), "out")
+        if err := c.UnpackContext(canceled, data, dest); !errors.Is(err, context.Canceled) {
+            t.Fatalf("%s: expected unpacking to be canceled, got %v", format, err)
+        }
+        if _, err := os.Stat(filepath.Join(dest, "tree", "a.txt")); !os.IsNotExist(err) {
+            t.Fatalf("%s: expected nothing to be extracted, got %v", format, err)
+        }
+    }
+}
+
+func TestContext_StopsStreamsBetweenChunks(t *testing.T) {
+    payload := strings.Repeat("x", 1<<20)
+    for _, format := range []string{FormatZip, FormatTar, FormatTarZstd} {
+        c, err := NewContainer(format, Options{})
+        if err != nil {
+            t.Fatalf("container %s: %v", format, err)
+        }
+        ctx, cancel := context.WithCancel(context.Background())
+        r := &cancelingReader{r: strings.NewReader(payload), cancel: cancel}
+        if _, err := c.PackStreamContext(ctx, "big", r); !errors.Is(err, context.Canceled) {
+            t.Fatalf("%s: expected the stream to be canceled, got %v", format, err)
+        }
+
+        data, err := c.PackStream("big", strings.NewReader(payload))
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        canceled, cancel := context.WithCancel(context.Background())
+        cancel()
+        var out bytes.Buffer
+        if _, err := c.UnpackStreamContext(canceled, data, &out); !errors.Is(err, context.Canceled) || out.Len() != 0 {
+            t.Fatalf("%s: expected writing the stream to be canceled, got %v after %d bytes", format, err, out.Len())
+        }
+    }
+}
@@ -0,0 +1,76 @@
+package zipper
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// Entry describes an entry of packed data
+type Entry struct {
+	Name string
+	// Size is the size of the content of files, 0 for other entries
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// Entries implements Container. The content of every file is read completely, so its checksum
+// gets verified.
+func (z *Zipper) Entries(ctx context.Context, data []byte) ([]Entry, error) {
+	reader, err := zipNewReaderFn(data)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(reader.File))
+	for _, f := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry := Entry{Name: f.Name, Mode: f.Mode(), ModTime: f.Modified}
+		_, hardlink := findExtra(f.Extra, extraHardlink)
+		if !f.Mode().IsDir() && !hardlink {
+			rc, err := zipFileOpenFn(f)
+			if err != nil {
+				return nil, err
+			}
+			entry.Size, err = ioCopyFn(io.Discard, &ctxReader{ctx: ctx, r: rc})
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if entry.Mode&os.ModeSymlink != 0 {
+			entry.Size = 0
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Entries implements Container. The whole stream is read, so damaged zstd frames are noticed.
+func (t *Tarrer) Entries(ctx context.Context, data []byte) ([]Entry, error) {
+	var entries []Entry
+	err := t.read(bytes.NewReader(data), func(hdr *tar.Header, tr io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry := Entry{Name: hdr.Name, Mode: hdr.FileInfo().Mode(), ModTime: hdr.ModTime}
+		if hdr.Typeflag == tar.TypeReg {
+			n, err := io.Copy(io.Discard, &ctxReader{ctx: ctx, r: tr})
+			if err != nil {
+				return err
+			}
+			entry.Size = n
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,61 @@
+package zipper
+
+import (
+    "bytes"
+    "context"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestEntries(t *testing.T) {
+    src := filepath.Join(t.TempDir(), "docs")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(src, "sub", "b.txt"), []byte(strings.Repeat("b", 5000)))
+    for _, format := range []string{FormatZip, FormatTar, FormatTarZstd} {
+        c, err := NewContainer(format, Options{})
+        if err != nil {
+            t.Fatalf("container %s: %v", format, err)
+        }
+        data, err := c.Pack(src)
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        entries, err := c.Entries(context.Background(), data)
+        if err != nil {
+            t.Fatalf("entries %s: %v", format, err)
+        }
+        sizes := map[string]int64{}
+        for _, e := range entries {
+            sizes[strings.TrimSuffix(e.Name, "/")] = e.Size
+        }
+        if len(entries) != 4 || sizes["docs/a.txt"] != 5 || sizes["docs/sub/b.txt"] != 5000 || sizes["docs/sub"] != 0 {
+            t.Fatalf("%s: unexpected entries %+v", format, entries)
+        }
+    }
+}
+
+func TestEntries_DamagedContent(t *testing.T) {
+    src := filepath.Join(t.TempDir(), "a.txt")
+    payload := []byte(strings.Repeat("content worth checking ", 100))
+    writeFile(t, src, payload)
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        c, _ := NewContainer(format, Options{Compression: CompressionStore})
+        if format == FormatTarZstd {
+            c, _ = NewContainer(format, Options{})
+        }
+        data, err := c.Pack(src)
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        // flip a byte in the middle of the content
+        i := bytes.Index(data, []byte("worth"))
+        if i < 0 {
+            i = len(data) / 2
+        }
+        data[i] ^= 0xff
+        if _, err := c.Entries(context.Background(), data); err == nil {
+            t.Fatalf("%s: expected damaged content to be noticed", format)
+        }
+    }
+}
@@ -0,0 +1,122 @@
+package zipper
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestList_ExcludesAndIgnoreFiles(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "project")
+    writeFile(t, filepath.Join(root, "main.go"), []byte("package main"))
+    writeFile(t, filepath.Join(root, "debug.log"), []byte("log"))
+    writeFile(t, filepath.Join(root, "node_modules", "dep", "index.js"), []byte("js"))
+    writeFile(t, filepath.Join(root, "sub", "keep.txt"), []byte("keep"))
+    writeFile(t, filepath.Join(root, "sub", "cache.bin"), []byte("cache"))
+    writeFile(t, filepath.Join(root, "sub", ".tachiignore"), []byte("# local rules\n*.bin\n"))
+    writeFile(t, filepath.Join(root, ".tachiignore"), []byte("!important.log\n"))
+    writeFile(t, filepath.Join(root, "important.log"), []byte("keep me"))
+
+    opts := Options{Excludes: []string{"node_modules/", "*.log"}}
+    names, err := opts.List(root)
+    if err != nil {
+        t.Fatalf("list: %v", err)
+    }
+    want := []string{
+        "project/",
+        "project/.tachiignore",
+        "project/important.log",
+        "project/main.go",
+        "project/sub/",
+        "project/sub/.tachiignore",
+        "project/sub/keep.txt",
+    }
+    if !reflect.DeepEqual(names, want) {
+        t.Fatalf("unexpected listing:\n got %v\nwant %v", names, want)
+    }
+
+    // the packed container has to hold exactly what the listing promised
+    data, err := (&Zipper{Options: opts}).Pack(root)
+    if err != nil {
+        t.Fatalf("pack: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := New().Unpack(data, dest); err != nil {
+        t.Fatalf("unpack: %v", err)
+    }
+    for _, excluded := range []string{"debug.log", "node_modules", filepath.Join("sub", "cache.bin")} {
+        if _, err := os.Lstat(filepath.Join(dest, "project", excluded)); !os.IsNotExist(err) {
+            t.Fatalf("expected %s to be excluded, got %v", excluded, err)
+        }
+    }
+    if got := readFile(t, filepath.Join(dest, "project", "important.log")); string(got) != "keep me" {
+        t.Fatalf("re-included file mismatch: %q", got)
+    }
+}
+
+func TestList_RootIsNeverExcluded(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "notes.log")
+    writeFile(t, src, []byte("x"))
+
+    names, err := (&Options{Excludes: []string{"*.log"}}).List(src)
+    if err != nil {
+        t.Fatalf("list: %v", err)
+    }
+    if !reflect.DeepEqual(names, []string{"notes.log"}) {
+        t.Fatalf("unexpected listing: %v", names)
+    }
+}
+
+func TestSources_MatchesListing(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "project")
+    writeFile(t, filepath.Join(root, "main.go"), []byte("package main"))
+    writeFile(t, filepath.Join(root, "debug.log"), []byte("log"))
+    writeFile(t, filepath.Join(root, "sub", "keep.txt"), []byte("keep"))
+
+    paths, err := (&Options{Excludes: []string{"*.log"}}).Sources(root)
+    if err != nil {
+        t.Fatalf("sources: %v", err)
+    }
+    want := []string{root, filepath.Join(root, "main.go"), filepath.Join(root, "sub"), filepath.Join(root, "sub", "keep.txt")}
+    if !reflect.DeepEqual(paths, want) {
+        t.Fatalf("unexpected sources:\n got %v\nwant %v", paths, want)
+    }
+}
+
+func TestPacked_ReportsWhatGotPacked(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "project")
+    writeFile(t, filepath.Join(root, "main.go"), []byte("package main"))
+    writeFile(t, filepath.Join(root, "debug.log"), []byte("log"))
+    writeFile(t, filepath.Join(root, "sub", "keep.txt"), []byte("keep"))
+    want := []string{root, filepath.Join(root, "main.go"), filepath.Join(root, "sub"), filepath.Join(root, "sub", "keep.txt")}
+
+    for _, format := range []string{FormatZip, FormatTar} {
+        var paths []string
+        opts := Options{Excludes: []string{"*.log"}, Packed: func(path string, info os.FileInfo) {
+            if info.Name() != filepath.Base(path) {
+                t.Fatalf("%s: info of %s doesn't match %s", format, info.Name(), path)
+            }
+            paths = append(paths, path)
+        }}
+        c, err := NewContainer(format, opts)
+        if err != nil {
+            t.Fatalf("container: %v", err)
+        }
+        if _, err := c.Pack(root); err != nil {
+            t.Fatalf("%s pack: %v", format, err)
+        }
+        if !reflect.DeepEqual(paths, want) {
+            t.Fatalf("%s: unexpected packed paths:\n got %v\nwant %v", format, paths, want)
+        }
+        paths = nil
+        if _, err := c.PackStream("stream", strings.NewReader("x")); err != nil || len(paths) != 0 {
+            t.Fatalf("%s: expected streams not to be reported, got %v %v", format, paths, err)
+        }
+    }
+}
@@ -0,0 +1,262 @@
+package zipper
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsafePath is returned for archive entries which would be written outside of the destination
+var ErrUnsafePath = errors.New("unsafe path in archive")
+
+// metadata is the file metadata a container recorded for an entry. Only the parts flagged as
+// present are restored.
+type metadata struct {
+	mode     os.FileMode
+	hasMode  bool
+	mtime    time.Time
+	hasMtime bool
+	uid      int
+	gid      int
+	hasOwner bool
+	xattrs   map[string]string
+}
+
+// extractor recreates container entries below destDir. It is shared by all container formats.
+type extractor struct {
+	// ctx stops the extraction once it's done
+	ctx     context.Context
+	destDir string
+	// writeDir is where entries are written to, a staging directory or destDir itself
+	writeDir string
+	dirs     []pendingDir
+	// policy decides what happens to entries whose path already exists
+	policy    string
+	decisions map[string]decision
+	// taken are the names used by entries, renamed entries must not clash with them
+	taken     map[string]bool
+	conflicts []Conflict
+	// extracted are the target names of the non-directory entries written
+	extracted []string
+	// journal tracks the written files of resumable extractions
+	journal Journal
+}
+
+// pendingDir is a directory whose metadata is applied once all of its content is written
+type pendingDir struct {
+	path string
+	meta metadata
+}
+
+func newExtractor(ctx context.Context, destDir string, opts Options) *extractor {
+	writeDir := destDir
+	if opts.StagingDir != "" {
+		writeDir = opts.StagingDir
+	}
+	return &extractor{ctx: ctx, destDir: destDir, writeDir: writeDir, policy: opts.OnConflict, journal: opts.Journal}
+}
+
+// dir creates a directory. It is created writable and only gets its recorded metadata in
+// finish, otherwise a read-only dir would block the extraction of its content.
+func (x *extractor) dir(name string, meta metadata) error {
+	target, skip, err := x.resolve(name, true)
+	if err != nil || skip {
+		return err
+	}
+	if err := osMkdirAllFn(target, 0755); err != nil {
+		return err
+	}
+	x.dirs = append(x.dirs, pendingDir{path: target, meta: meta})
+	return nil
+}
+
+// file writes a regular file with the content read from r
+func (x *extractor) file(name string, r io.Reader, meta metadata) error {
+	target, skip, err := x.prepare(name)
+	if err != nil || skip {
+		return err
+	}
+
+	sum := sha256.New()
+	if x.journal != nil {
+		// resumed extractions keep what an earlier run already wrote
+		if x.journal.Done(name, target) {
+			return restoreMetadata(target, meta, false)
+		}
+		r = io.TeeReader(r, sum)
+	}
+
+	// whatever is left at the target, like a partly written file of a resumed run, is replaced
+	// instead of truncated. O_EXCL never follows a symlink, so the content can't end up
+	// outside of the destination even if one got there in between.
+	if info, err := osLstatFn(target); err == nil && !info.IsDir() {
+		if err := osRemoveFn(target); err != nil {
+			return err
+		}
+	}
+	fw, err := osOpenFileFn(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = ioCopyFn(fw, &ctxReader{ctx: x.ctx, r: r})
+	if err == nil && x.journal != nil {
+		// the journal may only claim what really reached the disk
+		err = fw.Sync()
+	}
+	fw.Close()
+	if err != nil {
+		return err
+	}
+	if err := restoreMetadata(target, meta, false); err != nil {
+		return err
+	}
+	if x.journal == nil {
+		return nil
+	}
+	return x.journal.Add(name, sum.Sum(nil))
+}
+
+// symlink recreates a symlink pointing to linkTarget
+func (x *extractor) symlink(name string, linkTarget string, meta metadata) error {
+	target, skip, err := x.prepare(name)
+	if err != nil || skip {
+		return err
+	}
+	if err := replaceWith(target, func() error { return osSymlinkFn(linkTarget, target) }); err != nil {
+		return err
+	}
+	return restoreMetadata(target, meta, true)
+}
+
+// hardlink links name to the already extracted entry first. Links to an entry which was
+// skipped are skipped as well, they'd otherwise share the content of the existing file.
+func (x *extractor) hardlink(name string, first string) error {
+	if x.skipped(first) {
+		if !x.skipped(name) {
+			x.conflicts = append(x.conflicts, Conflict{Name: cleanName(name), Action: ConflictSkip})
+			x.decisions[cleanName(name)] = decision{skip: true}
+		}
+		return nil
+	}
+	target, skip, err := x.prepare(name)
+	if err != nil || skip {
+		return err
+	}
+	firstTarget := cleanName(first)
+	if d, ok := x.decisions[firstTarget]; ok {
+		firstTarget = d.target
+	}
+	source, err := safeJoin(x.writeDir, firstTarget)
+	if err != nil {
+		return err
+	}
+	return replaceWith(target, func() error { return osLinkFn(source, target) })
+}
+
+// special recreates device files and fifos; creating devices requires root
+func (x *extractor) special(name string, major, minor int64, meta metadata) error {
+	target, skip, err := x.prepare(name)
+	if err != nil || skip {
+		return err
+	}
+	if err := replaceWith(target, func() error { return mknod(target, meta.mode, major, minor) }); err != nil {
+		return err
+	}
+	return restoreMetadata(target, meta, false)
+}
+
+// finish applies the recorded directory metadata, deepest first so parent mtimes aren't bumped again
+func (x *extractor) finish() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		if err := restoreMetadata(x.dirs[i].path, x.dirs[i].meta, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare resolves the target path of a non-directory entry and creates its parent directories
+func (x *extractor) prepare(name string) (string, bool, error) {
+	target, skip, err := x.resolve(name, false)
+	if err != nil || skip {
+		return "", skip, err
+	}
+	if err := osMkdirAllFn(filepath.Dir(target), 0755); err != nil {
+		return "", false, err
+	}
+	x.extracted = append(x.extracted, x.decisions[cleanName(name)].target)
+	return target, false, nil
+}
+
+// replaceWith removes whatever non-directory is at target before creating the link, since
+// links can't be created over existing files
+func replaceWith(target string, create func() error) error {
+	if info, err := osLstatFn(target); err == nil && !info.IsDir() {
+		if err := osRemoveFn(target); err != nil {
+			return err
+		}
+	}
+	return create()
+}
+
+// safeJoin resolves an archive entry name below destDir. Names escaping destDir and names
+// leading through a symlink inside destDir are rejected so crafted archives can't write
+// outside of the destination.
+func safeJoin(destDir, name string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	parent := destDir
+	parts := strings.Split(filepath.Dir(rel), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		parent = filepath.Join(parent, part)
+		info, err := osLstatFn(parent)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s leads through a symlink", ErrUnsafePath, name)
+		}
+	}
+	return filepath.Join(destDir, rel), nil
+}
+
+// restoreMetadata applies the recorded ownership, mode bits, extended attributes and
+// modification time to the extracted path
+func restoreMetadata(target string, meta metadata, isSymlink bool) error {
+	if meta.hasOwner && osGeteuidFn() == 0 {
+		if err := osLchownFn(target, meta.uid, meta.gid); err != nil {
+			return err
+		}
+	}
+	// extended attributes are best effort, the target file system might not support them
+	for name, value := range meta.xattrs {
+		_ = setXattr(target, name, value)
+	}
+	// chmod and chtimes would follow the link, so symlinks only get their owner restored
+	if isSymlink {
+		return nil
+	}
+	if meta.hasMode {
+		if err := osChmodFn(target, meta.mode.Perm()|meta.mode&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+	}
+	if meta.hasMtime {
+		if err := osChtimesFn(target, meta.mtime, meta.mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package zipper
+
+import (
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestPack_MultiplePathsAndCollisions(t *testing.T) {
+    tmp := t.TempDir()
+    keys := filepath.Join(tmp, "home", "keys")
+    notesA := filepath.Join(tmp, "a", "notes.md")
+    notesB := filepath.Join(tmp, "b", "notes.md")
+    keysB := filepath.Join(tmp, "b", "keys")
+    writeFile(t, filepath.Join(keys, "id_ed25519"), []byte("secret key"))
+    writeFile(t, notesA, []byte("first notes"))
+    writeFile(t, notesB, []byte("second notes"))
+    writeFile(t, keysB, []byte("not a dir"))
+
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        c, err := NewContainer(format, Options{})
+        if err != nil {
+            t.Fatalf("container %s: %v", format, err)
+        }
+        // the repeated notesA path has to be stored once only
+        data, err := c.Pack(keys, notesA, notesB, notesA, keysB)
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        dest := filepath.Join(tmp, "out-"+format)
+        if err := c.Unpack(data, dest); err != nil {
+            t.Fatalf("unpack %s: %v", format, err)
+        }
+
+        want := map[string]string{
+            filepath.Join("keys", "id_ed25519"): "secret key",
+            "notes.md":                          "first notes",
+            "notes_2.md":                        "second notes",
+            "keys_2":                            "not a dir",
+        }
+        for name, content := range want {
+            if got := readFile(t, filepath.Join(dest, name)); string(got) != content {
+                t.Fatalf("%s: %s mismatch: %q", format, name, got)
+            }
+        }
+    }
+}
+
+func TestTopLevelNames(t *testing.T) {
+    paths, names := topLevelNames([]string{"/x/.bashrc", "/y/.bashrc", "/x/.bashrc/", "/z/archive.tar.gz", "/w/archive.tar.gz"})
+    if !reflect.DeepEqual(paths, []string{"/x/.bashrc", "/y/.bashrc", "/z/archive.tar.gz", "/w/archive.tar.gz"}) {
+        t.Fatalf("unexpected paths: %v", paths)
+    }
+    if !reflect.DeepEqual(names, []string{".bashrc", ".bashrc_2", "archive.tar.gz", "archive.tar_2.gz"}) {
+        t.Fatalf("unexpected names: %v", names)
+    }
+}
+
+func TestPack_NoPaths(t *testing.T) {
+    if _, err := New().Pack(); err == nil {
+        t.Fatalf("expected error without input paths")
+    }
+}
@@ -0,0 +1,68 @@
+package zipper
+
+import (
+    "crypto/sha256"
+    "path/filepath"
+    "testing"
+)
+
+// fakeJournal records added files and reports the configured names as done
+type fakeJournal struct {
+    done  map[string]bool
+    added map[string][]byte
+}
+
+func (j *fakeJournal) Done(name, path string) bool { return j.done[name] }
+
+func (j *fakeJournal) Add(name string, sum []byte) error {
+    j.added[name] = sum
+    return nil
+}
+
+func TestUnpack_ResumesWithJournal(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "src")
+    writeFile(t, filepath.Join(src, "a.txt"), []byte("alpha"))
+    writeFile(t, filepath.Join(src, "sub", "b.txt"), []byte("beta"))
+
+    for _, format := range []string{FormatZip, FormatTar} {
+        t.Run(format, func(t *testing.T) {
+            packer, _ := NewContainer(format, Options{})
+            data, err := packer.Pack(src)
+            if err != nil {
+                t.Fatalf("pack: %v", err)
+            }
+            // resumed extractions happen in a staging directory which survived the interruption
+            dest := filepath.Join(t.TempDir(), "out")
+            staging := t.TempDir()
+
+            j := &fakeJournal{done: map[string]bool{}, added: map[string][]byte{}}
+            c, _ := NewContainer(format, Options{Journal: j, StagingDir: staging})
+            if err := c.Unpack(data, dest); err != nil {
+                t.Fatalf("unpack: %v", err)
+            }
+            want := sha256.Sum256([]byte("alpha"))
+            if len(j.added) != 2 || string(j.added["src/a.txt"]) != string(want[:]) {
+                t.Fatalf("unexpected journal records: %v", j.added)
+            }
+
+            // files the journal reports as done are kept as they are, the others written again
+            writeFile(t, filepath.Join(staging, "src", "a.txt"), []byte("kept"))
+            writeFile(t, filepath.Join(staging, "src", "sub", "b.txt"), []byte("torn"))
+            j = &fakeJournal{done: map[string]bool{"src/a.txt": true}, added: map[string][]byte{}}
+            c, _ = NewContainer(format, Options{Journal: j, StagingDir: staging})
+            if err := c.Unpack(data, dest); err != nil {
+                t.Fatalf("resumed unpack: %v", err)
+            }
+            if got := readFile(t, filepath.Join(staging, "src", "a.txt")); string(got) != "kept" {
+                t.Fatalf("expected done file to be kept, got %q", got)
+            }
+            if got := readFile(t, filepath.Join(staging, "src", "sub", "b.txt")); string(got) != "beta" {
+                t.Fatalf("expected torn file to be rewritten, got %q", got)
+            }
+            if len(j.added) != 1 {
+                t.Fatalf("expected only the rewritten file to be journaled, got %v", j.added)
+            }
+        })
+    }
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package zipper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fileOwner reports no ownership on platforms without unix uid/gid
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// fileKey falls back to the resolved path on platforms without inode numbers, which is enough
+// to detect directory loops but not hardlinks
+func fileKey(path string, info os.FileInfo) (string, bool, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false, err
+	}
+	return resolved, false, nil
+}
+
+// mknod is not supported on platforms without unix device files
+func mknod(path string, mode os.FileMode, major, minor int64) error {
+	return fmt.Errorf("special files are not supported on this platform: %s", path)
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package zipper
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the uid/gid of the given file info if the platform exposes them
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// fileKey identifies the inode behind the given file so hardlinks and directory loops can be
+// detected. The second return value reports whether the file may have other hardlinks.
+func fileKey(path string, info os.FileInfo) (string, bool, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return path, false, nil
+	}
+	key := strconv.FormatUint(uint64(st.Dev), 10) + ":" + strconv.FormatUint(uint64(st.Ino), 10)
+	return key, st.Nlink > 1, nil
+}
+
+// mknod creates a device file or fifo with the given mode
+func mknod(path string, mode os.FileMode, major, minor int64) error {
+	perm := uint32(mode.Perm())
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return syscall.Mkfifo(path, perm)
+	case mode&os.ModeCharDevice != 0:
+		return syscall.Mknod(path, syscall.S_IFCHR|perm, int(mkdev(major, minor)))
+	case mode&os.ModeDevice != 0:
+		return syscall.Mknod(path, syscall.S_IFBLK|perm, int(mkdev(major, minor)))
+	}
+	return fmt.Errorf("unsupported special file mode %s for %s", mode, path)
+}
+
+// mkdev combines major and minor numbers the way glibc's makedev does
+func mkdev(major, minor int64) uint64 {
+	ma, mi := uint64(major), uint64(minor)
+	return (ma&0xfff)<<8 | (ma&^0xfff)<<32 | mi&0xff | (mi&^0xff)<<12
+}
@@ -0,0 +1,143 @@
+package zipper
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// streamInfo describes data read from a stream, which has no file system metadata of its own.
+// It is stored as a private regular file modified at the time it was read.
+type streamInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *streamInfo) Name() string       { return s.name }
+func (s *streamInfo) Size() int64        { return s.size }
+func (s *streamInfo) Mode() os.FileMode  { return 0600 }
+func (s *streamInfo) ModTime() time.Time { return s.modTime }
+func (s *streamInfo) IsDir() bool        { return false }
+func (s *streamInfo) Sys() interface{}   { return nil }
+
+// streamEntry returns the entry storing everything read from r under name. Formats which
+// need the size in advance have the stream buffered by setting sized.
+func streamEntry(name string, r io.Reader, sized bool) (*entry, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid stream entry name %q, a plain file name is required", name)
+	}
+	info := &streamInfo{name: name, modTime: time.Now()}
+	if sized {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		info.size = int64(len(data))
+		r = bytes.NewReader(data)
+	}
+	return &entry{name: name, info: info, reader: r}, nil
+}
+
+// PackStream implements Container by zipping everything read from r as a single file entry
+func (z *Zipper) PackStream(name string, r io.Reader) ([]byte, error) {
+	return z.PackStreamContext(context.Background(), name, r)
+}
+
+// PackStreamContext implements Container
+func (z *Zipper) PackStreamContext(ctx context.Context, name string, r io.Reader) ([]byte, error) {
+	e, err := streamEntry(name, r, false)
+	if err != nil {
+		return []byte{}, err
+	}
+	return z.writeZip(func(w *zip.Writer) error { return z.zipFile(ctx, e, w) })
+}
+
+// UnpackStream implements Container by writing the content of the only file in the zip data to w
+func (z *Zipper) UnpackStream(data []byte, w io.Writer) (string, error) {
+	return z.UnpackStreamContext(context.Background(), data, w)
+}
+
+// UnpackStreamContext implements Container
+func (z *Zipper) UnpackStreamContext(ctx context.Context, data []byte, w io.Writer) (string, error) {
+	reader, err := zipNewReaderFn(data)
+	if err != nil {
+		return "", err
+	}
+	if len(reader.File) != 1 || !reader.File[0].Mode().IsRegular() {
+		return "", singleEntryError(len(reader.File))
+	}
+	f := reader.File[0]
+	if _, ok := findExtra(f.Extra, extraHardlink); ok {
+		return "", singleEntryError(len(reader.File))
+	}
+	rc, err := zipFileOpenFn(f)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	if _, err := ioCopyFn(w, &ctxReader{ctx: ctx, r: rc}); err != nil {
+		return "", err
+	}
+	return f.Name, nil
+}
+
+// PackStream implements Container by storing everything read from r as a single tar file entry.
+// tar headers carry the size of an entry, so the stream is read into memory first.
+func (t *Tarrer) PackStream(name string, r io.Reader) ([]byte, error) {
+	return t.PackStreamContext(context.Background(), name, r)
+}
+
+// PackStreamContext implements Container
+func (t *Tarrer) PackStreamContext(ctx context.Context, name string, r io.Reader) ([]byte, error) {
+	e, err := streamEntry(name, &ctxReader{ctx: ctx, r: r}, true)
+	if err != nil {
+		return []byte{}, err
+	}
+	buf := &bytes.Buffer{}
+	if err := t.write(buf, func(tw *tar.Writer) error { return t.tarFile(ctx, e, tw) }); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnpackStream implements Container by writing the content of the only file in the tar data to w.
+// The archive is checked completely before anything is written.
+func (t *Tarrer) UnpackStream(data []byte, w io.Writer) (string, error) {
+	return t.UnpackStreamContext(context.Background(), data, w)
+}
+
+// UnpackStreamContext implements Container
+func (t *Tarrer) UnpackStreamContext(ctx context.Context, data []byte, w io.Writer) (string, error) {
+	var name string
+	count := 0
+	err := t.read(bytes.NewReader(data), func(hdr *tar.Header, _ io.Reader) error {
+		count++
+		if hdr.Typeflag == tar.TypeReg {
+			name = hdr.Name
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if count != 1 || name == "" {
+		return "", singleEntryError(count)
+	}
+
+	err = t.read(bytes.NewReader(data), func(_ *tar.Header, r io.Reader) error {
+		_, err := ioCopyFn(w, &ctxReader{ctx: ctx, r: r})
+		return err
+	})
+	return name, err
+}
+
+func singleEntryError(count int) error {
+	return fmt.Errorf("archive holds %d entries, writing to a stream needs a single file", count)
+}
@@ -0,0 +1,69 @@
+package zipper
+
+import (
+    "bytes"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestStream_RoundTrip(t *testing.T) {
+    payload := strings.Repeat("INSERT INTO t VALUES (1);\n", 1000)
+    for _, format := range []string{FormatZip, FormatTar, FormatTarZstd} {
+        c, err := NewContainer(format, Options{})
+        if err != nil {
+            t.Fatalf("container %s: %v", format, err)
+        }
+        data, err := c.PackStream("dump.sql", strings.NewReader(payload))
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+
+        var out bytes.Buffer
+        name, err := c.UnpackStream(data, &out)
+        if err != nil {
+            t.Fatalf("unpack %s: %v", format, err)
+        }
+        if name != "dump.sql" || out.String() != payload {
+            t.Fatalf("%s: unexpected stream result %q (%d bytes)", format, name, out.Len())
+        }
+
+        // a stream entry is a regular file when extracted into a directory as well
+        dest := filepath.Join(t.TempDir(), "out")
+        if err := c.Unpack(data, dest); err != nil {
+            t.Fatalf("extract %s: %v", format, err)
+        }
+        if got := readFile(t, filepath.Join(dest, "dump.sql")); string(got) != payload {
+            t.Fatalf("%s: extracted content mismatch", format)
+        }
+    }
+}
+
+func TestUnpackStream_RejectsMultipleEntries(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "dir")
+    writeFile(t, filepath.Join(root, "a.txt"), []byte("a"))
+
+    for _, format := range []string{FormatZip, FormatTarZstd} {
+        c, _ := NewContainer(format, Options{})
+        data, err := c.Pack(root)
+        if err != nil {
+            t.Fatalf("pack %s: %v", format, err)
+        }
+        var out bytes.Buffer
+        if _, err := c.UnpackStream(data, &out); err == nil || !strings.Contains(err.Error(), "2 entries") {
+            t.Fatalf("%s: expected rejection of a directory archive, got %v", format, err)
+        }
+        if out.Len() != 0 {
+            t.Fatalf("%s: expected nothing to be written, got %q", format, out.String())
+        }
+    }
+}
+
+func TestPackStream_InvalidName(t *testing.T) {
+    for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+        if _, err := New().PackStream(name, strings.NewReader("x")); err == nil {
+            t.Fatalf("expected name %q to be rejected", name)
+        }
+    }
+}
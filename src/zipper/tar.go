@@ -0,0 +1,298 @@
+package zipper
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// paxXattrPrefix is the PAX record prefix GNU tar and others use for extended attributes
+const paxXattrPrefix = "SCHILY.xattr."
+
+// Tarrer is the tar Container implementation. Unlike zip it needs no seeking, carries
+// extended attributes, device files and long ownership data, and can optionally be
+// zstd compressed as a whole.
+type Tarrer struct {
+	Options
+	// Zstd compresses the whole tar stream with zstd
+	Zstd bool
+	// conflicts are the ones resolved by the last extraction, extracted what it wrote
+	conflicts []Conflict
+	extracted []string
+}
+
+func NewTar(zstdCompressed bool) *Tarrer {
+	return &Tarrer{Zstd: zstdCompressed}
+}
+
+// validate makes sure the compression settings fit the tar flavour
+func (t *Tarrer) validate() error {
+	want := CompressionStore
+	if t.Zstd {
+		want = CompressionZstd
+	}
+	if t.Compression != "" && t.Compression != want {
+		return fmt.Errorf("compression %s can't be used with the %s format", t.Compression, t.format())
+	}
+	return ValidateCompression(want, t.Level)
+}
+
+func (t *Tarrer) format() string {
+	if t.Zstd {
+		return FormatTarZstd
+	}
+	return FormatTar
+}
+
+// Pack implements Container by writing the tar stream into memory
+func (t *Tarrer) Pack(paths ...string) ([]byte, error) {
+	return t.PackContext(context.Background(), paths...)
+}
+
+// PackContext implements Container
+func (t *Tarrer) PackContext(ctx context.Context, paths ...string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := t.writePaths(ctx, buf, paths); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unpack implements Container by reading the tar stream from memory. All entries are checked
+// for conflicts with existing files before anything is written.
+func (t *Tarrer) Unpack(data []byte, destDir string) error {
+	return t.UnpackContext(context.Background(), data, destDir)
+}
+
+// UnpackContext implements Container
+func (t *Tarrer) UnpackContext(ctx context.Context, data []byte, destDir string) error {
+	if err := ValidateConflictPolicy(t.OnConflict); err != nil {
+		return err
+	}
+	x := newExtractor(ctx, destDir, t.Options)
+	defer func() { t.conflicts, t.extracted = x.conflicts, x.extracted }()
+	var entries []planEntry
+	err := t.read(bytes.NewReader(data), func(hdr *tar.Header, _ io.Reader) error {
+		entries = append(entries, planEntry{name: hdr.Name, isDir: hdr.Typeflag == tar.TypeDir})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := x.plan(entries); err != nil {
+		return err
+	}
+	return t.extract(bytes.NewReader(data), x)
+}
+
+// Conflicts implements Container
+func (t *Tarrer) Conflicts() []Conflict {
+	return t.conflicts
+}
+
+// Extracted implements Container
+func (t *Tarrer) Extracted() []string {
+	return t.extracted
+}
+
+// Write streams the given paths as tar archive into w
+func (t *Tarrer) Write(w io.Writer, paths ...string) error {
+	return t.writePaths(context.Background(), w, paths)
+}
+
+// writePaths streams the given paths as tar archive into w until ctx is done
+func (t *Tarrer) writePaths(ctx context.Context, w io.Writer, paths []string) error {
+	return t.write(w, func(tw *tar.Writer) error {
+		return t.walk(ctx, paths, func(e *entry) error {
+			if err := t.tarFile(ctx, e, tw); err != nil {
+				return err
+			}
+			t.packed(e)
+			return nil
+		})
+	})
+}
+
+// write streams the tar archive holding the entries fn adds to the tar writer into w
+func (t *Tarrer) write(w io.Writer, fn func(tw *tar.Writer) error) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	out := w
+	var zw *zstd.Encoder
+	if t.Zstd {
+		level := zstd.SpeedDefault
+		if t.Level != 0 {
+			level = zstd.EncoderLevelFromZstd(t.Level)
+		}
+		var err error
+		zw, err = zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+		if err != nil {
+			return err
+		}
+		out = zw
+	}
+
+	tw := tar.NewWriter(out)
+	err := fn(tw)
+	if closeErr := tw.Close(); err == nil {
+		err = closeErr
+	}
+	if zw != nil {
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (t *Tarrer) tarFile(ctx context.Context, e *entry, tw *tar.Writer) error {
+	hdr, err := tar.FileInfoHeader(e.info, e.link)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, e.path)
+	}
+	hdr.Name = e.name
+	hdr.Format = tar.FormatPAX
+	if e.info.IsDir() {
+		hdr.Name += "/"
+	}
+	if e.hardlink != "" {
+		hdr.Typeflag = tar.TypeLink
+		hdr.Linkname = e.hardlink
+		hdr.Size = 0
+	}
+
+	if t.StripMetadata {
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		hdr.ModTime, hdr.AccessTime, hdr.ChangeTime = time.Unix(0, 0), time.Time{}, time.Time{}
+		switch {
+		case e.info.IsDir():
+			hdr.Mode = 0755
+		case hdr.Typeflag == tar.TypeSymlink:
+			hdr.Mode = 0777
+		default:
+			hdr.Mode = 0644
+		}
+	} else {
+		// access and change times aren't restored, storing them would only leak information
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if attrs, err := listXattrs(e.path); err == nil && len(attrs) > 0 {
+			hdr.PAXRecords = map[string]string{}
+			for name, value := range attrs {
+				hdr.PAXRecords[paxXattrPrefix+name] = value
+			}
+		}
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	f, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = ioCopyFn(tw, &ctxReader{ctx: ctx, r: f})
+	return err
+}
+
+// Read extracts the tar stream read from r into destDir. As the stream can only be read once,
+// conflicts with existing files are checked entry by entry while extracting.
+func (t *Tarrer) Read(r io.Reader, destDir string) error {
+	if err := ValidateConflictPolicy(t.OnConflict); err != nil {
+		return err
+	}
+	x := newExtractor(context.Background(), destDir, t.Options)
+	defer func() { t.conflicts, t.extracted = x.conflicts, x.extracted }()
+	return t.extract(r, x)
+}
+
+// extract writes the entries of the tar stream read from r using x
+func (t *Tarrer) extract(r io.Reader, x *extractor) error {
+	err := t.read(r, func(hdr *tar.Header, tr io.Reader) error {
+		if err := x.ctx.Err(); err != nil {
+			return err
+		}
+		meta := tarMetadata(hdr)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			return x.dir(hdr.Name, meta)
+		case tar.TypeReg:
+			return x.file(hdr.Name, tr, meta)
+		case tar.TypeSymlink:
+			return x.symlink(hdr.Name, hdr.Linkname, meta)
+		case tar.TypeLink:
+			return x.hardlink(hdr.Name, hdr.Linkname)
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			return x.special(hdr.Name, hdr.Devmajor, hdr.Devminor, meta)
+		default:
+			return fmt.Errorf("unsupported tar entry type %q for %s", hdr.Typeflag, hdr.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return x.finish()
+}
+
+// read calls fn for every entry of the tar stream read from r, the reader passed along
+// providing the content of the entry
+func (t *Tarrer) read(r io.Reader, fn func(hdr *tar.Header, tr io.Reader) error) error {
+	in := r
+	if t.Zstd {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		in = zr
+	}
+
+	tr := tar.NewReader(in)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// tarMetadata collects the metadata recorded in a tar header. A zero mtime marks archives
+// written with stripped metadata.
+func tarMetadata(hdr *tar.Header) metadata {
+	meta := metadata{
+		mode:     hdr.FileInfo().Mode(),
+		hasMode:  true,
+		mtime:    hdr.ModTime,
+		hasMtime: hdr.ModTime.Unix() != 0,
+		uid:      hdr.Uid,
+		gid:      hdr.Gid,
+		hasOwner: true,
+	}
+	for key, value := range hdr.PAXRecords {
+		if strings.HasPrefix(key, paxXattrPrefix) {
+			if meta.xattrs == nil {
+				meta.xattrs = map[string]string{}
+			}
+			meta.xattrs[strings.TrimPrefix(key, paxXattrPrefix)] = value
+		}
+	}
+	return meta
+}
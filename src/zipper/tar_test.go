@@ -0,0 +1,102 @@
+package zipper
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestTar_RoundTrip(t *testing.T) {
+    for _, compressed := range []bool{false, true} {
+        tmp := t.TempDir()
+        root := filepath.Join(tmp, "tree")
+        writeFile(t, filepath.Join(root, "a", "file1.txt"), []byte("alpha"))
+        writeFile(t, filepath.Join(root, "b", "run.sh"), bytes.Repeat([]byte("echo beta\n"), 100))
+        if err := os.Chmod(filepath.Join(root, "b", "run.sh"), 0o700); err != nil {
+            t.Fatalf("chmod: %v", err)
+        }
+        if err := os.MkdirAll(filepath.Join(root, "empty"), 0o755); err != nil {
+            t.Fatalf("mkdir: %v", err)
+        }
+        if err := os.Symlink("a/file1.txt", filepath.Join(root, "link")); err != nil {
+            t.Fatalf("symlink: %v", err)
+        }
+        if err := os.Link(filepath.Join(root, "a", "file1.txt"), filepath.Join(root, "hard")); err != nil {
+            t.Fatalf("link: %v", err)
+        }
+        mtime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+        if err := os.Chtimes(filepath.Join(root, "a", "file1.txt"), mtime, mtime); err != nil {
+            t.Fatalf("chtimes: %v", err)
+        }
+
+        tr := NewTar(compressed)
+        data, err := tr.Pack(root)
+        if err != nil {
+            t.Fatalf("pack (zstd=%v): %v", compressed, err)
+        }
+        dest := filepath.Join(tmp, "out")
+        if err := NewTar(compressed).Unpack(data, dest); err != nil {
+            t.Fatalf("unpack (zstd=%v): %v", compressed, err)
+        }
+
+        restored := filepath.Join(dest, "tree")
+        if got := readFile(t, filepath.Join(restored, "a", "file1.txt")); string(got) != "alpha" {
+            t.Fatalf("content mismatch: %q", got)
+        }
+        info, err := os.Stat(filepath.Join(restored, "b", "run.sh"))
+        if err != nil || info.Mode().Perm() != 0o700 {
+            t.Fatalf("mode not restored: %v %v", err, info)
+        }
+        info, err = os.Stat(filepath.Join(restored, "a", "file1.txt"))
+        if err != nil || !info.ModTime().Equal(mtime) {
+            t.Fatalf("mtime not restored: %v %v", err, info)
+        }
+        if target, err := os.Readlink(filepath.Join(restored, "link")); err != nil || target != "a/file1.txt" {
+            t.Fatalf("symlink not restored: %q %v", target, err)
+        }
+        hard, err := os.Stat(filepath.Join(restored, "hard"))
+        if err != nil || !os.SameFile(hard, info) {
+            t.Fatalf("hardlink not restored: %v", err)
+        }
+        if info, err := os.Stat(filepath.Join(restored, "empty")); err != nil || !info.IsDir() {
+            t.Fatalf("empty dir not restored: %v", err)
+        }
+    }
+}
+
+func TestTar_StreamWriteAndRead(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "stream.txt")
+    writeFile(t, src, []byte("streamed"))
+
+    var buf bytes.Buffer
+    if err := NewTar(true).Write(&buf, src); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := NewTar(true).Read(&buf, dest); err != nil {
+        t.Fatalf("read: %v", err)
+    }
+    if got := readFile(t, filepath.Join(dest, "stream.txt")); string(got) != "streamed" {
+        t.Fatalf("content mismatch: %q", got)
+    }
+}
+
+func TestNewContainer(t *testing.T) {
+    for _, format := range []string{"", FormatZip, FormatTar, FormatTarZstd} {
+        if _, err := NewContainer(format, Options{}); err != nil {
+            t.Fatalf("format %q: %v", format, err)
+        }
+    }
+    if _, err := NewContainer("rar", Options{}); err == nil {
+        t.Fatalf("expected unknown format to be rejected")
+    }
+    if _, err := NewContainer(FormatTar, Options{Compression: CompressionDeflate}); err == nil {
+        t.Fatalf("expected deflate to be rejected for tar")
+    }
+    if _, err := NewContainer(FormatTarZstd, Options{Compression: CompressionZstd, Level: 19}); err != nil {
+        t.Fatalf("expected zstd level to be accepted for tar.zst: %v", err)
+    }
+}
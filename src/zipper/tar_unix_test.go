@@ -0,0 +1,38 @@
+//go:build unix
+
+package zipper
+
+import (
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+)
+
+func TestTar_Fifo(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0o640); err != nil {
+        t.Skipf("mkfifo not supported: %v", err)
+    }
+
+    if _, err := New().Zip(root); err == nil {
+        t.Fatalf("expected zip to reject special files")
+    }
+
+    data, err := NewTar(false).Pack(root)
+    if err != nil {
+        t.Fatalf("pack: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := NewTar(false).Unpack(data, dest); err != nil {
+        t.Fatalf("unpack: %v", err)
+    }
+    info, err := os.Lstat(filepath.Join(dest, "tree", "pipe"))
+    if err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+        t.Fatalf("fifo not restored: %v %v", err, info)
+    }
+}
@@ -0,0 +1,227 @@
+package zipper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/voodooEntity/go-tachicrypt/src/ignore"
+)
+
+// entry is a single file system object found while walking the input
+type entry struct {
+	// path is the location on disk
+	path string
+	// name is the slash separated name inside the container
+	name string
+	info os.FileInfo
+	// link is the target of a symlink
+	link string
+	// hardlink is the container name of the entry already holding the content of this inode
+	hardlink string
+	// reader provides the content of entries read from a stream instead of a file on disk
+	reader io.Reader
+}
+
+// open returns the content of a regular file entry
+func (e *entry) open() (io.ReadCloser, error) {
+	if e.reader != nil {
+		return io.NopCloser(e.reader), nil
+	}
+	return osOpenFn(e.path)
+}
+
+// walker tracks inodes seen while walking so hardlinks are stored once and directory loops are caught
+type walker struct {
+	ctx    context.Context
+	follow bool
+	inodes map[string]string
+	active map[string]bool
+}
+
+// walk calls fn for the given paths and, for directories, everything below them that isn't
+// excluded by the Excludes patterns or an ignore file. Every path becomes a top-level entry
+// and parents are always visited before their children. Walking stops once ctx is done.
+func (o *Options) walk(ctx context.Context, roots []string, fn func(e *entry) error) error {
+	if len(roots) == 0 {
+		return fmt.Errorf("no input paths given")
+	}
+	matcher, err := ignore.New(o.Excludes)
+	if err != nil {
+		return fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	w := &walker{ctx: ctx, follow: o.FollowSymlinks, inodes: map[string]string{}, active: map[string]bool{}}
+	roots, names := topLevelNames(roots)
+	for i, root := range roots {
+		if err := w.walk(root, names[i], "", matcher, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topLevelNames drops paths given more than once and returns the remaining paths together
+// with their unique top-level container names. Inputs sharing a base name, like a/notes.md
+// and b/notes.md, get a counter appended: notes.md, notes_2.md, ...
+func topLevelNames(roots []string) ([]string, []string) {
+	var paths, names []string
+	seenPaths := map[string]bool{}
+	taken := map[string]bool{}
+	for _, root := range roots {
+		key := filepath.Clean(root)
+		if abs, err := filepath.Abs(root); err == nil {
+			key = abs
+		}
+		if seenPaths[key] {
+			continue
+		}
+		seenPaths[key] = true
+
+		base := filepath.Base(key)
+		name := base
+		ext := filepath.Ext(base)
+		if ext == base {
+			// dotfiles like .bashrc have no extension to keep
+			ext = ""
+		}
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(base, ext), i, ext)
+		}
+		taken[name] = true
+		paths = append(paths, root)
+		names = append(names, name)
+	}
+	return paths, names
+}
+
+// List returns the container names of everything hiding the given paths would include,
+// directories carrying a trailing slash
+func (o *Options) List(roots ...string) ([]string, error) {
+	var names []string
+	err := o.walk(context.Background(), roots, func(e *entry) error {
+		if e.info.IsDir() {
+			names = append(names, e.name+"/")
+		} else {
+			names = append(names, e.name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Sources returns the locations on disk of everything hiding the given paths would include,
+// parents before their children
+func (o *Options) Sources(roots ...string) ([]string, error) {
+	var paths []string
+	err := o.walk(context.Background(), roots, func(e *entry) error {
+		paths = append(paths, e.path)
+		return nil
+	})
+	return paths, err
+}
+
+// packed reports an entry read from disk to the Packed callback
+func (o *Options) packed(e *entry) {
+	if o.Packed != nil && e.reader == nil {
+		o.Packed(e.path, e.info)
+	}
+}
+
+// walk handles a single path stored as name. rel is the path relative to the walked root
+// which exclude patterns are matched against, "" for the root itself which is never excluded.
+func (w *walker) walk(filePath string, name string, rel string, matcher ignore.Matcher, fn func(e *entry) error) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	statFn := osLstatFn
+	if w.follow {
+		statFn = osStatFn
+	}
+	info, err := statFn(filePath)
+	if err != nil {
+		return err
+	}
+	if rel != "" && matcher.Match(rel, info.IsDir()) {
+		return nil
+	}
+
+	e := &entry{
+		path: filePath,
+		name: name,
+		info: info,
+	}
+
+	switch {
+	case info.IsDir():
+		// a directory already being walked further up means a symlink led us back into it
+		key, _, err := fileKey(filePath, info)
+		if err != nil {
+			return err
+		}
+		if w.active[key] {
+			return fmt.Errorf("symlink loop detected at %s", filePath)
+		}
+		w.active[key] = true
+		defer delete(w.active, key)
+
+		if err := fn(e); err != nil {
+			return err
+		}
+		matcher, err = addIgnoreFile(matcher, filePath, rel)
+		if err != nil {
+			return err
+		}
+		files, err := osReadDirFn(filePath)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			childPath := filepath.Join(filePath, file.Name())
+			if err := w.walk(childPath, path.Join(e.name, file.Name()), path.Join(rel, file.Name()), matcher, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case info.Mode()&os.ModeSymlink != 0:
+		e.link, err = osReadlinkFn(filePath)
+		if err != nil {
+			return err
+		}
+	case info.Mode().IsRegular():
+		key, linked, err := fileKey(filePath, info)
+		if err != nil {
+			return err
+		}
+		if linked {
+			// further hardlinks to an already stored inode only reference the first entry
+			if first, ok := w.inodes[key]; ok {
+				e.hardlink = first
+			} else {
+				w.inodes[key] = e.name
+			}
+		}
+	}
+
+	return fn(e)
+}
+
+// addIgnoreFile extends the matcher by the ignore file in dir, if there is one
+func addIgnoreFile(matcher ignore.Matcher, dir string, rel string) (ignore.Matcher, error) {
+	f, err := osOpenFn(filepath.Join(dir, ignore.FileName))
+	if os.IsNotExist(err) {
+		return matcher, nil
+	}
+	if err != nil {
+		return matcher, err
+	}
+	defer f.Close()
+	matcher, err = matcher.AddFile(rel, f)
+	if err != nil {
+		return matcher, fmt.Errorf("invalid pattern in %s: %w", filepath.Join(dir, ignore.FileName), err)
+	}
+	return matcher, nil
+}
@@ -0,0 +1,43 @@
+package zipper
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs reads all extended attributes of path without following symlinks
+func listXattrs(path string) (map[string]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]string{}
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		vsize, err := unix.Lgetxattr(path, string(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, vsize)
+		vsize, err = unix.Lgetxattr(path, string(name), value)
+		if err != nil {
+			return nil, err
+		}
+		attrs[string(name)] = string(value[:vsize])
+	}
+	return attrs, nil
+}
+
+// setXattr sets an extended attribute on path without following symlinks
+func setXattr(path, name, value string) error {
+	return unix.Lsetxattr(path, name, []byte(value), 0)
+}
@@ -0,0 +1,31 @@
+package zipper
+
+import (
+    "path/filepath"
+    "testing"
+
+    "golang.org/x/sys/unix"
+)
+
+func TestTar_Xattrs(t *testing.T) {
+    tmp := t.TempDir()
+    src := filepath.Join(tmp, "attr.txt")
+    writeFile(t, src, []byte("x"))
+    if err := unix.Setxattr(src, "user.tachicrypt", []byte("kept"), 0); err != nil {
+        t.Skipf("user xattrs not supported: %v", err)
+    }
+
+    data, err := NewTar(false).Pack(src)
+    if err != nil {
+        t.Fatalf("pack: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := NewTar(false).Unpack(data, dest); err != nil {
+        t.Fatalf("unpack: %v", err)
+    }
+    value := make([]byte, 16)
+    n, err := unix.Getxattr(filepath.Join(dest, "attr.txt"), "user.tachicrypt", value)
+    if err != nil || string(value[:n]) != "kept" {
+        t.Fatalf("xattr not restored: %q %v", value[:n], err)
+    }
+}
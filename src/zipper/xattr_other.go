@@ -0,0 +1,13 @@
+//go:build !linux
+
+package zipper
+
+// listXattrs reports no extended attributes on platforms where they aren't supported yet
+func listXattrs(path string) (map[string]string, error) {
+	return nil, nil
+}
+
+// setXattr is a no-op on platforms where extended attributes aren't supported yet
+func setXattr(path, name, value string) error {
+	return nil
+}
@@ -3,12 +3,31 @@ package zipper
 import (
     "archive/zip"
     "bytes"
+    "context"
+    "encoding/binary"
+    "fmt"
     "io"
     "os"
     "path/filepath"
+    "strings"
 )
 
+// extraUnixOwner is the Info-ZIP "new unix" extra field id (0x7875, "ux") which carries uid/gid
+const extraUnixOwner = 0x7875
+
+// extraHardlink is a private extra field id marking an entry as hardlink; its payload is the
+// archive name of the entry holding the content
+const extraHardlink = 0x6c68
+
+// creatorUnix is the "version made by" host id zip uses for entries carrying unix mode bits
+const creatorUnix = 3
+
+// Zipper is the zip Container implementation
 type Zipper struct {
+    Options
+    // conflicts are the ones resolved by the last extraction, extracted what it wrote
+    conflicts []Conflict
+    extracted []string
 }
 
 func New() *Zipper {
@@ -18,23 +37,85 @@ func New() *Zipper {
 // test hooks for easier unit testing of error paths; default to real implementations
 var (
     osStatFn       = os.Stat
+    osLstatFn      = os.Lstat
+    osReadlinkFn   = os.Readlink
+    osSymlinkFn    = os.Symlink
+    osLinkFn       = os.Link
+    osRemoveFn     = os.Remove
     osReadDirFn    = os.ReadDir
     osOpenFn       = os.Open
     osMkdirAllFn   = os.MkdirAll
-    osCreateFn     = os.Create
+    osOpenFileFn   = os.OpenFile
+    osChmodFn      = os.Chmod
+    osLchownFn     = os.Lchown
+    osChtimesFn    = os.Chtimes
+    osGeteuidFn    = os.Geteuid
     ioCopyFn       = io.Copy
     zipNewReaderFn = func(b []byte) (*zip.Reader, error) { return zip.NewReader(bytes.NewReader(b), int64(len(b))) }
     zipFileOpenFn  = func(f *zip.File) (io.ReadCloser, error) { return f.Open() }
     closeZipWriterFn = func(w *zip.Writer) error { return w.Close() }
-    createZipEntryFn = func(w *zip.Writer, name string) (io.Writer, error) { return w.Create(name) }
+    createZipEntryFn = func(w *zip.Writer, hdr *zip.FileHeader) (io.Writer, error) { return w.CreateHeader(hdr) }
 )
 
-func (z *Zipper) Zip(path string) ([]byte, error) {
+// Pack implements Container by zipping the given paths
+func (z *Zipper) Pack(paths ...string) ([]byte, error) {
+    return z.Zip(paths...)
+}
+
+// PackContext implements Container
+func (z *Zipper) PackContext(ctx context.Context, paths ...string) ([]byte, error) {
+    return z.ZipContext(ctx, paths...)
+}
+
+// Unpack implements Container by extracting the given zip data
+func (z *Zipper) Unpack(data []byte, destDir string) error {
+    return z.Extract(data, destDir)
+}
+
+// UnpackContext implements Container
+func (z *Zipper) UnpackContext(ctx context.Context, data []byte, destDir string) error {
+    return z.ExtractContext(ctx, data, destDir)
+}
+
+// Conflicts implements Container
+func (z *Zipper) Conflicts() []Conflict {
+    return z.conflicts
+}
+
+// Extracted implements Container
+func (z *Zipper) Extracted() []string {
+    return z.extracted
+}
+
+// Zip packs the given files and directories into a zip archive, each of them as top-level entry
+func (z *Zipper) Zip(paths ...string) ([]byte, error) {
+    return z.ZipContext(context.Background(), paths...)
+}
+
+// ZipContext is Zip stopping with the error of ctx once it's done
+func (z *Zipper) ZipContext(ctx context.Context, paths ...string) ([]byte, error) {
+    return z.writeZip(func(w *zip.Writer) error {
+        return z.walk(ctx, paths, func(e *entry) error {
+            if err := z.zipFile(ctx, e, w); err != nil {
+                return err
+            }
+            z.packed(e)
+            return nil
+        })
+    })
+}
+
+// writeZip returns the zip archive holding the entries fn adds to the writer
+func (z *Zipper) writeZip(fn func(w *zip.Writer) error) ([]byte, error) {
+    if err := ValidateCompression(z.compression(), z.Level); err != nil {
+        return []byte{}, err
+    }
+
     buf := &bytes.Buffer{}
     w := zip.NewWriter(buf)
+    z.registerCompressors(w)
 
-    // Zip the file(s)
-    err := z.zipFile(path, "", w)
+    err := fn(w)
     if err != nil {
         _ = closeZipWriterFn(w)
         return []byte{}, err
@@ -49,80 +130,231 @@ func (z *Zipper) Zip(path string) ([]byte, error) {
     return buf.Bytes(), nil
 }
 
-func (z *Zipper) zipFile(path string, prefix string, w *zip.Writer) error {
-    info, err := osStatFn(path)
+func (z *Zipper) zipFile(ctx context.Context, e *entry, w *zip.Writer) error {
+    hdr, err := z.fileHeader(e.info, e.name)
     if err != nil {
         return err
     }
 
-    if info.IsDir() {
-        prefix = filepath.Join(prefix, filepath.Base(path))
-        files, err := osReadDirFn(path)
+    mode := e.info.Mode()
+    switch {
+    case mode.IsDir():
+        // directories get their own entry so empty ones survive and their metadata can be restored
+        hdr.Method = zip.Store
+        _, err := createZipEntryFn(w, hdr)
+        return err
+    case e.hardlink != "":
+        hdr.Method = zip.Store
+        hdr.Extra = appendExtra(hdr.Extra, extraHardlink, []byte(e.hardlink))
+        _, err := createZipEntryFn(w, hdr)
+        return err
+    case mode&os.ModeSymlink != 0:
+        // symlinks are stored as links, the entry content being the link target
+        hdr.Method = zip.Store
+        zf, err := createZipEntryFn(w, hdr)
         if err != nil {
             return err
         }
-        for _, file := range files {
-            if err := z.zipFile(filepath.Join(path, file.Name()), prefix, w); err != nil {
-                return err
-            }
-        }
-    } else {
-        f, err := osOpenFn(path)
+        _, err = io.WriteString(zf, e.link)
+        return err
+    case mode.IsRegular():
+        f, err := e.open()
         if err != nil {
             return err
         }
         defer f.Close()
+        r := &ctxReader{ctx: ctx, r: f}
+
+        // entries which look incompressible are stored, compressing them again only costs time
+        sample := make([]byte, sampleSize)
+        n, err := io.ReadFull(r, sample)
+        if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+            return err
+        }
+        sample = sample[:n]
+        if looksIncompressible(sample) {
+            hdr.Method = zip.Store
+        }
 
-        zf, err := createZipEntryFn(w, filepath.Join(prefix, filepath.Base(path)))
+        zf, err := createZipEntryFn(w, hdr)
         if err != nil {
             return err
         }
-        _, err = ioCopyFn(zf, f)
+        _, err = ioCopyFn(zf, io.MultiReader(bytes.NewReader(sample), r))
         return err
     }
+    return fmt.Errorf("unsupported file type %s at %s, use the tar format for special files", mode.Type(), e.path)
+}
+
+// fileHeader builds the zip header for the given file. Unless StripMetadata is set
+// the header carries mode bits, the modification time and the owner uid/gid.
+func (z *Zipper) fileHeader(info os.FileInfo, name string) (*zip.FileHeader, error) {
+    name = filepath.ToSlash(name)
+    if info.IsDir() {
+        name += "/"
+    }
 
-    return nil
+    if z.StripMetadata {
+        hdr := &zip.FileHeader{Name: name, Method: z.zipMethod()}
+        switch {
+        case info.IsDir():
+            hdr.SetMode(os.ModeDir | 0755)
+        case info.Mode()&os.ModeSymlink != 0:
+            hdr.SetMode(os.ModeSymlink | 0777)
+        default:
+            hdr.SetMode(0644)
+        }
+        return hdr, nil
+    }
+
+    hdr, err := zip.FileInfoHeader(info)
+    if err != nil {
+        return nil, err
+    }
+    hdr.Name = name
+    hdr.Method = z.zipMethod()
+    if uid, gid, ok := fileOwner(info); ok {
+        hdr.Extra = appendExtra(hdr.Extra, extraUnixOwner, ownerPayload(uid, gid))
+    }
+    return hdr, nil
 }
 
 func (z *Zipper) Extract(zipData []byte, destDir string) error {
+    return z.ExtractContext(context.Background(), zipData, destDir)
+}
+
+// ExtractContext is Extract stopping with the error of ctx once it's done
+func (z *Zipper) ExtractContext(ctx context.Context, zipData []byte, destDir string) error {
+    if err := ValidateConflictPolicy(z.OnConflict); err != nil {
+        return err
+    }
     // Create a ZIP reader directly from the byte slice
     reader, err := zipNewReaderFn(zipData)
     if nil != err {
         return err
     }
 
+    x := newExtractor(ctx, destDir, z.Options)
+    defer func() { z.conflicts, z.extracted = x.conflicts, x.extracted }()
+    entries := make([]planEntry, 0, len(reader.File))
+    for _, f := range reader.File {
+        entries = append(entries, planEntry{name: f.Name, isDir: f.Mode().IsDir()})
+    }
+    if err := x.plan(entries); err != nil {
+        return err
+    }
+
     for _, f := range reader.File {
-        if f.FileInfo().IsDir() {
-            err := osMkdirAllFn(filepath.Join(destDir, f.Name), f.FileInfo().Mode())
-            if err != nil {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        meta := zipMetadata(f)
+        mode := f.Mode()
+        if mode.IsDir() {
+            if err := x.dir(f.Name, meta); err != nil {
                 return err
             }
-        } else {
-            rc, err := zipFileOpenFn(f)
-            if err != nil {
+            continue
+        }
+        if first, ok := findExtra(f.Extra, extraHardlink); ok {
+            if err := x.hardlink(f.Name, string(first)); err != nil {
                 return err
             }
+            continue
+        }
 
-            outFile := filepath.Join(destDir, f.Name)
-
-            err = osMkdirAllFn(filepath.Dir(outFile), 0755) // ### note to myself - 755 fixes a current issue but is not a good chmod
-            if err != nil {
-                return err
+        rc, err := zipFileOpenFn(f)
+        if err != nil {
+            return err
+        }
+        if mode&os.ModeSymlink != 0 {
+            var link strings.Builder
+            if _, err = ioCopyFn(&link, rc); err == nil {
+                err = x.symlink(f.Name, link.String(), meta)
             }
+        } else {
+            err = x.file(f.Name, rc, meta)
+        }
+        rc.Close()
+        if err != nil {
+            return err
+        }
+    }
 
-            fw, err := osCreateFn(outFile)
-            if err != nil {
-                return err
-            }
+    return x.finish()
+}
 
-            _, err = ioCopyFn(fw, rc)
-            if err != nil {
-                return err
-            }
-            fw.Close()
-            rc.Close()
+// zipMetadata collects the metadata recorded in a zip header. Entries written by older versions
+// carry neither unix mode bits nor timestamps and keep the defaults on extraction.
+func zipMetadata(f *zip.File) metadata {
+    meta := metadata{}
+    if f.CreatorVersion>>8 == creatorUnix {
+        meta.mode, meta.hasMode = f.Mode(), true
+    }
+    if f.ModifiedDate != 0 || f.ModifiedTime != 0 {
+        meta.mtime, meta.hasMtime = f.Modified, true
+    }
+    meta.uid, meta.gid, meta.hasOwner = parseOwnerExtra(f.Extra)
+    return meta
+}
+
+// appendExtra appends a zip extra field with the given id and payload
+func appendExtra(extra []byte, id uint16, payload []byte) []byte {
+    extra = binary.LittleEndian.AppendUint16(extra, id)
+    extra = binary.LittleEndian.AppendUint16(extra, uint16(len(payload)))
+    return append(extra, payload...)
+}
+
+// findExtra returns the payload of the first zip extra field with the given id
+func findExtra(extra []byte, id uint16) ([]byte, bool) {
+    for len(extra) >= 4 {
+        fieldID := binary.LittleEndian.Uint16(extra[0:])
+        size := int(binary.LittleEndian.Uint16(extra[2:]))
+        if len(extra) < 4+size {
+            return nil, false
         }
+        if fieldID == id {
+            return extra[4 : 4+size], true
+        }
+        extra = extra[4+size:]
     }
+    return nil, false
+}
+
+// ownerPayload encodes uid/gid as payload of an Info-ZIP "new unix" extra field
+func ownerPayload(uid, gid int) []byte {
+    b := []byte{1, 4}
+    b = binary.LittleEndian.AppendUint32(b, uint32(uid))
+    b = append(b, 4)
+    return binary.LittleEndian.AppendUint32(b, uint32(gid))
+}
 
-    return nil
+// parseOwnerExtra looks for an Info-ZIP "new unix" extra field and returns the uid/gid stored in it
+func parseOwnerExtra(extra []byte) (uid, gid int, ok bool) {
+    field, ok := findExtra(extra, extraUnixOwner)
+    if !ok || len(field) < 2 || field[0] != 1 {
+        return 0, 0, false
+    }
+    uid, field, ok = readVarUint(field[1:])
+    if !ok {
+        return 0, 0, false
+    }
+    gid, _, ok = readVarUint(field)
+    return uid, gid, ok
+}
+
+// readVarUint reads a size prefixed little endian unsigned integer as used by the "ux" extra field
+func readVarUint(b []byte) (int, []byte, bool) {
+    if len(b) < 1 {
+        return 0, nil, false
+    }
+    size := int(b[0])
+    if size > 8 || len(b) < 1+size {
+        return 0, nil, false
+    }
+    var v uint64
+    for i := size - 1; i >= 0; i-- {
+        v = v<<8 | uint64(b[1+i])
+    }
+    return int(v), b[1+size:], true
 }
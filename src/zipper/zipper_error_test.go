@@ -230,7 +230,7 @@ func TestZip_CreateEntryError(t *testing.T) {
 
     // Stub createZipEntryFn to return error
     oldCreate := createZipEntryFn
-    createZipEntryFn = func(w *zip.Writer, name string) (io.Writer, error) { return nil, io.ErrUnexpectedEOF }
+    createZipEntryFn = func(w *zip.Writer, hdr *zip.FileHeader) (io.Writer, error) { return nil, io.ErrUnexpectedEOF }
     t.Cleanup(func() { createZipEntryFn = oldCreate })
 
     z := New()
@@ -0,0 +1,291 @@
+package zipper
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestZipAndExtract_SymlinksStoredAsLinks(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    writeFile(t, filepath.Join(root, "data", "file.txt"), []byte("payload"))
+    if err := os.Symlink("data", filepath.Join(root, "dirlink")); err != nil {
+        t.Fatalf("symlink dir: %v", err)
+    }
+    if err := os.Symlink("data/file.txt", filepath.Join(root, "filelink")); err != nil {
+        t.Fatalf("symlink file: %v", err)
+    }
+
+    z := New()
+    data, err := z.Zip(root)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+
+    // the linked directory must not have been archived a second time
+    r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        t.Fatalf("reader: %v", err)
+    }
+    for _, f := range r.File {
+        if strings.HasPrefix(f.Name, "tree/dirlink/") {
+            t.Fatalf("symlinked directory content was archived: %s", f.Name)
+        }
+    }
+
+    dest := filepath.Join(tmp, "out")
+    if err := z.Extract(data, dest); err != nil {
+        t.Fatalf("extract: %v", err)
+    }
+    for link, want := range map[string]string{"dirlink": "data", "filelink": "data/file.txt"} {
+        got, err := os.Readlink(filepath.Join(dest, "tree", link))
+        if err != nil {
+            t.Fatalf("readlink %s: %v", link, err)
+        }
+        if got != want {
+            t.Fatalf("link target mismatch for %s: got %q want %q", link, got, want)
+        }
+    }
+    if got := readFile(t, filepath.Join(dest, "tree", "filelink")); string(got) != "payload" {
+        t.Fatalf("restored link does not resolve to content: %q", got)
+    }
+}
+
+func TestZip_SymlinkLoopWithoutFollowing(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := os.Symlink("..", filepath.Join(root, "up")); err != nil {
+        t.Fatalf("symlink: %v", err)
+    }
+
+    z := New()
+    if _, err := z.Zip(root); err != nil {
+        t.Fatalf("expected loop to be stored as a link, got: %v", err)
+    }
+}
+
+func TestZip_FollowSymlinks(t *testing.T) {
+    tmp := t.TempDir()
+    writeFile(t, filepath.Join(tmp, "outside", "file.txt"), []byte("followed"))
+    root := filepath.Join(tmp, "tree")
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := os.Symlink(filepath.Join(tmp, "outside"), filepath.Join(root, "link")); err != nil {
+        t.Fatalf("symlink: %v", err)
+    }
+
+    z := New()
+    z.FollowSymlinks = true
+    data, err := z.Zip(root)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := z.Extract(data, dest); err != nil {
+        t.Fatalf("extract: %v", err)
+    }
+    restored := filepath.Join(dest, "tree", "link", "file.txt")
+    info, err := os.Lstat(filepath.Join(dest, "tree", "link"))
+    if err != nil || !info.IsDir() {
+        t.Fatalf("expected followed link to be restored as directory: %v", err)
+    }
+    if got := readFile(t, restored); string(got) != "followed" {
+        t.Fatalf("content mismatch: %q", got)
+    }
+}
+
+func TestZip_FollowSymlinks_LoopDetected(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+    if err := os.Symlink("..", filepath.Join(root, "sub", "loop")); err != nil {
+        t.Fatalf("symlink: %v", err)
+    }
+
+    z := New()
+    z.FollowSymlinks = true
+    _, err := z.Zip(root)
+    if err == nil || !strings.Contains(err.Error(), "loop") {
+        t.Fatalf("expected loop detection error, got: %v", err)
+    }
+}
+
+func TestZipAndExtract_HardlinksStoredOnce(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    content := bytes.Repeat([]byte("hardlinked content "), 64)
+    writeFile(t, filepath.Join(root, "a.txt"), content)
+    if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")); err != nil {
+        t.Skipf("hardlinks not supported: %v", err)
+    }
+
+    z := New()
+    data, err := z.Zip(root)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+
+    r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        t.Fatalf("reader: %v", err)
+    }
+    var linked int
+    for _, f := range r.File {
+        if _, ok := findExtra(f.Extra, extraHardlink); ok {
+            linked++
+            if f.UncompressedSize64 != 0 {
+                t.Fatalf("hardlink entry %s carries content", f.Name)
+            }
+        }
+    }
+    if linked != 1 {
+        t.Fatalf("expected exactly one hardlink entry, got %d", linked)
+    }
+
+    dest := filepath.Join(tmp, "out")
+    if err := z.Extract(data, dest); err != nil {
+        t.Fatalf("extract: %v", err)
+    }
+    a, err := os.Stat(filepath.Join(dest, "tree", "a.txt"))
+    if err != nil {
+        t.Fatalf("stat a: %v", err)
+    }
+    b, err := os.Stat(filepath.Join(dest, "tree", "b.txt"))
+    if err != nil {
+        t.Fatalf("stat b: %v", err)
+    }
+    if !os.SameFile(a, b) {
+        t.Fatalf("expected restored files to be hardlinked")
+    }
+    if got := readFile(t, filepath.Join(dest, "tree", "b.txt")); !bytes.Equal(got, content) {
+        t.Fatalf("hardlink content mismatch")
+    }
+}
+
+func TestExtract_RejectsUnsafePaths(t *testing.T) {
+    z := New()
+    for _, name := range []string{"../escape.txt", "a/../../escape.txt", "/abs.txt"} {
+        data := buildZip(t, nil, map[string]string{name: "x"})
+        dest := filepath.Join(t.TempDir(), "out")
+        err := z.Extract(data, dest)
+        if !errors.Is(err, ErrUnsafePath) {
+            t.Fatalf("expected unsafe path error for %q, got: %v", name, err)
+        }
+    }
+}
+
+func TestExtract_RejectsWritesThroughSymlink(t *testing.T) {
+    tmp := t.TempDir()
+    outside := filepath.Join(tmp, "outside")
+    if err := os.MkdirAll(outside, 0o755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+
+    var buf bytes.Buffer
+    w := zip.NewWriter(&buf)
+    link := &zip.FileHeader{Name: "link", Method: zip.Store}
+    link.SetMode(os.ModeSymlink | 0o777)
+    lw, err := w.CreateHeader(link)
+    if err != nil {
+        t.Fatalf("create link entry: %v", err)
+    }
+    if _, err := lw.Write([]byte(outside)); err != nil {
+        t.Fatalf("write link entry: %v", err)
+    }
+    fw, err := w.Create("link/evil.txt")
+    if err != nil {
+        t.Fatalf("create file entry: %v", err)
+    }
+    if _, err := fw.Write([]byte("x")); err != nil {
+        t.Fatalf("write file entry: %v", err)
+    }
+    if err := w.Close(); err != nil {
+        t.Fatalf("close: %v", err)
+    }
+
+    z := New()
+    if err := z.Extract(buf.Bytes(), filepath.Join(tmp, "out")); err == nil {
+        t.Fatalf("expected error when writing through an extracted symlink")
+    }
+    if _, err := os.Stat(filepath.Join(outside, "evil.txt")); err == nil {
+        t.Fatalf("file was written outside of the destination")
+    }
+}
+
+func TestExtract_RejectsFileThroughEarlierSymlinkOfSameName(t *testing.T) {
+    tmp := t.TempDir()
+    outside := filepath.Join(tmp, "outside.txt")
+
+    var zipped bytes.Buffer
+    zw := zip.NewWriter(&zipped)
+    link := &zip.FileHeader{Name: "x", Method: zip.Store}
+    link.SetMode(os.ModeSymlink | 0o777)
+    lw, err := zw.CreateHeader(link)
+    if err != nil {
+        t.Fatalf("create link entry: %v", err)
+    }
+    if _, err := lw.Write([]byte(outside)); err != nil {
+        t.Fatalf("write link entry: %v", err)
+    }
+    fw, err := zw.Create("x")
+    if err != nil {
+        t.Fatalf("create file entry: %v", err)
+    }
+    if _, err := fw.Write([]byte("PWNED")); err != nil {
+        t.Fatalf("write file entry: %v", err)
+    }
+    if err := zw.Close(); err != nil {
+        t.Fatalf("close zip: %v", err)
+    }
+
+    var tarred bytes.Buffer
+    tw := tar.NewWriter(&tarred)
+    if err := tw.WriteHeader(&tar.Header{Name: "x", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0o777}); err != nil {
+        t.Fatalf("write link header: %v", err)
+    }
+    if err := tw.WriteHeader(&tar.Header{Name: "x", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5}); err != nil {
+        t.Fatalf("write file header: %v", err)
+    }
+    if _, err := tw.Write([]byte("PWNED")); err != nil {
+        t.Fatalf("write file entry: %v", err)
+    }
+    if err := tw.Close(); err != nil {
+        t.Fatalf("close tar: %v", err)
+    }
+
+    for _, tc := range []struct {
+        format string
+        data   []byte
+    }{{FormatZip, zipped.Bytes()}, {FormatTar, tarred.Bytes()}} {
+        for _, staged := range []bool{false, true} {
+            writeFile(t, outside, []byte("original"))
+            dest := filepath.Join(t.TempDir(), "out")
+            var opts Options
+            if staged {
+                opts.StagingDir = filepath.Join(t.TempDir(), "staging")
+            }
+            c, err := NewContainer(tc.format, opts)
+            if err != nil {
+                t.Fatalf("container: %v", err)
+            }
+            if err := c.Unpack(tc.data, dest); !errors.Is(err, ErrUnsafePath) {
+                t.Fatalf("%s (staged %v): expected an unsafe path error for the duplicate entry, got: %v", tc.format, staged, err)
+            }
+            if got := readFile(t, outside); string(got) != "original" {
+                t.Fatalf("%s (staged %v): file outside of the destination was overwritten: %q", tc.format, staged, got)
+            }
+        }
+    }
+}
@@ -0,0 +1,143 @@
+package zipper
+
+import (
+    "archive/zip"
+    "bytes"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestZipAndExtract_PreservesModeAndMtime(t *testing.T) {
+    tmp := t.TempDir()
+    root := filepath.Join(tmp, "tree")
+    script := filepath.Join(root, "bin", "run.sh")
+    writeFile(t, script, []byte("#!/bin/sh\necho hi\n"))
+    if err := os.Chmod(script, 0o750); err != nil {
+        t.Fatalf("chmod script: %v", err)
+    }
+    if err := os.MkdirAll(filepath.Join(root, "empty"), 0o700); err != nil {
+        t.Fatalf("mkdir empty: %v", err)
+    }
+    mtime := time.Date(2020, 5, 17, 12, 30, 0, 0, time.UTC)
+    if err := os.Chtimes(script, mtime, mtime); err != nil {
+        t.Fatalf("chtimes script: %v", err)
+    }
+    dirTime := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+    if err := os.Chtimes(filepath.Join(root, "bin"), dirTime, dirTime); err != nil {
+        t.Fatalf("chtimes dir: %v", err)
+    }
+
+    z := New()
+    data, err := z.Zip(root)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+    dest := filepath.Join(tmp, "out")
+    if err := z.Extract(data, dest); err != nil {
+        t.Fatalf("extract: %v", err)
+    }
+
+    info, err := os.Stat(filepath.Join(dest, "tree", "bin", "run.sh"))
+    if err != nil {
+        t.Fatalf("stat restored script: %v", err)
+    }
+    if info.Mode().Perm() != 0o750 {
+        t.Fatalf("mode mismatch: got %v want %v", info.Mode().Perm(), os.FileMode(0o750))
+    }
+    if !info.ModTime().Equal(mtime) {
+        t.Fatalf("mtime mismatch: got %v want %v", info.ModTime(), mtime)
+    }
+
+    dirInfo, err := os.Stat(filepath.Join(dest, "tree", "bin"))
+    if err != nil {
+        t.Fatalf("stat restored dir: %v", err)
+    }
+    if !dirInfo.ModTime().Equal(dirTime) {
+        t.Fatalf("dir mtime mismatch: got %v want %v", dirInfo.ModTime(), dirTime)
+    }
+
+    emptyInfo, err := os.Stat(filepath.Join(dest, "tree", "empty"))
+    if err != nil || !emptyInfo.IsDir() {
+        t.Fatalf("expected empty dir to be restored: %v", err)
+    }
+    if emptyInfo.Mode().Perm() != 0o700 {
+        t.Fatalf("empty dir mode mismatch: got %v", emptyInfo.Mode().Perm())
+    }
+}
+
+func TestZip_StripMetadata(t *testing.T) {
+    tmp := t.TempDir()
+    f := filepath.Join(tmp, "tool")
+    writeFile(t, f, []byte("x"))
+    if err := os.Chmod(f, 0o755); err != nil {
+        t.Fatalf("chmod: %v", err)
+    }
+
+    z := New()
+    z.StripMetadata = true
+    data, err := z.Zip(f)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+
+    r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        t.Fatalf("reader: %v", err)
+    }
+    hdr := r.File[0]
+    if hdr.Mode().Perm() != 0o644 {
+        t.Fatalf("expected normalized mode 0644, got %v", hdr.Mode().Perm())
+    }
+    if hdr.ModifiedDate != 0 || hdr.ModifiedTime != 0 {
+        t.Fatalf("expected no modification time to be stored")
+    }
+    if _, _, ok := parseOwnerExtra(hdr.Extra); ok {
+        t.Fatalf("expected no owner information to be stored")
+    }
+}
+
+func TestExtract_RestoresOwnerOnlyAsRoot(t *testing.T) {
+    tmp := t.TempDir()
+    f := filepath.Join(tmp, "owned.txt")
+    writeFile(t, f, []byte("x"))
+
+    z := New()
+    data, err := z.Zip(f)
+    if err != nil {
+        t.Fatalf("zip: %v", err)
+    }
+
+    var chowned bool
+    oldEuid, oldChown := osGeteuidFn, osLchownFn
+    osLchownFn = func(string, int, int) error { chowned = true; return nil }
+    t.Cleanup(func() { osGeteuidFn = oldEuid; osLchownFn = oldChown })
+
+    osGeteuidFn = func() int { return 1000 }
+    if err := z.Extract(data, filepath.Join(tmp, "user")); err != nil {
+        t.Fatalf("extract as user: %v", err)
+    }
+    if chowned {
+        t.Fatalf("expected no chown when not running as root")
+    }
+
+    osGeteuidFn = func() int { return 0 }
+    if err := z.Extract(data, filepath.Join(tmp, "root")); err != nil {
+        t.Fatalf("extract as root: %v", err)
+    }
+    if !chowned {
+        t.Fatalf("expected chown when running as root")
+    }
+}
+
+func TestOwnerExtra_RoundTrip(t *testing.T) {
+    extra := appendExtra([]byte{0x55, 0x54, 0x01, 0x00, 0x00}, extraUnixOwner, ownerPayload(1234, 5678))
+    uid, gid, ok := parseOwnerExtra(extra)
+    if !ok || uid != 1234 || gid != 5678 {
+        t.Fatalf("unexpected owner: %d %d %v", uid, gid, ok)
+    }
+    if _, _, ok := parseOwnerExtra([]byte{0x75, 0x78, 0x20, 0x00}); ok {
+        t.Fatalf("expected truncated extra field to be rejected")
+    }
+}
@@ -134,15 +134,17 @@ func TestZipAndExtract_EmptyDirectory(t *testing.T) {
     if err := z.Extract(zipBytes, dest); err != nil {
         t.Fatalf("extract empty dir error: %v", err)
     }
-    // Current implementation doesn't create entries for empty dirs when zipping;
-    // just assert that no files were created and extraction succeeded.
-    count := 0
-    _ = filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
-        if err != nil { return err }
-        if !info.IsDir() { count++ }
-        return nil
-    })
-    if count != 0 {
-        t.Fatalf("expected no files extracted from empty dir, got %d", count)
+    // The empty directory itself must be restored, without any files in it.
+    restored := filepath.Join(dest, filepath.Base(empty))
+    info, err := os.Stat(restored)
+    if err != nil || !info.IsDir() {
+        t.Fatalf("expected empty directory to be restored: %v", err)
+    }
+    entries, err := os.ReadDir(restored)
+    if err != nil {
+        t.Fatalf("readdir restored: %v", err)
+    }
+    if len(entries) != 0 {
+        t.Fatalf("expected restored directory to be empty, got %d entries", len(entries))
     }
 }
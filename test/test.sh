@@ -53,12 +53,12 @@ build_cli() {
 
 encrypt() {
   local src="$1" parts="$2" outdir="$3"
-  TACHICRYPT_PASSWORD="${PWD_VALUE}" "${BIN_PATH}" --hide --parts "${parts}" --data "${src}" --output "${outdir}" >/dev/null
+  TACHICRYPT_PASSWORD="${PWD_VALUE}" "${BIN_PATH}" hide --parts "${parts}" --data "${src}" --output "${outdir}" >/dev/null
 }
 
 decrypt() {
   local encdir="$1" outdir="$2"
-  TACHICRYPT_PASSWORD="${PWD_VALUE}" "${BIN_PATH}" --unhide --data "${encdir}" --output "${outdir}" >/dev/null
+  TACHICRYPT_PASSWORD="${PWD_VALUE}" "${BIN_PATH}" unhide --data "${encdir}" --output "${outdir}" >/dev/null
 }
 
 expect_match() {